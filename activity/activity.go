@@ -0,0 +1,109 @@
+// Package activity maintains the activity counters of the devices. The
+// counters are derived from the handled requests of each device, so running
+// the job twice or after a missed boundary gives the same result.
+package activity
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"time"
+)
+
+type Config struct {
+	// TimeZone is the IANA time zone the day, week, month and year
+	// boundaries are computed in.
+	TimeZone string
+	// WeekStart is the first day of a week.
+	WeekStart time.Weekday
+	// Interval is the time between two updates when running continuously.
+	Interval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TimeZone:  "Europe/Berlin",
+		WeekStart: time.Monday,
+		Interval:  15 * time.Minute,
+	}
+}
+
+// Boundaries are the starts of the periods the counters are computed for.
+type Boundaries struct {
+	Day   time.Time
+	Week  time.Time
+	Month time.Time
+	Year  time.Time
+}
+
+// BoundariesAt returns the starts of the day, week, month and year t is in,
+// in location.
+func BoundariesAt(t time.Time, location *time.Location, weekStart time.Weekday) Boundaries {
+	t = t.In(location)
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	daysSinceWeekStart := (int(t.Weekday()) - int(weekStart) + 7) % 7
+
+	return Boundaries{
+		Day:   day,
+		Week:  day.AddDate(0, 0, -daysSinceWeekStart),
+		Month: time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location),
+		Year:  time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, location),
+	}
+}
+
+type Job struct {
+	Config     Config
+	Repository repository.Repository
+	Clock      scheduling.Clock
+	location   *time.Location
+}
+
+func NewJob(config Config, repo repository.Repository) (*Job, error) {
+	location, err := time.LoadLocation(config.TimeZone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		Config:     config,
+		Repository: repo,
+		Clock:      scheduling.SystemClock{},
+		location:   location,
+	}, nil
+}
+
+// Update recomputes the counters of all devices for the current periods.
+func (j *Job) Update() error {
+	boundaries := BoundariesAt(j.Clock.Now(), j.location, j.Config.WeekStart)
+
+	updated, err := j.Repository.UpdateActivityCounters(boundaries.Day, boundaries.Week, boundaries.Month, boundaries.Year)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Updated activity counters of %d devices (day starting %s)", updated, boundaries.Day.Format(time.RFC3339))
+
+	return nil
+}
+
+// Run updates the counters every Interval until ctx is canceled.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Update(); err != nil {
+			log.WithError(err).Error("Activity update failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package activity
+
+import (
+	"database/sql"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"testing"
+	"time"
+)
+
+func loadBerlin(t *testing.T) *time.Location {
+	t.Helper()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	return berlin
+}
+
+func TestBoundariesAt(t *testing.T) {
+	berlin := loadBerlin(t)
+
+	date := func(year int, month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, berlin)
+	}
+	midnight := func(year int, month time.Month, day int) time.Time {
+		return date(year, month, day, 0, 0)
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		weekStart time.Weekday
+		want      Boundaries
+	}{
+		{
+			name:      "within a week",
+			at:        date(2026, time.October, 21, 15, 30),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2026, time.October, 21), midnight(2026, time.October, 19), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "start of a week",
+			at:        midnight(2026, time.October, 19),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2026, time.October, 19), midnight(2026, time.October, 19), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "end of a week",
+			at:        date(2026, time.October, 18, 23, 59),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2026, time.October, 18), midnight(2026, time.October, 12), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			// 22:30 UTC is already the next day in Berlin.
+			name:      "day in the time zone",
+			at:        time.Date(2026, time.October, 20, 22, 30, 0, 0, time.UTC),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2026, time.October, 21), midnight(2026, time.October, 19), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "start of a year",
+			at:        date(2027, time.January, 1, 0, 30),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2027, time.January, 1), midnight(2026, time.December, 28), midnight(2027, time.January, 1), midnight(2027, time.January, 1)},
+		},
+		{
+			// The clocks moved forward on 2026-03-29, the week started in
+			// winter time.
+			name:      "start of the summer time",
+			at:        date(2026, time.March, 29, 12, 0),
+			weekStart: time.Monday,
+			want:      Boundaries{midnight(2026, time.March, 29), midnight(2026, time.March, 23), midnight(2026, time.March, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			// The clocks moved back on 2026-10-25, the week started in
+			// summer time.
+			name:      "end of the summer time",
+			at:        date(2026, time.October, 26, 8, 0),
+			weekStart: time.Sunday,
+			want:      Boundaries{midnight(2026, time.October, 26), midnight(2026, time.October, 25), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "week starting on sunday",
+			at:        date(2026, time.October, 21, 15, 30),
+			weekStart: time.Sunday,
+			want:      Boundaries{midnight(2026, time.October, 21), midnight(2026, time.October, 18), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "week starting on saturday",
+			at:        date(2026, time.October, 23, 9, 0),
+			weekStart: time.Saturday,
+			want:      Boundaries{midnight(2026, time.October, 23), midnight(2026, time.October, 17), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+		{
+			name:      "first day of a week starting on saturday",
+			at:        date(2026, time.October, 24, 9, 0),
+			weekStart: time.Saturday,
+			want:      Boundaries{midnight(2026, time.October, 24), midnight(2026, time.October, 24), midnight(2026, time.October, 1), midnight(2026, time.January, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := BoundariesAt(test.at, berlin, test.weekStart)
+
+			for _, boundary := range []struct {
+				name      string
+				got, want time.Time
+			}{
+				{"day", got.Day, test.want.Day},
+				{"week", got.Week, test.want.Week},
+				{"month", got.Month, test.want.Month},
+				{"year", got.Year, test.want.Year},
+			} {
+				if !boundary.got.Equal(boundary.want) {
+					t.Errorf("%s starts at %s instead of %s", boundary.name, boundary.got, boundary.want)
+				}
+			}
+		})
+	}
+
+	// Only 11 hours passed since midnight on the day the clocks moved
+	// forward.
+	day := BoundariesAt(time.Date(2026, time.March, 29, 12, 0, 0, 0, berlin), berlin, time.Monday).Day
+
+	if since := time.Date(2026, time.March, 29, 12, 0, 0, 0, berlin).Sub(day); since != 11*time.Hour {
+		t.Errorf("day started %s before noon instead of 11h", since)
+	}
+}
+
+func TestUpdateIsIdempotent(t *testing.T) {
+	berlin := loadBerlin(t)
+	repo := memory.New()
+
+	if err := repo.CreateDevices(&[]model.IOSDevice{{DeviceID: "device-a"}, {DeviceID: "device-b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, time.October, 21, 15, 0, 0, 0, berlin)
+
+	var requestLogs []model.IOSDeviceRequestLog
+
+	// Today, this week, this month and this year.
+	for _, handledAt := range []time.Time{
+		now.Add(-time.Hour),
+		now.AddDate(0, 0, -2),
+		now.AddDate(0, 0, -10),
+		now.AddDate(0, -3, 0),
+	} {
+		requestLog := model.NewIOSDeviceRequestLog("device-a", model.IOSLectureUpdateRequestType, handledAt)
+		requestLog.Status = model.IOSRequestStatusHandled
+		requestLog.HandledAt = sql.NullTime{Time: handledAt, Valid: true}
+
+		requestLogs = append(requestLogs, requestLog)
+	}
+
+	if err := repo.CreateRequestLogs(&requestLogs); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := NewJob(DefaultConfig(), repo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := scheduling.NewFixedClock(now)
+	job.Clock = clock
+
+	counters := func() map[string][4]int32 {
+		devices, err := repo.GetDevices()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string][4]int32, len(*devices))
+
+		for _, device := range *devices {
+			got[device.DeviceID] = [4]int32{device.ActivityToday, device.ActivityThisWeek, device.ActivityThisMonth, device.ActivityThisYear}
+		}
+
+		return got
+	}
+
+	want := map[string][4]int32{"device-a": {1, 2, 3, 4}, "device-b": {}}
+
+	for run := 1; run <= 2; run++ {
+		if err := job.Update(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := counters()
+
+		for deviceId, counter := range want {
+			if got[deviceId] != counter {
+				t.Errorf("run %d: %s has counters %v instead of %v", run, deviceId, got[deviceId], counter)
+			}
+		}
+	}
+
+	// The next day the request of today only counts for the week.
+	clock.Set(time.Date(2026, time.October, 22, 0, 1, 0, 0, berlin))
+
+	if err := job.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := counters()["device-a"]; got != [4]int32{0, 2, 3, 4} {
+		t.Errorf("device-a has counters %v instead of [0 2 3 4] the next day", got)
+	}
+}
@@ -0,0 +1,379 @@
+// Package api lets other backend services trigger selections and read their
+// results over HTTP with JSON. It runs the same pipeline as the solve command,
+// see selection.Run.
+//
+//	POST /solves                  run a selection, the body is a SolveRequest
+//	GET  /runs                    list the kept runs, the latest first
+//	GET  /runs/{id}               the result of a run, see result.Run
+//	GET  /runs/{id}/explanation   why the devices of a run were selected
+//	GET  /lectures                the staleness and coverage of the lectures,
+//	                              optionally of ?year=2023&semester=summer
+//
+// Errors are returned as {"error": "..."}.
+//
+// The runs are kept in memory only, up to Config.MaxRuns, and are lost when
+// the server restarts. The lecture update requests of a commit are persisted
+// in the repository.
+//
+// The API has no authentication of its own. With mode "commit" any caller
+// creates lecture update requests that are pushed to the devices, so the
+// server must only be reachable from the backend network or behind a proxy
+// that authenticates the callers.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/selection"
+	"time"
+)
+
+// Modes of a SolveRequest.
+const (
+	ModeSolve  = "solve"
+	ModeCommit = "commit"
+)
+
+// maxBodySize is the maximum size of a request body in bytes.
+const maxBodySize = 1 << 20
+
+var (
+	errNotFound         = errors.New("not found")
+	errMethodNotAllowed = errors.New("method not allowed")
+	errCanceled         = errors.New("request canceled before the selection started")
+)
+
+// SolveRequest are the parameters of a selection. Omitted parameters take
+// the defaults of the server.
+type SolveRequest struct {
+	// Mode is "solve" to only select the devices or "commit" to also
+	// persist the selection as lecture update requests.
+	Mode   string          `json:"mode"`
+	Solver string          `json:"solver"`
+	Term   *selection.Term `json:"term"`
+	// Budget is the maximum number of selected devices.
+	Budget *int `json:"budget"`
+}
+
+type Config struct {
+	// Defaults are the options of a selection not given by a request.
+	Defaults selection.Options
+	// MaxRuns is the number of runs kept for reading, older runs are
+	// dropped.
+	MaxRuns int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Defaults: selection.DefaultOptions(),
+		MaxRuns:  100,
+	}
+}
+
+type Server struct {
+	Config     Config
+	Repository repository.Repository
+	// Now returns the current time the staleness is measured at.
+	Now func() time.Time
+
+	// solving serializes the selections, so that two commits don't create
+	// requests for the same lectures. It holds a token while a selection
+	// runs, a channel instead of a mutex lets waiting requests give up when
+	// their client disconnects.
+	solving chan struct{}
+	mutex   sync.Mutex
+	runs    map[string]*selection.Outcome
+	// order has the IDs of the kept runs, the oldest first.
+	order []string
+}
+
+func NewServer(config Config, repo repository.Repository) *Server {
+	return &Server{
+		Config:     config,
+		Repository: repo,
+		Now:        time.Now,
+		solving:    make(chan struct{}, 1),
+		runs:       make(map[string]*selection.Outcome),
+	}
+}
+
+// ServeHTTP routes the request by its path, which is relative to the mount
+// point of the server, e.g. with http.StripPrefix.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(segments) == 1 && segments[0] == "solves":
+		s.allow(w, r, http.MethodPost, s.solve)
+	case len(segments) == 1 && segments[0] == "runs":
+		s.allow(w, r, http.MethodGet, s.listRuns)
+	case len(segments) == 2 && segments[0] == "runs":
+		s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			s.getRun(w, segments[1])
+		})
+	case len(segments) == 3 && segments[0] == "runs" && segments[2] == "explanation":
+		s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			s.getExplanation(w, segments[1])
+		})
+	case len(segments) == 1 && segments[0] == "lectures":
+		s.allow(w, r, http.MethodGet, s.lectures)
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		return
+	}
+
+	handler(w, r)
+}
+
+func (s *Server) solve(w http.ResponseWriter, r *http.Request) {
+	options, err := s.decodeSolveRequest(w, r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	select {
+	case s.solving <- struct{}{}:
+	case <-ctx.Done():
+		log.Info("Client disconnected while waiting for another selection")
+		writeError(w, http.StatusServiceUnavailable, errCanceled)
+		return
+	}
+
+	// A selection is not interrupted once started, as a commit could be
+	// left half written, so the client is checked only before.
+	if ctx.Err() != nil {
+		<-s.solving
+		log.Info("Client disconnected before the selection started")
+		writeError(w, http.StatusServiceUnavailable, errCanceled)
+		return
+	}
+
+	outcome, err := selection.Run(s.Repository, *options)
+	<-s.solving
+
+	if err != nil {
+		log.WithError(err).Error("Selection requested over HTTP failed")
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	// The run is kept even if the client is gone, it can be read from /runs
+	// and a commit has already created its requests.
+	s.keep(outcome)
+
+	if ctx.Err() != nil {
+		log.Infof("Client disconnected during run %s", outcome.Run.ID)
+	}
+
+	w.Header().Set("Location", "runs/"+outcome.Run.ID)
+	writeJSON(w, http.StatusCreated, outcome.Run)
+}
+
+// decodeSolveRequest validates the SolveRequest of the body and returns the
+// options of the selection.
+func (s *Server) decodeSolveRequest(w http.ResponseWriter, r *http.Request) (*selection.Options, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	var request SolveRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid body: %w", err)
+	}
+
+	if decoder.More() {
+		return nil, errors.New("invalid body: more than one JSON value")
+	}
+
+	options := s.Config.Defaults
+	options.Term = request.Term
+
+	switch request.Mode {
+	case "", ModeSolve:
+	case ModeCommit:
+		options.Commit = true
+	default:
+		return nil, fmt.Errorf("mode has to be %s or %s, got %q", ModeSolve, ModeCommit, request.Mode)
+	}
+
+	if request.Solver != "" {
+		options.Solver = request.Solver
+	}
+
+	if request.Budget != nil {
+		options.Budget = *request.Budget
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &options, nil
+}
+
+// keep stores the outcome and drops the oldest runs beyond MaxRuns.
+func (s *Server) keep(outcome *selection.Outcome) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.runs[outcome.Run.ID] = outcome
+	s.order = append(s.order, outcome.Run.ID)
+
+	for len(s.order) > s.Config.MaxRuns && len(s.order) > 1 {
+		delete(s.runs, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *Server) outcome(id string) *selection.Outcome {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.runs[id]
+}
+
+// RunSummary is the entry of a run in the list of runs.
+type RunSummary struct {
+	ID        string    `json:"runId"`
+	StartedAt time.Time `json:"startedAt"`
+	Solver    string    `json:"solver"`
+	Term      string    `json:"term,omitempty"`
+	Selected  int       `json:"selected"`
+	Valid     bool      `json:"valid"`
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	summaries := make([]RunSummary, 0, len(s.order))
+
+	for i := len(s.order) - 1; i >= 0; i-- {
+		run := s.runs[s.order[i]].Run
+		summaries = append(summaries, RunSummary{
+			ID:        run.ID,
+			StartedAt: run.StartedAt,
+			Solver:    run.Solver,
+			Term:      run.Term,
+			Selected:  len(run.SelectedDevices),
+			Valid:     run.Verification.Valid,
+		})
+	}
+
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, id string) {
+	outcome := s.outcome(id)
+
+	if outcome == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q %w", id, errNotFound))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, outcome.Run)
+}
+
+func (s *Server) getExplanation(w http.ResponseWriter, id string) {
+	outcome := s.outcome(id)
+
+	if outcome == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q %w", id, errNotFound))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, outcome.Explanation)
+}
+
+func (s *Server) lectures(w http.ResponseWriter, r *http.Request) {
+	term, err := parseTerm(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	coverage, err := selection.Lectures(s.Repository, term, s.Now(), s.Config.Defaults.Loader)
+
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, coverage)
+}
+
+// parseTerm reads the term of the query parameters year and semester, which
+// are given together or not at all.
+func parseTerm(r *http.Request) (*selection.Term, error) {
+	query := r.URL.Query()
+	year := query.Get("year")
+	semester := query.Get("semester")
+
+	if year == "" && semester == "" {
+		return nil, nil
+	}
+
+	parsedYear, err := strconv.ParseInt(year, 10, 16)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid year %q", year)
+	}
+
+	term := selection.Term{Year: int16(parsedYear), Semester: semester}
+
+	if err := term.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &term, nil
+}
+
+// statusCode maps the errors of the pipeline to a status code.
+func statusCode(err error) int {
+	if errors.Is(err, repository.ErrConnect) {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Warn("Failed to write response")
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
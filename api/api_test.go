@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/result"
+	"testing"
+)
+
+// newTestServer returns a server on a repository with 3 devices enrolled in
+// 4 winter lectures, device-a covers 3 of them.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	repo := memory.New()
+	devices := []model.IOSDevice{
+		{DeviceID: "device-a", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-b", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-c", State: model.IOSDeviceStateActive},
+	}
+	lectures := []model.IOSLecture{
+		{Id: "lecture-1", Year: 2023, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-2", Year: 2023, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-3", Year: 2023, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-4", Year: 2023, Semester: model.IOSLectureSemesterWinter},
+	}
+	deviceLectures := []model.IOSDeviceLecture{
+		{DeviceId: "device-a", LectureId: "lecture-1"},
+		{DeviceId: "device-a", LectureId: "lecture-2"},
+		{DeviceId: "device-a", LectureId: "lecture-3"},
+		{DeviceId: "device-b", LectureId: "lecture-1"},
+		{DeviceId: "device-c", LectureId: "lecture-4"},
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&lectures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDeviceLectures(&deviceLectures); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewServer(DefaultConfig(), repo)
+}
+
+func serve(s *Server, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	return w
+}
+
+func newSolveRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/solves", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	return r
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(w.Body).Decode(value); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestSolveRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"content type", "text/plain", `{}`},
+		{"malformed body", "application/json", `{"mode":`},
+		{"unknown field", "application/json", `{"budjet": 2}`},
+		{"more than one value", "application/json", `{} {}`},
+		{"mode", "application/json", `{"mode": "dry-run"}`},
+		{"solver", "application/json", `{"solver": "exhaustive"}`},
+		{"negative budget", "application/json", `{"budget": -1}`},
+		{"semester", "application/json", `{"term": {"year": 2023, "semester": "spring"}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			r := newSolveRequest(test.body)
+			r.Header.Set("Content-Type", test.contentType)
+			w := serve(s, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d instead of %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+
+			var response errorResponse
+			decode(t, w, &response)
+
+			if response.Error == "" {
+				t.Error("no error message")
+			}
+
+			if len(s.order) != 0 {
+				t.Errorf("%d runs kept", len(s.order))
+			}
+		})
+	}
+}
+
+func TestRouting(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{http.MethodGet, "/unknown", http.StatusNotFound, ""},
+		{http.MethodGet, "/runs/unknown", http.StatusNotFound, ""},
+		{http.MethodGet, "/runs/unknown/explanation", http.StatusNotFound, ""},
+		{http.MethodGet, "/solves", http.StatusMethodNotAllowed, http.MethodPost},
+		{http.MethodPost, "/runs", http.StatusMethodNotAllowed, http.MethodGet},
+		{http.MethodDelete, "/runs/unknown", http.StatusMethodNotAllowed, http.MethodGet},
+		{http.MethodPost, "/lectures", http.StatusMethodNotAllowed, http.MethodGet},
+		{http.MethodGet, "/lectures?year=2023", http.StatusBadRequest, ""},
+		{http.MethodGet, "/lectures?year=twenty&semester=winter", http.StatusBadRequest, ""},
+		{http.MethodGet, "/lectures?year=2023&semester=winter", http.StatusOK, ""},
+		{http.MethodGet, "/runs", http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		w := serve(s, httptest.NewRequest(test.method, test.path, nil))
+
+		if w.Code != test.status {
+			t.Errorf("%s %s: status %d instead of %d", test.method, test.path, w.Code, test.status)
+		}
+
+		if allow := w.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("%s %s: Allow %q instead of %q", test.method, test.path, allow, test.allow)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%s %s: content type %q", test.method, test.path, contentType)
+		}
+	}
+}
+
+func TestSolveAndReadRun(t *testing.T) {
+	s := newTestServer(t)
+	w := serve(s, newSolveRequest(`{"solver": "greedy", "budget": 1, "term": {"year": 2023, "semester": "winter"}}`))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d instead of %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var run result.Run
+	decode(t, w, &run)
+
+	if location := w.Header().Get("Location"); location != "runs/"+run.ID {
+		t.Errorf("location %q for run %s", location, run.ID)
+	}
+
+	// device-a covers the most lectures and is kept within the budget.
+	if len(run.SelectedDevices) != 1 || run.SelectedDevices[0] != "device-a" {
+		t.Errorf("selected %v instead of [device-a]", run.SelectedDevices)
+	}
+
+	if run.Requests != 0 {
+		t.Errorf("%d requests created without commit", run.Requests)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodGet, "/runs", nil))
+
+	var summaries []RunSummary
+	decode(t, w, &summaries)
+
+	if len(summaries) != 1 || summaries[0].ID != run.ID || summaries[0].Selected != 1 || summaries[0].Term != "winter 2023" {
+		t.Errorf("summaries %+v for run %s", summaries, run.ID)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodGet, "/runs/"+run.ID, nil))
+
+	var kept result.Run
+	decode(t, w, &kept)
+
+	if w.Code != http.StatusOK || kept.ID != run.ID {
+		t.Errorf("status %d and run %s instead of %s", w.Code, kept.ID, run.ID)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodGet, "/runs/"+run.ID+"/explanation", nil))
+
+	var explanation result.Explanation
+	decode(t, w, &explanation)
+
+	if w.Code != http.StatusOK || explanation.RunID != run.ID || len(explanation.Devices) != 1 {
+		t.Errorf("status %d and explanation %+v of run %s", w.Code, explanation, run.ID)
+	}
+}
+
+func TestSolveCommits(t *testing.T) {
+	s := newTestServer(t)
+	r := newSolveRequest(`{"mode": "commit"}`)
+	w := serve(s, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d instead of %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var run result.Run
+	decode(t, w, &run)
+
+	if run.Requests != len(run.SelectedDevices) || run.Requests == 0 {
+		t.Errorf("%d requests for %d selected devices", run.Requests, len(run.SelectedDevices))
+	}
+}
+
+func TestKeepDropsTheOldestRuns(t *testing.T) {
+	s := newTestServer(t)
+	s.Config.MaxRuns = 2
+
+	var ids []string
+
+	for i := 0; i < 3; i++ {
+		w := serve(s, newSolveRequest(""))
+
+		var run result.Run
+		decode(t, w, &run)
+		ids = append(ids, run.ID)
+	}
+
+	if w := serve(s, httptest.NewRequest(http.MethodGet, "/runs/"+ids[0], nil)); w.Code != http.StatusNotFound {
+		t.Errorf("oldest run still kept with status %d", w.Code)
+	}
+
+	for _, id := range ids[1:] {
+		if w := serve(s, httptest.NewRequest(http.MethodGet, "/runs/"+id, nil)); w.Code != http.StatusOK {
+			t.Errorf("run %s dropped with status %d", id, w.Code)
+		}
+	}
+}
+
+func TestSolveStopsForDisconnectedClients(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("before the selection", func(t *testing.T) {
+		s := newTestServer(t)
+		w := serve(s, newSolveRequest(`{"mode": "commit"}`).WithContext(ctx))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status %d instead of %d", w.Code, http.StatusServiceUnavailable)
+		}
+
+		if len(s.order) != 0 {
+			t.Errorf("%d runs kept", len(s.order))
+		}
+
+		if len(s.solving) != 0 {
+			t.Error("the selection token was not released")
+		}
+	})
+
+	t.Run("waiting for another selection", func(t *testing.T) {
+		s := newTestServer(t)
+		s.solving <- struct{}{}
+		w := serve(s, newSolveRequest(`{"mode": "commit"}`).WithContext(ctx))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status %d instead of %d", w.Code, http.StatusServiceUnavailable)
+		}
+
+		if len(s.order) != 0 {
+			t.Errorf("%d runs kept", len(s.order))
+		}
+	})
+}
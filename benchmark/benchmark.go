@@ -0,0 +1,219 @@
+// Package benchmark runs the registered solvers over generated instance
+// families and captured scenarios and compares their solutions with a lower
+// bound. The timings and allocations of the solvers are measured by
+// BenchmarkSolvers with `go test -bench . ./benchmark`.
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"test-student-lecture-selection-algorithm/generator"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/scenario"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+// Instance is a problem the solvers are run on: the lectures and the
+// enrollments of the devices that can be selected.
+type Instance struct {
+	Name        string
+	Lectures    []string
+	Enrollments []model.IOSDeviceLecture
+}
+
+// Input builds a new input of the solvers, as they modify their input.
+func (i *Instance) Input() (*solver.LectureToOverlapped, *solver.DeviceToOverlappedLectures) {
+	lectures := make(solver.LectureToOverlapped, len(i.Lectures))
+
+	for _, lectureId := range i.Lectures {
+		lectures[lectureId] = &solver.LectureOverlapped{LectureId: lectureId}
+	}
+
+	return &lectures, solver.DevicesLecturesToMap(&i.Enrollments, &lectures)
+}
+
+// Family is a generated kind of instance.
+type Family struct {
+	Name   string
+	Config generator.Config
+}
+
+// Families returns the matrix of every size, density and skew. The density
+// is the mean number of lectures per student, the skew the exponent of the
+// Zipf distributed popularity of the lectures.
+func Families(seed int64) []Family {
+	sizes := []struct {
+		name     string
+		devices  int
+		lectures int
+	}{
+		{"small", 2000, 100},
+		{"medium", 20000, 500},
+	}
+	densities := []struct {
+		name    string
+		courses float64
+	}{
+		{"sparse", 3},
+		{"dense", 8},
+	}
+	skews := []struct {
+		name     string
+		exponent float64
+	}{
+		{"flat", 1.05},
+		{"skewed", 2},
+	}
+
+	var families []Family
+
+	for _, size := range sizes {
+		for _, density := range densities {
+			for _, skew := range skews {
+				config := generator.DefaultConfig()
+				config.Seed = seed
+				config.Now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+				config.Devices = size.devices
+				config.Lectures = size.lectures
+				config.CoursesMean = density.courses
+				config.ZipfExponent = skew.exponent
+				config.MaxCourses = 2 * int(density.courses)
+				config.RequestsPerDevice = 0
+
+				families = append(families, Family{
+					Name:   fmt.Sprintf("%s-%s-%s", size.name, density.name, skew.name),
+					Config: config,
+				})
+			}
+		}
+	}
+
+	return families
+}
+
+// Generate builds the instance of the family.
+func (f *Family) Generate() (*Instance, error) {
+	g, err := generator.NewGenerator(f.Config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo := memory.New()
+
+	if _, err := g.Generate(repo); err != nil {
+		return nil, err
+	}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	enrollments, err := repo.GetReadyDeviceLectures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	instance := Instance{Name: f.Name, Enrollments: *enrollments}
+
+	for _, lecture := range *lectures {
+		instance.Lectures = append(instance.Lectures, lecture.Id)
+	}
+
+	return &instance, nil
+}
+
+// FromScenario builds the instance of a scenario from its active devices.
+func FromScenario(name string, s *scenario.Scenario) *Instance {
+	lectures, devicesLectures := s.SolverInput()
+	instance := Instance{Name: name}
+
+	for lectureId := range *lectures {
+		instance.Lectures = append(instance.Lectures, lectureId)
+	}
+
+	sort.Strings(instance.Lectures)
+
+	for deviceId, deviceLectures := range *devicesLectures {
+		for _, lecture := range deviceLectures {
+			instance.Enrollments = append(instance.Enrollments, model.IOSDeviceLecture{DeviceId: deviceId, LectureId: lecture.LectureId})
+		}
+	}
+
+	sort.Slice(instance.Enrollments, func(i, j int) bool {
+		a, b := instance.Enrollments[i], instance.Enrollments[j]
+
+		if a.DeviceId != b.DeviceId {
+			return a.DeviceId < b.DeviceId
+		}
+
+		return a.LectureId < b.LectureId
+	})
+
+	return &instance
+}
+
+// Result is the outcome of a solver on an instance.
+type Result struct {
+	Instance string
+	Solver   string
+	Devices  int
+	Lectures int
+	// Size is the number of devices selected by the solver.
+	Size int
+	// Covered is the number of lectures covered by the selection.
+	Covered    int
+	LowerBound int
+}
+
+// Ratio returns the size of the solution relative to the lower bound, which
+// is an upper bound of how far the solution is from the optimum.
+func (r *Result) Ratio() float64 {
+	if r.LowerBound == 0 {
+		return 1
+	}
+
+	return float64(r.Size) / float64(r.LowerBound)
+}
+
+// Run solves instance with the solver registered as name and checks the
+// solution. The solvers are deterministic, so the same instance always
+// yields the same Result and greedy-pruned prunes the selection greedy
+// reports.
+func Run(instance *Instance, name string) (*Result, error) {
+	solve, err := solver.Get(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lectures, devicesLectures := instance.Input()
+
+	result := Result{
+		Instance:   instance.Name,
+		Solver:     name,
+		Devices:    len(*devicesLectures),
+		Lectures:   len(instance.Lectures),
+		LowerBound: solver.LowerBound(devicesLectures),
+	}
+
+	enrolled := solver.Enrolled(devicesLectures)
+	assignments := solve(devicesLectures, lectures)
+
+	if err := solver.Verify(assignments, enrolled); err != nil {
+		return nil, fmt.Errorf("solver %s on %s: %w", name, instance.Name, err)
+	}
+
+	result.Size = len(*assignments)
+
+	for _, assignment := range *assignments {
+		result.Covered += len(assignment.LectureIds)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,131 @@
+package benchmark
+
+import (
+	"path/filepath"
+	"strings"
+	"test-student-lecture-selection-algorithm/scenario"
+	"test-student-lecture-selection-algorithm/solver"
+	"testing"
+)
+
+// BenchmarkSolvers measures every registered solver on every generated
+// instance family. Building the input is not measured.
+func BenchmarkSolvers(b *testing.B) {
+	for _, family := range Families(1) {
+		family := family
+
+		b.Run(family.Name, func(b *testing.B) {
+			instance, err := family.Generate()
+
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for _, name := range solver.Names() {
+				solve, err := solver.Get(name)
+
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.Run(name, func(b *testing.B) {
+					b.ReportAllocs()
+
+					for i := 0; i < b.N; i++ {
+						b.StopTimer()
+						lectures, devicesLectures := instance.Input()
+						b.StartTimer()
+
+						solve(devicesLectures, lectures)
+					}
+				})
+			}
+		})
+	}
+}
+
+// smallInstances returns the small generated families, which are quick
+// enough for the tests, and the bundled scenarios.
+func smallInstances(t *testing.T) []*Instance {
+	t.Helper()
+
+	var instances []*Instance
+
+	for _, family := range Families(1) {
+		if !strings.HasPrefix(family.Name, "small-") {
+			continue
+		}
+
+		instance, err := family.Generate()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		instances = append(instances, instance)
+	}
+
+	paths, err := filepath.Glob(filepath.Join("..", "scenarios", "*.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		s, err := scenario.Load(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		instances = append(instances, FromScenario(path, s))
+	}
+
+	return instances
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	for _, instance := range smallInstances(t) {
+		for _, name := range solver.Names() {
+			first, err := Run(instance, name)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			second, err := Run(instance, name)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *first != *second {
+				t.Errorf("%s on %s: %+v and %+v differ", name, instance.Name, first, second)
+			}
+
+			if first.Size < first.LowerBound {
+				t.Errorf("%s on %s: %d devices are below the lower bound %d", name, instance.Name, first.Size, first.LowerBound)
+			}
+		}
+	}
+}
+
+func TestPruningNeverReportsMoreDevices(t *testing.T) {
+	for _, instance := range smallInstances(t) {
+		greedy, err := Run(instance, "greedy")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pruned, err := Run(instance, "greedy-pruned")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pruned.Size > greedy.Size || pruned.Covered != greedy.Covered {
+			t.Errorf("%s: greedy-pruned selected %d devices covering %d lectures, greedy %d covering %d", instance.Name, pruned.Size, pruned.Covered, greedy.Size, greedy.Covered)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var columns = []string{
+	"instance",
+	"solver",
+	"devices",
+	"lectures",
+	"size",
+	"covered",
+	"lower_bound",
+	"ratio",
+}
+
+func (r *Result) row() []string {
+	return []string{
+		r.Instance,
+		r.Solver,
+		strconv.Itoa(r.Devices),
+		strconv.Itoa(r.Lectures),
+		strconv.Itoa(r.Size),
+		strconv.Itoa(r.Covered),
+		strconv.Itoa(r.LowerBound),
+		strconv.FormatFloat(r.Ratio(), 'f', 3, 64),
+	}
+}
+
+// WriteCSV writes the results as CSV with a header.
+func WriteCSV(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if err := writer.Write(result.row()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteMarkdown writes the results as a Markdown table.
+func WriteMarkdown(w io.Writer, results []Result) error {
+	separators := make([]string, len(columns))
+
+	for i := range separators {
+		separators[i] = "---"
+	}
+
+	lines := []string{
+		"| " + strings.Join(columns, " | ") + " |",
+		"| " + strings.Join(separators, " | ") + " |",
+	}
+
+	for _, result := range results {
+		lines = append(lines, "| "+strings.Join(result.row(), " | ")+" |")
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+
+	return err
+}
+
+// Write writes the results in format, which is "csv" or "markdown".
+func Write(w io.Writer, results []Result, format string) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, results)
+	case "markdown", "md":
+		return WriteMarkdown(w, results)
+	}
+
+	return fmt.Errorf("unknown format %q, expected csv or markdown", format)
+}
@@ -0,0 +1,122 @@
+// Package callback handles the answers of the devices to the requests sent
+// by background push notifications. It is the third step of the protocol
+// described at model.IOSDeviceRequestLog.
+package callback
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid response")
+	ErrUnknownRequest  = errors.New("unknown request")
+	ErrDeviceMismatch  = errors.New("request belongs to another device")
+	ErrAlreadyHandled  = errors.New("request was already handled")
+	ErrExpired         = errors.New("request expired")
+	ErrSuperseded      = errors.New("request was superseded by a newer request")
+)
+
+// Response is the answer of a device to a request.
+type Response struct {
+	RequestID string          `json:"requestId"`
+	DeviceID  string          `json:"deviceId"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Handler struct {
+	Repository repository.Repository
+	// Now returns the current time. It can be replaced to simulate answers
+	// at a different time.
+	Now func() time.Time
+}
+
+func NewHandler(repo repository.Repository) *Handler {
+	return &Handler{
+		Repository: repo,
+		Now:        time.Now,
+	}
+}
+
+// Handle validates the RequestID of the response and marks the request as
+// handled.
+func (h *Handler) Handle(response *Response) (*model.IOSDeviceRequestLog, error) {
+	if response.RequestID == "" || response.DeviceID == "" {
+		return nil, ErrInvalidResponse
+	}
+
+	now := h.Now()
+
+	requestLog, err := h.Repository.HandleRequest(response.RequestID, now, func(requestLog *model.IOSDeviceRequestLog) error {
+		if requestLog.DeviceID != response.DeviceID {
+			return ErrDeviceMismatch
+		}
+
+		switch {
+		case requestLog.Status == model.IOSRequestStatusHandled || requestLog.HandledAt.Valid:
+			return ErrAlreadyHandled
+		case requestLog.Status == model.IOSRequestStatusSuperseded:
+			return ErrSuperseded
+		case requestLog.IsExpiredAt(now):
+			return ErrExpired
+		case !requestLog.CanTransitionTo(model.IOSRequestStatusHandled):
+			return ErrInvalidResponse
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnknownRequest
+	}
+
+	return requestLog, err
+}
+
+// ServeHTTP accepts a Response as JSON body.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response Response
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		http.Error(w, ErrInvalidResponse.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.Handle(&response)
+
+	if err != nil {
+		log.WithError(err).WithField("requestId", response.RequestID).Warn("Rejected device response")
+
+		http.Error(w, err.Error(), statusCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidResponse):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownRequest):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDeviceMismatch):
+		return http.StatusForbidden
+	case errors.Is(err, ErrAlreadyHandled):
+		return http.StatusConflict
+	case errors.Is(err, ErrExpired), errors.Is(err, ErrSuperseded):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
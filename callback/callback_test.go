@@ -0,0 +1,308 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+// newTestHandler returns a handler of a repository in which device-a is
+// enrolled in lecture-1 and lecture-2 and device-b in lecture-3.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	repo := memory.New()
+	devices := []model.IOSDevice{{DeviceID: "device-a"}, {DeviceID: "device-b"}}
+	lectures := []model.IOSLecture{
+		{Id: "lecture-1", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-2", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-3", Semester: model.IOSLectureSemesterWinter},
+	}
+	deviceLectures := []model.IOSDeviceLecture{
+		{DeviceId: "device-a", LectureId: "lecture-1"},
+		{DeviceId: "device-a", LectureId: "lecture-2"},
+		{DeviceId: "device-b", LectureId: "lecture-3"},
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&lectures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDeviceLectures(&deviceLectures); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHandler(repo)
+}
+
+// createRequest creates a lecture update request of deviceId covering
+// lectureIds.
+func createRequest(t *testing.T, h *Handler, deviceId string, lectureIds ...string) model.IOSDeviceRequestLog {
+	t.Helper()
+
+	requests, err := h.Repository.CreateLectureUpdateRequests(map[string][]string{deviceId: lectureIds})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return (*requests)[0]
+}
+
+func lastUpdates(t *testing.T, h *Handler) map[string]time.Time {
+	t.Helper()
+
+	lectures, err := h.Repository.GetLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(map[string]time.Time)
+
+	for _, lecture := range *lectures {
+		updates[lecture.Id] = lecture.LastUpdate
+	}
+
+	return updates
+}
+
+func TestHandleRefreshesAllLecturesOfTheDevice(t *testing.T) {
+	h := newTestHandler(t)
+	request := createRequest(t, h, "device-a", "lecture-2")
+	now := time.Now().Truncate(time.Second)
+	h.Now = func() time.Time { return now }
+
+	handled, err := h.Handle(&Response{RequestID: request.RequestID, DeviceID: "device-a"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if handled.Status != model.IOSRequestStatusHandled || !handled.HandledAt.Time.Equal(now) {
+		t.Errorf("request is %s at %v, expected handled at %s", handled.Status, handled.HandledAt, now)
+	}
+
+	updates := lastUpdates(t, h)
+
+	for _, lectureId := range []string{"lecture-1", "lecture-2"} {
+		if !updates[lectureId].Equal(now) {
+			t.Errorf("%s was updated at %s instead of %s", lectureId, updates[lectureId], now)
+		}
+	}
+
+	if updates["lecture-3"].Equal(now) {
+		t.Error("lecture-3 was updated by a device not enrolled in it")
+	}
+}
+
+func TestHandleRejectsInvalidResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		// respond answers a request to device-a and returns the response.
+		respond func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response
+		err     error
+	}{
+		{
+			name: "missing request id",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				return &Response{DeviceID: "device-a"}
+			},
+			err: ErrInvalidResponse,
+		},
+		{
+			name: "unknown request",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				return &Response{RequestID: "unknown", DeviceID: "device-a"}
+			},
+			err: ErrUnknownRequest,
+		},
+		{
+			name: "other device",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				return &Response{RequestID: request.RequestID, DeviceID: "device-b"}
+			},
+			err: ErrDeviceMismatch,
+		},
+		{
+			name: "duplicate",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				response := &Response{RequestID: request.RequestID, DeviceID: "device-a"}
+
+				if _, err := h.Handle(response); err != nil {
+					t.Fatal(err)
+				}
+
+				return response
+			},
+			err: ErrAlreadyHandled,
+		},
+		{
+			name: "superseded",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				createRequest(t, h, "device-a", "lecture-1")
+
+				return &Response{RequestID: request.RequestID, DeviceID: "device-a"}
+			},
+			err: ErrSuperseded,
+		},
+		{
+			name: "expired",
+			respond: func(t *testing.T, h *Handler, request model.IOSDeviceRequestLog) *Response {
+				h.Now = func() time.Time { return request.ExpiresAt.Time.Add(time.Second) }
+
+				return &Response{RequestID: request.RequestID, DeviceID: "device-a"}
+			},
+			err: ErrExpired,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			request := createRequest(t, h, "device-a", "lecture-1")
+
+			_, err := h.Handle(test.respond(t, h, request))
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	h := newTestHandler(t)
+	request := createRequest(t, h, "device-a", "lecture-1")
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid body", http.MethodPost, "{", http.StatusBadRequest},
+		{"unknown request", http.MethodPost, `{"requestId": "unknown", "deviceId": "device-a"}`, http.StatusNotFound},
+		{"other device", http.MethodPost, `{"requestId": "` + request.RequestID + `", "deviceId": "device-b"}`, http.StatusForbidden},
+		{"answer", http.MethodPost, `{"requestId": "` + request.RequestID + `", "deviceId": "device-a"}`, http.StatusNoContent},
+		{"duplicate", http.MethodPost, `{"requestId": "` + request.RequestID + `", "deviceId": "device-a"}`, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+
+		h.ServeHTTP(recorder, httptest.NewRequest(test.method, "/callback", strings.NewReader(test.body)))
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, recorder.Code)
+		}
+	}
+}
+
+func TestSimulatedDevicesAnswer(t *testing.T) {
+	h := newTestHandler(t)
+	requests, err := h.Repository.CreateLectureUpdateRequests(map[string][]string{
+		"device-a": {"lecture-1", "lecture-2"},
+		"device-b": {"lecture-3"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devices := NewSimulatedDevices(h, 1, 0)
+
+	if err := devices.Send(context.Background(), 1, requests); err != nil {
+		t.Fatal(err)
+	}
+
+	requestIds := make([]string, 0, len(*requests))
+
+	for _, request := range *requests {
+		requestIds = append(requestIds, request.RequestID)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		responded, err := h.Repository.GetHandledRequestsDevices(&requestIds)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(*responded) == len(requestIds) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d simulated devices answered", len(*responded), len(requestIds))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	requestLogs, err := h.Repository.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, requestLog := range *requestLogs {
+		if requestLog.Status != model.IOSRequestStatusHandled || requestLog.Attempts != 1 {
+			t.Errorf("request of %s is %s after %d attempts, expected handled after 1", requestLog.DeviceID, requestLog.Status, requestLog.Attempts)
+		}
+	}
+}
+
+func TestSimulatedDevicesSilent(t *testing.T) {
+	h := newTestHandler(t)
+	request := createRequest(t, h, "device-a", "lecture-1")
+	requests := []model.IOSDeviceRequestLog{request}
+
+	if err := NewSimulatedDevices(h, 0, 0).Send(context.Background(), 1, &requests); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	requestLogs, err := h.Repository.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := (*requestLogs)[0].Status; status != model.IOSRequestStatusSent {
+		t.Errorf("request of a silent device is %s instead of sent", status)
+	}
+}
+
+func TestHandleAnswerAfterFailedDelivery(t *testing.T) {
+	h := newTestHandler(t)
+	request := createRequest(t, h, "device-a", "lecture-1")
+
+	if _, err := h.Repository.MarkRequestsAttempted(&[]string{request.RequestID}, model.IOSRequestStatusDeliveryFailed); err != nil {
+		t.Fatal(err)
+	}
+
+	handled, err := h.Handle(&Response{RequestID: request.RequestID, DeviceID: "device-a"})
+
+	if err != nil {
+		t.Fatalf("answer to a request whose delivery failed was rejected: %v", err)
+	}
+
+	if handled.Status != model.IOSRequestStatusHandled {
+		t.Errorf("request is %s instead of handled", handled.Status)
+	}
+}
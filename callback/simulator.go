@@ -0,0 +1,73 @@
+package callback
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+// SimulatedDevices is a stand-in for real devices. It answers a share of the
+// requests it is sent through the Handler, after a random latency, and can
+// be used as dispatch.Sender to run a dispatch without push delivery.
+type SimulatedDevices struct {
+	Handler *Handler
+	// ResponseRate is the probability (0 to 1) that a device answers.
+	ResponseRate float64
+	// MaxLatency is the maximum time a device takes to answer.
+	MaxLatency time.Duration
+	Rand       *rand.Rand
+}
+
+func NewSimulatedDevices(handler *Handler, responseRate float64, maxLatency time.Duration) *SimulatedDevices {
+	return &SimulatedDevices{
+		Handler:      handler,
+		ResponseRate: responseRate,
+		MaxLatency:   maxLatency,
+		Rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Send lets the simulated devices answer the requests in the background.
+func (s *SimulatedDevices) Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error {
+	answering := 0
+	requestIds := make([]string, 0, len(*requests))
+
+	for _, request := range *requests {
+		requestIds = append(requestIds, request.RequestID)
+	}
+
+	if _, err := s.Handler.Repository.MarkRequestsAttempted(&requestIds, model.IOSRequestStatusSent); err != nil {
+		return err
+	}
+
+	for _, request := range *requests {
+		if s.Rand.Float64() >= s.ResponseRate {
+			continue
+		}
+
+		answering++
+
+		latency := time.Duration(s.Rand.Int63n(int64(s.MaxLatency) + 1))
+		response := Response{
+			RequestID: request.RequestID,
+			DeviceID:  request.DeviceID,
+		}
+
+		time.AfterFunc(latency, func() {
+			s.Answer(&response)
+		})
+	}
+
+	log.Infof("Round %d: %d/%d simulated devices will answer", round, answering, len(*requests))
+
+	return nil
+}
+
+// Answer sends the response of a simulated device to the Handler.
+func (s *SimulatedDevices) Answer(response *Response) {
+	if _, err := s.Handler.Handle(response); err != nil {
+		log.WithError(err).WithField("requestId", response.RequestID).Warn("Simulated response was rejected")
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFlags are the flags of a command named solve.
+type testFlags struct {
+	set       *flag.FlagSet
+	dsn       *string
+	solver    *string
+	timeout   *time.Duration
+	chunkSize *int
+	encrypt   *bool
+}
+
+func newTestFlags() *testFlags {
+	set := flag.NewFlagSet("solve", flag.ContinueOnError)
+	set.SetOutput(&strings.Builder{})
+
+	return &testFlags{
+		set:       set,
+		dsn:       set.String("dsn", "", "database"),
+		solver:    set.String("solver", "greedy", "solver"),
+		timeout:   set.Duration("round-timeout", 2*time.Minute, "timeout"),
+		chunkSize: set.Int("chunk-size", 10000, "chunk size"),
+		encrypt:   set.Bool("encrypt", false, "encrypt"),
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// known reports schedule as another command and push as a flag of another
+// command.
+func known(name string) bool {
+	return name == "schedule" || name == "push"
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"dsn":           "DB_DSN",
+		"round-timeout": "SELECTION_ROUND_TIMEOUT",
+		"config":        "SELECTION_CONFIG",
+	}
+
+	for name, envName := range tests {
+		if got := EnvName(name); got != envName {
+			t.Errorf("%s: expected %s, got %s", name, envName, got)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	path := writeFile(t, `{
+		"dsn": "sqlite://file.db",
+		"solver": "greedy-pruned",
+		"round-timeout": "10m",
+		"chunk-size": 500,
+		"solve": {"round-timeout": "5m", "encrypt": true},
+		"schedule": {"round-timeout": "1h"}
+	}`)
+
+	t.Setenv("DB_DSN", "sqlite://env.db")
+	t.Setenv("SELECTION_ROUND_TIMEOUT", "30s")
+	t.Setenv("SELECTION_CHUNK_SIZE", "200")
+
+	f := newTestFlags()
+
+	if err := Parse(f.set, []string{"-config", path, "-chunk-size", "100"}, known); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"flag over environment and file", *f.chunkSize, 100},
+		{"environment over file", *f.timeout, 30 * time.Second},
+		{"DB_DSN over file", *f.dsn, "sqlite://env.db"},
+		{"file over default", *f.solver, "greedy-pruned"},
+		{"section of the command", *f.encrypt, true},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, test.got)
+		}
+	}
+}
+
+func TestParseCommandSectionOverFile(t *testing.T) {
+	path := writeFile(t, `{"round-timeout": "10m", "solve": {"round-timeout": "5m"}, "schedule": {"round-timeout": "1h"}}`)
+	t.Setenv(EnvName(FileFlag), path)
+
+	f := newTestFlags()
+
+	if err := Parse(f.set, nil, known); err != nil {
+		t.Fatal(err)
+	}
+
+	if *f.timeout != 5*time.Minute {
+		t.Errorf("expected the timeout of the solve section, got %s", *f.timeout)
+	}
+
+	if *f.solver != "greedy" || *f.chunkSize != 10000 {
+		t.Errorf("defaults were changed to %s and %d", *f.solver, *f.chunkSize)
+	}
+}
+
+func TestParseRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+	}{
+		{"unknown setting", `{"solverr": "greedy"}`, nil},
+		{"unknown command", `{"sovle": {"solver": "greedy"}}`, nil},
+		{"config in the file", `{"config": "other.json"}`, nil},
+		{"invalid value", `{"round-timeout": "soon"}`, nil},
+		{"array value", `{"solver": ["greedy"]}`, nil},
+		{"invalid json", `{"solver": `, nil},
+		{"invalid environment", `{}`, map[string]string{"SELECTION_CHUNK_SIZE": "many"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			if err := Parse(newTestFlags().set, []string{"-config", writeFile(t, test.content)}, known); err == nil {
+				t.Error("settings were accepted")
+			}
+		})
+	}
+}
+
+func TestParseIgnoresFlagsOfOtherCommands(t *testing.T) {
+	path := writeFile(t, `{"push": "apns", "solver": "greedy-pruned"}`)
+	f := newTestFlags()
+
+	if err := Parse(f.set, []string{"-config", path}, known); err != nil {
+		t.Fatal(err)
+	}
+
+	if *f.solver != "greedy-pruned" {
+		t.Errorf("expected greedy-pruned, got %s", *f.solver)
+	}
+}
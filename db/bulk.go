@@ -0,0 +1,64 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+// BulkLoad writes every table of the dataset with multi-row INSERTs of
+// config.BatchSize rows. With config.Upsert the INSERTs update all columns
+// but the primary key and the creation time on conflict.
+func (r *Repository) BulkLoad(dataset *repository.Dataset, config repository.BulkConfig) (*repository.BulkStats, error) {
+	startTime := time.Now()
+	stats := repository.BulkStats{}
+
+	tables := []struct {
+		name    string
+		rows    int
+		records interface{}
+	}{
+		{"ios_lectures", len(dataset.Lectures), &dataset.Lectures},
+		{"ios_devices", len(dataset.Devices), &dataset.Devices},
+		{"ios_device_lectures", len(dataset.DeviceLectures), &dataset.DeviceLectures},
+		{"ios_device_request_logs", len(dataset.RequestLogs), &dataset.RequestLogs},
+		{"ios_scheduling_priorities", len(dataset.Priorities), &dataset.Priorities},
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			if table.rows == 0 {
+				continue
+			}
+
+			tableStartTime := time.Now()
+			insert := tx.Omit(clause.Associations)
+
+			if config.Upsert {
+				insert = insert.Clauses(clause.OnConflict{UpdateAll: true})
+			}
+
+			if err := insert.CreateInBatches(table.records, config.Size()).Error; err != nil {
+				return err
+			}
+
+			stats.Tables = append(stats.Tables, repository.TableStats{
+				Table:   table.name,
+				Rows:    table.rows,
+				Batches: config.Batches(table.rows),
+				Elapsed: time.Now().Sub(tableStartTime),
+			})
+		}
+
+		return nil
+	})
+
+	stats.Elapsed = time.Now().Sub(startTime)
+
+	if err != nil {
+		return nil, repository.QueryError("bulk load", err)
+	}
+
+	return &stats, nil
+}
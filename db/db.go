@@ -1,299 +1,94 @@
+// Package db implements the repository.Repository with gorm on MySQL,
+// PostgreSQL and SQLite. The database is selected by the scheme of the DSN,
+// see ParseDSN.
 package db
 
 import (
-	"database/sql"
-	"github.com/bxcodec/faker/v4"
 	log "github.com/sirupsen/logrus"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"math/rand"
-	"os"
 	"test-student-lecture-selection-algorithm/model"
-	"time"
+	"test-student-lecture-selection-algorithm/repository"
 )
 
-var DB *gorm.DB
-var dbHost = os.Getenv("DB_DSN")
-
-func Init() {
-	log.Infof("Connecting to dsn: %s\n", dbHost)
-
-	conn := mysql.Open(dbHost)
-
-	db, err := gorm.Open(conn, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-
-	if err != nil {
-		panic("failed to connect database")
-	}
-
-	DB = db
-
-	err = Migrate()
-
-	if err != nil {
-		log.WithError(err).Error("Migrate failed")
-		return
-	}
-
-	PopulateWithDummyData()
-}
-
-func Migrate() error {
-	err := DB.AutoMigrate(
-		&model.IOSDevice{},
-		&model.IOSDeviceRequestLog{},
-		&model.IOSEncryptedGrade{},
-		&model.IOSScheduledUpdateLog{},
-		&model.IOSSchedulingPriority{},
-		&model.IOSLecture{},
-		&model.IOSDeviceLecture{},
-	)
-	return err
-}
-
-func GetDevices() *[]model.IOSDevice {
-	var devices []model.IOSDevice
-
-	DB.Model(&model.IOSDevice{}).Find(&devices)
-
-	return &devices
-}
-
-func GetReadyDevices() *[]model.IOSDevice {
-	var devices []model.IOSDevice
-
-	DB.Find(&devices)
-
-	return &devices
-}
-
-func GetLectures() *[]model.IOSLecture {
-	var lectures []model.IOSLecture
-
-	DB.Find(&lectures)
-
-	return &lectures
-}
-
-func GetDeviceLectures() *[]model.IOSDeviceLecture {
-	var deviceLectures []model.IOSDeviceLecture
-
-	DB.Find(&deviceLectures)
-
-	return &deviceLectures
-}
-
-func GetLecturesThatHaveAtLeastOneDevice() *[]string {
-	var lectureIds []string
-
-	DB.Raw("select dl.lecture_id from ios_device_lectures dl group by dl.lecture_id;").Scan(&lectureIds)
-
-	return &lectureIds
-}
-
-func GetMaxAttendedLecturesCount() int {
-	var maxCount int
-
-	DB.Raw("select max(lecture_count) from (select count(*) as lecture_count from ios_device_lectures group by device_id) as t;").Scan(&maxCount)
-
-	return maxCount
-}
-
-func PopulateWithDummyData() {
-	devices := fakeDevices(20000)
-	lectures := fakeLectures(500)
-
-	fakeDeviceLectureRelation(devices, lectures)
-
-	fakeDevicesRequestLogs(devices)
-
-	log.Infof("Populated %d devices and %d lectures", len(*devices), len(*lectures))
+type Repository struct {
+	DB *gorm.DB
+	// RequestExpiry is how long the requests created by the repository can
+	// be answered.
+	RequestExpiry model.IOSRequestExpiry
 }
 
-func fakeDevices(count int) *[]model.IOSDevice {
-	var devicesCount int64
-	DB.Model(&model.IOSDevice{}).Count(&devicesCount)
-
-	if int(devicesCount) >= count {
-		log.Warn("Devices already populated")
-
-		return GetDevices()
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		DB:            db,
+		RequestExpiry: model.DefaultIOSRequestExpiry(),
 	}
-
-	var devices []model.IOSDevice
-
-	for i := 0; i < count; i++ {
-		device := model.IOSDevice{}
-
-		faker.FakeData(&device)
-
-		devices = append(devices, device)
-
-		if i%1000 == 0 {
-			DB.Create(&devices)
-			devices = []model.IOSDevice{}
-		}
-	}
-
-	DB.Create(&devices)
-
-	return GetDevices()
 }
 
-func fakeLectures(count int) *[]model.IOSLecture {
-	var lecturesCount int64
-	DB.Model(&model.IOSLecture{}).Count(&lecturesCount)
-
-	if int(lecturesCount) >= count {
-		log.Warn("Lectures already populated")
+// Open connects to the database of dsn and applies the pending migrations.
+// The returned error is of kind repository.ErrConnect or
+// repository.ErrMigrate.
+func Open(dsn string) (*Repository, error) {
+	r, err := Connect(dsn)
 
-		return GetLectures()
+	if err != nil {
+		return nil, err
 	}
 
-	var lectures []model.IOSLecture
-
-	for i := 0; i < count; i++ {
-		lecture := model.IOSLecture{}
-
-		faker.FakeData(&lecture)
-
-		lectures = append(lectures, lecture)
-
-		if i%1000 == 0 {
-			DB.Create(&lectures)
-			lectures = []model.IOSLecture{}
-		}
+	if err := r.Migrate(); err != nil {
+		return nil, err
 	}
 
-	DB.Create(&lectures)
-
-	return GetLectures()
+	return r, nil
 }
 
-func fakeDeviceLectureRelation(devices *[]model.IOSDevice, lectures *[]model.IOSLecture) {
-	var deviceLecturesCount int64
-	DB.Model(&model.IOSDeviceLecture{}).Count(&deviceLecturesCount)
+// Connect connects to the database of dsn without migrating it. The returned
+// error is of kind repository.ErrConnect.
+func Connect(dsn string) (*Repository, error) {
+	log.Infof("Connecting to dsn: %s\n", dsn)
 
-	if int(deviceLecturesCount) > 0 {
-		log.Warn("Lectures already populated")
+	conn, err := Dialector(dsn)
 
-		return
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrConnect, Op: "parse dsn", Err: err}
 	}
 
-	rand.Seed(time.Now().Unix())
-
-	tmpLectures := *lectures
-
-	var deviceLectures []model.IOSDeviceLecture
-
-	for _, device := range *devices {
-		numLectures := rand.Intn(8) + 1
+	db, err := gorm.Open(conn, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
 
-		for i := 0; i < numLectures; i++ {
-			lecture := tmpLectures[0]
-			deviceLecture := model.IOSDeviceLecture{
-				LectureId: lecture.Id,
-				DeviceId:  device.DeviceID,
-			}
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrConnect, Op: "open", Err: err}
+	}
 
-			deviceLectures = append(deviceLectures, deviceLecture)
+	// SQLite allows only one writer at a time and every connection to
+	// :memory: opens a database of its own.
+	if db.Dialector.Name() == DialectSQLite {
+		sqlDB, err := db.DB()
 
-			// Remove the assigned lecture from the list
-			tmpLectures = tmpLectures[1:]
-			if len(tmpLectures) == 0 {
-				tmpLectures = *lectures
-				break
-			}
+		if err != nil {
+			return nil, &repository.Error{Kind: repository.ErrConnect, Op: "open", Err: err}
 		}
 
-		if len(deviceLectures)%1000 == 0 {
-			DB.Create(&deviceLectures)
-			deviceLectures = []model.IOSDeviceLecture{}
-		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 
-	DB.Create(&deviceLectures)
+	return New(db), nil
 }
 
-func fakeDevicesRequestLogs(devices *[]model.IOSDevice) {
-	var devicesRequestLogs int64
-	DB.Model(&model.IOSDeviceRequestLog{}).Count(&devicesRequestLogs)
-
-	if int(devicesRequestLogs) > 0 {
-		log.Warn("Request logs already populated")
-
-		return
-	}
-
-	var requestLogs []model.IOSDeviceRequestLog
-
-	for _, device := range *devices {
-		requestLogsCount := rand.Intn(10) + 1
-
-		for i := 0; i < requestLogsCount; i++ {
-			timeToAdd := time.Duration(rand.Intn(50)+10+i*30) * time.Minute * -1
-
-			createdRequest := time.Now().Add(timeToAdd)
-
-			handledRequest := sql.NullTime{
-				Time:  createdRequest.Add(time.Duration(rand.Intn(59)+1) * time.Second),
-				Valid: true,
-			}
-
-			if rand.Intn(2) == 0 {
-				handledRequest = sql.NullTime{
-					Time:  time.Time{},
-					Valid: false,
-				}
-			}
-
-			requestLog := model.IOSDeviceRequestLog{
-				DeviceID:    device.DeviceID,
-				RequestType: model.IOSLectureUpdateRequestType,
-				CreatedAt:   createdRequest,
-				HandledAt:   handledRequest,
-			}
-
-			requestLogs = append(requestLogs, requestLog)
-
-			if len(requestLogs)%1000 == 0 {
-				DB.Create(&requestLogs)
-				requestLogs = []model.IOSDeviceRequestLog{}
-			}
-		}
-	}
-
-	DB.Create(&requestLogs)
+// replacedIndexes were replaced by other indexes and are dropped when a
+// database created by AutoMigrate is adopted.
+//
+// The unique index on the device allowed only one IOSScheduledUpdateLog per
+// device regardless of its type, it is replaced by the unique index on the
+// device and type.
+//
+// The unique index on the creation time failed as soon as the logs of
+// several devices were moved to the same time, e.g. by
+// RecordScheduledUpdates, it is replaced by a non-unique index.
+var replacedIndexes = []string{
+	"idx_scheduled_update_log_device",
+	"idx_scheduled_update_log_created",
 }
 
-func pickNRandomElements[T interface{}](n int, elements *[]T) []T {
-	if n > len(*elements) {
-		log.Panicf("n (%d) is greater than elements length (%d)", n, len(*elements))
-	}
-
-	rand.Seed(time.Now().Unix())
-
-	elementsMap := map[int]T{}
-
-	for i := 0; i < n; i++ {
-		randomIndex := rand.Intn(len(*elements))
-
-		if _, ok := elementsMap[randomIndex]; ok {
-			i--
-			continue
-		}
-
-		elementsMap[randomIndex] = (*elements)[randomIndex]
-	}
-
-	var result []T
-
-	for _, element := range elementsMap {
-		result = append(result, element)
-	}
-
-	return result
-}
+var _ repository.Repository = (*Repository)(nil)
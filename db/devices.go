@@ -0,0 +1,143 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+func (r *Repository) GetDevices() (*[]model.IOSDevice, error) {
+	var devices []model.IOSDevice
+
+	err := r.DB.Model(&model.IOSDevice{}).Find(&devices).Error
+
+	return &devices, repository.QueryError("get devices", err)
+}
+
+func (r *Repository) GetDevicesByIds(deviceIds *[]string) (*[]model.IOSDevice, error) {
+	var devices []model.IOSDevice
+
+	if len(*deviceIds) == 0 {
+		return &devices, nil
+	}
+
+	err := r.DB.Where("device_id IN ?", *deviceIds).Find(&devices).Error
+
+	return &devices, repository.QueryError("get devices by ids", err)
+}
+
+// GetReadyDevices returns the devices that can be selected, i.e. the ones in
+// state active.
+func (r *Repository) GetReadyDevices() (*[]model.IOSDevice, error) {
+	var devices []model.IOSDevice
+
+	err := r.DB.Where("state = ?", model.IOSDeviceStateActive).Find(&devices).Error
+
+	return &devices, repository.QueryError("get ready devices", err)
+}
+
+// GetDevicesInState returns the devices in state whose state changed before
+// changedBefore or was never changed.
+func (r *Repository) GetDevicesInState(state string, changedBefore time.Time) (*[]model.IOSDevice, error) {
+	var devices []model.IOSDevice
+
+	err := r.DB.Where("state = ? AND (state_changed_at IS NULL OR state_changed_at < ?)", state, changedBefore).
+		Find(&devices).
+		Error
+
+	return &devices, repository.QueryError("get devices in state", err)
+}
+
+func (r *Repository) CreateDevices(devices *[]model.IOSDevice) error {
+	if len(*devices) == 0 {
+		return nil
+	}
+
+	return repository.QueryError("create devices", r.DB.CreateInBatches(devices, 1000).Error)
+}
+
+// SetDevicesState moves the devices to state. Devices that already are in
+// state keep their StateChangedAt.
+func (r *Repository) SetDevicesState(deviceIds *[]string, state string, changedAt time.Time) (int64, error) {
+	if len(*deviceIds) == 0 {
+		return 0, nil
+	}
+
+	result := r.DB.Model(&model.IOSDevice{}).
+		Where("device_id IN ? AND state <> ?", *deviceIds, state).
+		Updates(map[string]interface{}{
+			"state":            state,
+			"state_changed_at": changedAt,
+		})
+
+	return result.RowsAffected, repository.QueryError("set devices state", result.Error)
+}
+
+// SetDevicesStateFrom moves the devices that are in state from to state to.
+func (r *Repository) SetDevicesStateFrom(deviceIds *[]string, from string, to string, changedAt time.Time) (int64, error) {
+	if len(*deviceIds) == 0 {
+		return 0, nil
+	}
+
+	result := r.DB.Model(&model.IOSDevice{}).
+		Where("device_id IN ? AND state = ?", *deviceIds, from).
+		Updates(map[string]interface{}{
+			"state":            to,
+			"state_changed_at": changedAt,
+		})
+
+	return result.RowsAffected, repository.QueryError("set devices state", result.Error)
+}
+
+// PurgeUnregisteredDevices deletes the devices that are unregistered since
+// before together with their enrollments. Request logs, grades and scheduled
+// update logs are removed by their foreign key constraints.
+func (r *Repository) PurgeUnregisteredDevices(before time.Time) (int64, error) {
+	var purged int64
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		unregistered := tx.Model(&model.IOSDevice{}).
+			Select("device_id").
+			Where("state = ? AND state_changed_at < ?", model.IOSDeviceStateUnregistered, before)
+
+		err := tx.Where("device_id IN (?)", unregistered).
+			Delete(&model.IOSDeviceLecture{}).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("state = ? AND state_changed_at < ?", model.IOSDeviceStateUnregistered, before).
+			Delete(&model.IOSDevice{})
+
+		purged = result.RowsAffected
+
+		return result.Error
+	})
+
+	return purged, repository.QueryError("purge unregistered devices", err)
+}
+
+// UpdateActivityCounters sets the activity counters of every device to the
+// number of its requests handled since the start of the day, week, month and
+// year.
+func (r *Repository) UpdateActivityCounters(day time.Time, week time.Time, month time.Time, year time.Time) (int64, error) {
+	handledSince := func(since time.Time) *gorm.DB {
+		return r.DB.Model(&model.IOSDeviceRequestLog{}).
+			Select("count(*)").
+			Where("ios_device_request_logs.device_id = ios_devices.device_id AND status = ? AND handled_at >= ?", model.IOSRequestStatusHandled, since)
+	}
+
+	result := r.DB.Model(&model.IOSDevice{}).
+		Where("1 = 1").
+		Updates(map[string]interface{}{
+			"activity_today":      handledSince(day),
+			"activity_this_week":  handledSince(week),
+			"activity_this_month": handledSince(month),
+			"activity_this_year":  handledSince(year),
+		})
+
+	return result.RowsAffected, repository.QueryError("update activity counters", result.Error)
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"gorm.io/gorm/clause"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+)
+
+func (r *Repository) GetGradesByDevice(deviceId string) (*[]model.IOSEncryptedGrade, error) {
+	var grades []model.IOSEncryptedGrade
+
+	err := r.DB.Where("device_id = ?", deviceId).Order("id").Find(&grades).Error
+
+	return &grades, repository.QueryError("get grades by device", err)
+}
+
+func (r *Repository) CreateGrades(grades *[]model.IOSEncryptedGrade) error {
+	if len(*grades) == 0 {
+		return nil
+	}
+
+	return repository.QueryError("create grades", r.DB.Omit(clause.Associations).CreateInBatches(grades, 1000).Error)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+)
+
+func (r *Repository) GetLectures() (*[]model.IOSLecture, error) {
+	var lectures []model.IOSLecture
+
+	err := r.DB.Find(&lectures).Error
+
+	return &lectures, repository.QueryError("get lectures", err)
+}
+
+func (r *Repository) CreateLectures(lectures *[]model.IOSLecture) error {
+	if len(*lectures) == 0 {
+		return nil
+	}
+
+	return repository.QueryError("create lectures", r.DB.Omit(clause.Associations).CreateInBatches(lectures, 1000).Error)
+}
+
+func (r *Repository) GetDeviceLectures() (*[]model.IOSDeviceLecture, error) {
+	var deviceLectures []model.IOSDeviceLecture
+
+	err := r.DB.Find(&deviceLectures).Error
+
+	return &deviceLectures, repository.QueryError("get device lectures", err)
+}
+
+// GetReadyDeviceLectures returns the enrollments of the devices returned by
+// GetReadyDevices.
+func (r *Repository) GetReadyDeviceLectures() (*[]model.IOSDeviceLecture, error) {
+	var deviceLectures []model.IOSDeviceLecture
+
+	err := r.DB.Joins("JOIN ios_devices d ON d.device_id = ios_device_lectures.device_id").
+		Where("d.state = ?", model.IOSDeviceStateActive).
+		Find(&deviceLectures).
+		Error
+
+	return &deviceLectures, repository.QueryError("get ready device lectures", err)
+}
+
+// GetDeviceLecturesAfter returns up to limit enrollments ordered by device
+// and lecture that come after the enrollment after. Only the keys are read.
+func (r *Repository) GetDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error) {
+	var deviceLectures []model.IOSDeviceLecture
+
+	err := deviceLecturesAfter(r.DB, after, limit).Find(&deviceLectures).Error
+
+	return &deviceLectures, repository.QueryError("get device lectures after", err)
+}
+
+// GetReadyDeviceLecturesAfter pages through the enrollments returned by
+// GetReadyDeviceLectures like GetDeviceLecturesAfter.
+func (r *Repository) GetReadyDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error) {
+	var deviceLectures []model.IOSDeviceLecture
+
+	err := deviceLecturesAfter(r.DB, after, limit).
+		Joins("JOIN ios_devices d ON d.device_id = ios_device_lectures.device_id").
+		Where("d.state = ?", model.IOSDeviceStateActive).
+		Find(&deviceLectures).
+		Error
+
+	return &deviceLectures, repository.QueryError("get ready device lectures after", err)
+}
+
+// deviceLecturesAfter selects a page of enrollments by the primary key, so
+// that every page is read from the index no matter how deep it is.
+func deviceLecturesAfter(db *gorm.DB, after model.IOSDeviceLecture, limit int) *gorm.DB {
+	query := db.Model(&model.IOSDeviceLecture{}).
+		Select("ios_device_lectures.device_id, ios_device_lectures.lecture_id").
+		Order("ios_device_lectures.device_id, ios_device_lectures.lecture_id").
+		Limit(limit)
+
+	if after.DeviceId == "" && after.LectureId == "" {
+		return query
+	}
+
+	return query.Where(
+		"(ios_device_lectures.device_id, ios_device_lectures.lecture_id) > (?, ?)",
+		after.DeviceId,
+		after.LectureId,
+	)
+}
+
+func (r *Repository) CreateDeviceLectures(deviceLectures *[]model.IOSDeviceLecture) error {
+	if len(*deviceLectures) == 0 {
+		return nil
+	}
+
+	return repository.QueryError("create device lectures", r.DB.Omit(clause.Associations).CreateInBatches(deviceLectures, 1000).Error)
+}
+
+func (r *Repository) GetLecturesThatHaveAtLeastOneDevice() (*[]string, error) {
+	var lectureIds []string
+
+	err := r.DB.Model(&model.IOSDeviceLecture{}).Distinct("lecture_id").Pluck("lecture_id", &lectureIds).Error
+
+	return &lectureIds, repository.QueryError("get lectures that have at least one device", err)
+}
+
+func (r *Repository) GetMaxAttendedLecturesCount() (int, error) {
+	var maxCount int
+
+	err := r.DB.Raw(
+		"select coalesce(max(lecture_count), 0) from (select count(*) as lecture_count from ios_device_lectures dl join ios_devices d on d.device_id = dl.device_id where d.state = ? group by dl.device_id) as t",
+		model.IOSDeviceStateActive,
+	).Scan(&maxCount).Error
+
+	return maxCount, repository.QueryError("get max attended lectures count", err)
+}
@@ -0,0 +1,525 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+// The migrations of every dialect are in migrations/<dialect> as pairs of
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Statements of a
+// script end with a semicolon at the end of a line.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockName identifies the lock that keeps concurrent migrators out.
+const migrationLockName = "schema_migrations"
+
+// MigrationLockTimeout is how long a migrator waits for another one to
+// finish.
+var MigrationLockTimeout = time.Minute
+
+var errMigrationLocked = errors.New("another migration is in progress")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records that the Migration of the Version was applied.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:191;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigrationStatus struct {
+	Version int
+	Name    string
+	// AppliedAt is nil if the migration is pending.
+	AppliedAt *time.Time
+	// Unknown is true if the migration was applied by a newer version of
+	// the application.
+	Unknown bool
+}
+
+// Migrations returns the migrations of dialect ordered by version.
+func Migrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := cutMigrationDirection(fileName)
+
+		if !ok {
+			return nil, fmt.Errorf("migration %s is neither .up.sql nor .down.sql", fileName)
+		}
+
+		versionText, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionText)
+
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has no valid version", fileName)
+		}
+
+		script, err := fs.ReadFile(migrationFiles, path.Join(dir, fileName))
+
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, name, version)
+		}
+
+		if direction == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down script", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func cutMigrationDirection(fileName string) (base string, direction string, ok bool) {
+	for _, direction := range []string{"up", "down"} {
+		suffix := "." + direction + ".sql"
+
+		if strings.HasSuffix(fileName, suffix) {
+			return strings.TrimSuffix(fileName, suffix), direction, true
+		}
+	}
+
+	return "", "", false
+}
+
+// splitStatements splits script into its statements and drops the comment
+// lines.
+func splitStatements(script string) []string {
+	var statements []string
+	var statement strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(statement.String()), ";"))
+			statement.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+// Migrate applies all pending migrations.
+func (r *Repository) Migrate() error {
+	_, err := r.MigrateUp(0)
+
+	return err
+}
+
+// MigrateUp applies up to steps pending migrations, all of them if steps is
+// zero or below, and returns the applied ones.
+//
+// A database that was created by AutoMigrate before the migrations were
+// introduced is brought to the baseline by AutoMigrate once and the
+// baseline is recorded as applied.
+func (r *Repository) MigrateUp(steps int) ([]Migration, error) {
+	migrations, err := Migrations(r.DB.Dialector.Name())
+
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrMigrate, Op: "load migrations", Err: err}
+	}
+
+	var applied []Migration
+
+	err = r.withMigrationLock(func(tx *gorm.DB) error {
+		if err := adoptLegacySchema(tx, migrations[0]); err != nil {
+			return err
+		}
+
+		versions, err := appliedVersions(tx)
+
+		if err != nil {
+			return err
+		}
+
+		if latest := migrations[len(migrations)-1].Version; len(versions) > 0 && versions[len(versions)-1].Version > latest {
+			return fmt.Errorf("database is at version %d, this build knows up to %d", versions[len(versions)-1].Version, latest)
+		}
+
+		done := make(map[int]bool, len(versions))
+
+		for _, version := range versions {
+			done[version.Version] = true
+		}
+
+		for _, migration := range migrations {
+			if done[migration.Version] {
+				continue
+			}
+
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+
+			log.Infof("Applying migration %04d_%s", migration.Version, migration.Name)
+
+			err := runMigration(tx, migration.Up, func(tx *gorm.DB) error {
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return applied, &repository.Error{Kind: repository.ErrMigrate, Op: "migrate up", Err: err}
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, at least one, and
+// returns the reverted ones.
+func (r *Repository) MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := Migrations(r.DB.Dialector.Name())
+
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrMigrate, Op: "load migrations", Err: err}
+	}
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	byVersion := make(map[int]Migration, len(migrations))
+
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []Migration
+
+	err = r.withMigrationLock(func(tx *gorm.DB) error {
+		versions, err := appliedVersions(tx)
+
+		if err != nil {
+			return err
+		}
+
+		for i := len(versions) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration, ok := byVersion[versions[i].Version]
+
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is unknown to this build", versions[i].Version, versions[i].Name)
+			}
+
+			log.Infof("Reverting migration %04d_%s", migration.Version, migration.Name)
+
+			err := runMigration(tx, migration.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&SchemaMigration{}, migration.Version).Error
+			})
+
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return reverted, &repository.Error{Kind: repository.ErrMigrate, Op: "migrate down", Err: err}
+	}
+
+	return reverted, nil
+}
+
+// MigrationStatus returns the known migrations and the ones applied by newer
+// versions of the application ordered by version.
+func (r *Repository) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations(r.DB.Dialector.Name())
+
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrMigrate, Op: "load migrations", Err: err}
+	}
+
+	versions, err := appliedVersions(r.DB)
+
+	if err != nil {
+		return nil, &repository.Error{Kind: repository.ErrMigrate, Op: "migration status", Err: err}
+	}
+
+	applied := make(map[int]SchemaMigration, len(versions))
+
+	for _, version := range versions {
+		applied[version.Version] = version
+	}
+
+	var statuses []MigrationStatus
+
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+
+		if version, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &version.AppliedAt
+			delete(applied, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, version := range applied {
+		version := version
+
+		statuses = append(statuses, MigrationStatus{
+			Version:   version.Version,
+			Name:      version.Name,
+			AppliedAt: &version.AppliedAt,
+			Unknown:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// appliedVersions returns the applied migrations ordered by version, none if
+// the schema_migrations table does not exist yet.
+func appliedVersions(tx *gorm.DB) ([]SchemaMigration, error) {
+	var versions []SchemaMigration
+
+	if !tx.Migrator().HasTable(&SchemaMigration{}) {
+		return versions, nil
+	}
+
+	err := tx.Order("version").Find(&versions).Error
+
+	return versions, err
+}
+
+// runMigration executes the statements of script and record in a
+// transaction. MySQL commits every DDL statement implicitly, a failing
+// migration may be applied partially there.
+func runMigration(tx *gorm.DB, script string, record func(tx *gorm.DB) error) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		return record(tx)
+	})
+}
+
+// withMigrationLock runs fc while holding the migration lock: a named lock on
+// MySQL, an advisory lock on PostgreSQL and the write lock of the transaction
+// on SQLite. SQLite connections begin transactions as immediate, see
+// sqliteDSN, so a concurrent migrator waits for the busy timeout at its BEGIN
+// instead of failing on its first write. The schema_migrations table is
+// created first.
+func (r *Repository) withMigrationLock(fc func(tx *gorm.DB) error) error {
+	if r.DB.Dialector.Name() == DialectSQLite {
+		return r.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&SchemaMigration{}); err != nil {
+				return err
+			}
+
+			return fc(tx)
+		})
+	}
+
+	return r.DB.Connection(func(conn *gorm.DB) error {
+		unlock, err := lock(conn)
+
+		if err != nil {
+			return err
+		}
+
+		defer unlock()
+
+		if err := conn.Migrator().AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+
+		return fc(conn)
+	})
+}
+
+// lock takes the migration lock on conn and returns the function releasing
+// it. Both locks are bound to the connection, they are released by the
+// database if the migrator dies.
+func lock(conn *gorm.DB) (func(), error) {
+	if conn.Dialector.Name() == DialectMySQL {
+		var locked sql.NullInt64
+
+		err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(MigrationLockTimeout.Seconds())).Scan(&locked).Error
+
+		if err != nil {
+			return nil, err
+		}
+
+		if locked.Int64 != 1 {
+			return nil, errMigrationLocked
+		}
+
+		return func() {
+			conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		}, nil
+	}
+
+	key := advisoryLockKey(migrationLockName)
+	deadline := time.Now().Add(MigrationLockTimeout)
+
+	for {
+		var locked bool
+
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&locked).Error; err != nil {
+			return nil, err
+		}
+
+		if locked {
+			return func() {
+				conn.Exec("SELECT pg_advisory_unlock(?)", key)
+			}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errMigrationLocked
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// advisoryLockKey derives the key of a PostgreSQL advisory lock from name
+// (FNV-1a).
+func advisoryLockKey(name string) int64 {
+	hash := uint64(14695981039346656037)
+
+	for i := 0; i < len(name); i++ {
+		hash ^= uint64(name[i])
+		hash *= 1099511628211
+	}
+
+	return int64(hash)
+}
+
+// adoptLegacySchema records baseline as applied if the tables exist but no
+// migration was applied yet, i.e. the database was created by AutoMigrate.
+// AutoMigrate brings such a database to the baseline first, which also
+// drops the replacedIndexes.
+func adoptLegacySchema(tx *gorm.DB, baseline Migration) error {
+	versions, err := appliedVersions(tx)
+
+	if err != nil || len(versions) > 0 || !tx.Migrator().HasTable(&model.IOSDevice{}) {
+		return err
+	}
+
+	log.Warnf("Adopting the schema created by AutoMigrate as migration %04d_%s", baseline.Version, baseline.Name)
+
+	for _, index := range replacedIndexes {
+		if !tx.Migrator().HasIndex(&model.IOSScheduledUpdateLog{}, index) {
+			continue
+		}
+
+		if err := tx.Migrator().DropIndex(&model.IOSScheduledUpdateLog{}, index); err != nil {
+			return fmt.Errorf("drop index %s: %w", index, err)
+		}
+	}
+
+	err = tx.AutoMigrate(
+		&model.IOSDevice{},
+		&model.IOSDeviceRequestLog{},
+		&model.IOSEncryptedGrade{},
+		&model.IOSScheduledUpdateLog{},
+		&model.IOSSchedulingPriority{},
+		&model.IOSLecture{},
+		&model.IOSDeviceLecture{},
+	)
+
+	if err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+
+	return tx.Create(&SchemaMigration{
+		Version:   baseline.Version,
+		Name:      baseline.Name,
+		AppliedAt: time.Now(),
+	}).Error
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSQLiteTransactionsTakeTheWriteLock(t *testing.T) {
+	tests := []string{
+		"sqlite:///tmp/a.db",
+		"sqlite:///tmp/a.db?cache=shared",
+	}
+
+	for _, dsn := range tests {
+		_, driverDSN, err := ParseDSN(dsn)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(driverDSN, "_txlock=immediate") {
+			t.Errorf("%s: transactions of %s do not take the write lock when they begin", dsn, driverDSN)
+		}
+	}
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "migrate.db")
+	migrators := 4
+	repos := make([]*Repository, migrators)
+
+	for i := range repos {
+		repo, err := Connect(dsn)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repos[i] = repo
+	}
+
+	t.Cleanup(func() {
+		for _, repo := range repos {
+			if sqlDB, err := repo.DB.DB(); err == nil {
+				sqlDB.Close()
+			}
+		}
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, migrators)
+
+	for i, repo := range repos {
+		wg.Add(1)
+
+		go func(i int, repo *Repository) {
+			defer wg.Done()
+
+			errs[i] = repo.Migrate()
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("migrator %d failed: %v", i, err)
+		}
+	}
+
+	statuses, err := repos[0].MigrationStatus()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil || status.Unknown {
+			t.Errorf("migration %d %s was not applied once", status.Version, status.Name)
+		}
+	}
+}
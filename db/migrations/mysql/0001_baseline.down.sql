@@ -0,0 +1,7 @@
+DROP TABLE `ios_device_lectures`;
+DROP TABLE `ios_lectures`;
+DROP TABLE `ios_scheduling_priorities`;
+DROP TABLE `ios_scheduled_update_logs`;
+DROP TABLE `ios_encrypted_grades`;
+DROP TABLE `ios_device_request_logs`;
+DROP TABLE `ios_devices`;
@@ -0,0 +1,93 @@
+-- The schema as created by gorm AutoMigrate before the migrations were
+-- introduced.
+
+CREATE TABLE `ios_devices` (
+    `device_id` varchar(191),
+    `created_at` datetime(3) NULL,
+    `public_key` longtext NOT NULL,
+    `activity_today` int DEFAULT 0,
+    `activity_this_week` int DEFAULT 0,
+    `activity_this_month` int DEFAULT 0,
+    `activity_this_year` int DEFAULT 0,
+    `state` varchar(16) NOT NULL DEFAULT 'active',
+    `state_changed_at` datetime(3) NULL,
+    PRIMARY KEY (`device_id`),
+    INDEX `idx_ios_devices_state` (`state`),
+    CONSTRAINT `chk_ios_devices_state` CHECK (state IN ('active', 'suspect', 'unregistered'))
+);
+
+CREATE TABLE `ios_device_request_logs` (
+    `request_id` varchar(191),
+    `device_id` varchar(200) NOT NULL,
+    `request_type` varchar(32) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `handled_at` datetime(3) NULL,
+    `status` varchar(16) NOT NULL DEFAULT 'created',
+    `attempts` bigint NOT NULL DEFAULT 0,
+    `expires_at` datetime(3) NULL,
+    PRIMARY KEY (`request_id`),
+    INDEX `idx_ios_device_request_logs_device_id` (`device_id`),
+    INDEX `idx_ios_device_request_logs_status` (`status`),
+    INDEX `idx_ios_device_request_logs_expires_at` (`expires_at`),
+    CONSTRAINT `fk_ios_device_request_logs_device` FOREIGN KEY (`device_id`) REFERENCES `ios_devices` (`device_id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_ios_device_request_logs_request_type` CHECK (request_type IN ('CAMPUS_TOKEN_REQUEST', 'LECTURE_UPDATE_REQUEST')),
+    CONSTRAINT `chk_ios_device_request_logs_status` CHECK (status IN ('created', 'sent', 'delivery_failed', 'handled', 'expired', 'superseded'))
+);
+
+CREATE TABLE `ios_encrypted_grades` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `device_id` varchar(191) NOT NULL,
+    `lecture_title` longtext NOT NULL,
+    `grade` longtext NOT NULL,
+    `is_encrypted` boolean,
+    PRIMARY KEY (`id`),
+    INDEX `idx_ios_encrypted_grades_device_id` (`device_id`),
+    CONSTRAINT `fk_ios_encrypted_grades_device` FOREIGN KEY (`device_id`) REFERENCES `ios_devices` (`device_id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `ios_scheduled_update_logs` (
+    `id` int unsigned AUTO_INCREMENT,
+    `device_id` varchar(191),
+    `type` varchar(16),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_scheduled_update_log_device_type` (`device_id`, `type`),
+    INDEX `idx_scheduled_update_log_created_at` (`created_at`),
+    CONSTRAINT `fk_ios_scheduled_update_logs_device` FOREIGN KEY (`device_id`) REFERENCES `ios_devices` (`device_id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_ios_scheduled_update_logs_type` CHECK (type IN ('grades', 'lectures'))
+);
+
+CREATE TABLE `ios_scheduling_priorities` (
+    `id` bigint AUTO_INCREMENT,
+    `from_day` bigint NOT NULL,
+    `to_day` bigint NOT NULL,
+    `from_month` bigint NOT NULL DEFAULT 0,
+    `from_month_day` bigint NOT NULL DEFAULT 0,
+    `to_month` bigint NOT NULL DEFAULT 0,
+    `to_month_day` bigint NOT NULL DEFAULT 0,
+    `from_hour` bigint NOT NULL,
+    `to_hour` bigint NOT NULL,
+    `weekdays` tinyint unsigned NOT NULL DEFAULT 0,
+    `time_zone` varchar(64) NOT NULL DEFAULT '',
+    `priority` bigint NOT NULL,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE `ios_lectures` (
+    `id` varchar(191),
+    `year` smallint,
+    `semester` varchar(8),
+    `last_update` datetime(3) NULL,
+    `last_request_id` varchar(191),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_ios_lectures_last_request` FOREIGN KEY (`last_request_id`) REFERENCES `ios_device_request_logs` (`request_id`) ON DELETE SET NULL,
+    CONSTRAINT `chk_ios_lectures_semester` CHECK (semester IN ('winter', 'summer'))
+);
+
+CREATE TABLE `ios_device_lectures` (
+    `device_id` varchar(191),
+    `lecture_id` varchar(191),
+    PRIMARY KEY (`device_id`, `lecture_id`),
+    CONSTRAINT `fk_ios_device_lectures_device` FOREIGN KEY (`device_id`) REFERENCES `ios_devices` (`device_id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_ios_device_lectures_lecture` FOREIGN KEY (`lecture_id`) REFERENCES `ios_lectures` (`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,97 @@
+-- The schema as created by gorm AutoMigrate before the migrations were
+-- introduced.
+
+CREATE TABLE "ios_devices" (
+    "device_id" text,
+    "created_at" timestamptz,
+    "public_key" text NOT NULL,
+    "activity_today" integer DEFAULT 0,
+    "activity_this_week" integer DEFAULT 0,
+    "activity_this_month" integer DEFAULT 0,
+    "activity_this_year" integer DEFAULT 0,
+    "state" varchar(16) NOT NULL DEFAULT 'active',
+    "state_changed_at" timestamptz,
+    PRIMARY KEY ("device_id"),
+    CONSTRAINT "chk_ios_devices_state" CHECK (state IN ('active', 'suspect', 'unregistered'))
+);
+
+CREATE INDEX "idx_ios_devices_state" ON "ios_devices" ("state");
+
+CREATE TABLE "ios_device_request_logs" (
+    "request_id" text,
+    "device_id" varchar(200) NOT NULL,
+    "request_type" varchar(32) NOT NULL,
+    "created_at" timestamptz,
+    "handled_at" timestamptz,
+    "status" varchar(16) NOT NULL DEFAULT 'created',
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "expires_at" timestamptz,
+    PRIMARY KEY ("request_id"),
+    CONSTRAINT "fk_ios_device_request_logs_device" FOREIGN KEY ("device_id") REFERENCES "ios_devices" ("device_id") ON DELETE CASCADE,
+    CONSTRAINT "chk_ios_device_request_logs_request_type" CHECK (request_type IN ('CAMPUS_TOKEN_REQUEST', 'LECTURE_UPDATE_REQUEST')),
+    CONSTRAINT "chk_ios_device_request_logs_status" CHECK (status IN ('created', 'sent', 'delivery_failed', 'handled', 'expired', 'superseded'))
+);
+
+CREATE INDEX "idx_ios_device_request_logs_device_id" ON "ios_device_request_logs" ("device_id");
+CREATE INDEX "idx_ios_device_request_logs_status" ON "ios_device_request_logs" ("status");
+CREATE INDEX "idx_ios_device_request_logs_expires_at" ON "ios_device_request_logs" ("expires_at");
+
+CREATE TABLE "ios_encrypted_grades" (
+    "id" bigserial,
+    "device_id" text NOT NULL,
+    "lecture_title" text NOT NULL,
+    "grade" text NOT NULL,
+    "is_encrypted" boolean,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_ios_encrypted_grades_device" FOREIGN KEY ("device_id") REFERENCES "ios_devices" ("device_id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_ios_encrypted_grades_device_id" ON "ios_encrypted_grades" ("device_id");
+
+CREATE TABLE "ios_scheduled_update_logs" (
+    "id" bigserial,
+    "device_id" text,
+    "type" varchar(16),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_ios_scheduled_update_logs_device" FOREIGN KEY ("device_id") REFERENCES "ios_devices" ("device_id") ON DELETE CASCADE,
+    CONSTRAINT "chk_ios_scheduled_update_logs_type" CHECK (type IN ('grades', 'lectures'))
+);
+
+CREATE UNIQUE INDEX "idx_scheduled_update_log_device_type" ON "ios_scheduled_update_logs" ("device_id", "type");
+CREATE INDEX "idx_scheduled_update_log_created_at" ON "ios_scheduled_update_logs" ("created_at");
+
+CREATE TABLE "ios_scheduling_priorities" (
+    "id" bigserial,
+    "from_day" bigint NOT NULL,
+    "to_day" bigint NOT NULL,
+    "from_month" bigint NOT NULL DEFAULT 0,
+    "from_month_day" bigint NOT NULL DEFAULT 0,
+    "to_month" bigint NOT NULL DEFAULT 0,
+    "to_month_day" bigint NOT NULL DEFAULT 0,
+    "from_hour" bigint NOT NULL,
+    "to_hour" bigint NOT NULL,
+    "weekdays" smallint NOT NULL DEFAULT 0,
+    "time_zone" varchar(64) NOT NULL DEFAULT '',
+    "priority" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "ios_lectures" (
+    "id" text,
+    "year" smallint,
+    "semester" varchar(8),
+    "last_update" timestamptz,
+    "last_request_id" varchar(191),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_ios_lectures_last_request" FOREIGN KEY ("last_request_id") REFERENCES "ios_device_request_logs" ("request_id") ON DELETE SET NULL,
+    CONSTRAINT "chk_ios_lectures_semester" CHECK (semester IN ('winter', 'summer'))
+);
+
+CREATE TABLE "ios_device_lectures" (
+    "device_id" text,
+    "lecture_id" text,
+    PRIMARY KEY ("device_id", "lecture_id"),
+    CONSTRAINT "fk_ios_device_lectures_device" FOREIGN KEY ("device_id") REFERENCES "ios_devices" ("device_id") ON DELETE CASCADE,
+    CONSTRAINT "fk_ios_device_lectures_lecture" FOREIGN KEY ("lecture_id") REFERENCES "ios_lectures" ("id") ON DELETE CASCADE
+);
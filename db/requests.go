@@ -0,0 +1,326 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+// GetRequestLogsSince returns all requests created at or after since, the
+// newest first.
+func (r *Repository) GetRequestLogsSince(since time.Time) (*[]model.IOSDeviceRequestLog, error) {
+	var requestLogs []model.IOSDeviceRequestLog
+
+	err := r.DB.Where("created_at >= ?", since).Order("created_at desc").Find(&requestLogs).Error
+
+	return &requestLogs, repository.QueryError("get request logs since", err)
+}
+
+// GetHandledRequestsDevices returns the devices of the requests out of
+// requestIds that were already answered.
+func (r *Repository) GetHandledRequestsDevices(requestIds *[]string) (*[]string, error) {
+	var deviceIds []string
+
+	if len(*requestIds) == 0 {
+		return &deviceIds, nil
+	}
+
+	err := r.DB.Model(&model.IOSDeviceRequestLog{}).
+		Where("request_id IN ? AND handled_at IS NOT NULL", *requestIds).
+		Pluck("device_id", &deviceIds).
+		Error
+
+	return &deviceIds, repository.QueryError("get handled requests devices", err)
+}
+
+func (r *Repository) CreateRequestLogs(requestLogs *[]model.IOSDeviceRequestLog) error {
+	if len(*requestLogs) == 0 {
+		return nil
+	}
+
+	for i := range *requestLogs {
+		if (*requestLogs)[i].RequestID == "" {
+			(*requestLogs)[i].RequestID = model.NewRequestID()
+		}
+	}
+
+	return repository.QueryError("create request logs", r.DB.Omit(clause.Associations).CreateInBatches(requestLogs, 1000).Error)
+}
+
+// CreateLectureUpdateRequests creates a LECTURE_UPDATE_REQUEST for every
+// device of lectureIdsByDevice and points IOSLecture.LastRequestId of the
+// lectures to the request of the device that covers them. Lectures of a
+// superseded request point to the new request of its device. Everything is
+// written in a single transaction.
+func (r *Repository) CreateLectureUpdateRequests(lectureIdsByDevice map[string][]string) (*[]model.IOSDeviceRequestLog, error) {
+	var requestLogs []model.IOSDeviceRequestLog
+
+	now := time.Now()
+
+	for deviceId := range lectureIdsByDevice {
+		requestLogs = append(requestLogs, r.RequestExpiry.NewRequestLog(deviceId, model.IOSLectureUpdateRequestType, now))
+	}
+
+	if len(requestLogs) == 0 {
+		return &requestLogs, nil
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).CreateInBatches(&requestLogs, 1000).Error; err != nil {
+			return err
+		}
+
+		// Open requests of the devices are replaced by the new ones.
+		if err := supersede(tx, requestLogs); err != nil {
+			return err
+		}
+
+		for _, requestLog := range requestLogs {
+			lectureIds := lectureIdsByDevice[requestLog.DeviceID]
+
+			if len(lectureIds) == 0 {
+				continue
+			}
+
+			err := tx.Model(&model.IOSLecture{}).
+				Where("id IN ?", lectureIds).
+				Update("last_request_id", requestLog.RequestID).
+				Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, repository.QueryError("create lecture update requests", err)
+	}
+
+	return &requestLogs, nil
+}
+
+// supersede moves the other open requests of the same type to the devices
+// of the created requestLogs to superseded and points the lectures of the
+// superseded requests to the new request of their device.
+func supersede(tx *gorm.DB, requestLogs []model.IOSDeviceRequestLog) error {
+	for _, requestLog := range requestLogs {
+		open := tx.Model(&model.IOSDeviceRequestLog{}).
+			Select("request_id").
+			Where("device_id = ? AND request_type = ? AND status IN ? AND request_id <> ?", requestLog.DeviceID, requestLog.RequestType, model.IOSOpenRequestStatuses, requestLog.RequestID)
+
+		err := tx.Model(&model.IOSLecture{}).
+			Where("last_request_id IN (?)", open).
+			Update("last_request_id", requestLog.RequestID).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.IOSDeviceRequestLog{}).
+			Where("device_id = ? AND request_type = ? AND status IN ? AND request_id <> ?", requestLog.DeviceID, requestLog.RequestType, model.IOSOpenRequestStatuses, requestLog.RequestID).
+			Update("status", model.IOSRequestStatusSuperseded).
+			Error
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HandleRequest marks the request as handled at handledAt after validate
+// accepted it. For a LECTURE_UPDATE_REQUEST the LastUpdate of the lectures
+// the request is responsible for and of all lectures the device is enrolled
+// in is set to handledAt as well. For a request refreshing an update type,
+// see model.IOSUpdateTypeOf, the IOSScheduledUpdateLog of the device is
+// moved to handledAt.
+//
+// An error of kind ErrNotFound is returned if the request does not exist and
+// the error of validate is returned as is.
+func (r *Repository) HandleRequest(
+	requestId string,
+	handledAt time.Time,
+	validate func(requestLog *model.IOSDeviceRequestLog) error,
+) (*model.IOSDeviceRequestLog, error) {
+	var requestLog model.IOSDeviceRequestLog
+	var validationErr error
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("request_id = ?", requestId).
+			First(&requestLog).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		if validationErr = validate(&requestLog); validationErr != nil {
+			return validationErr
+		}
+
+		if validationErr = requestLog.TransitionTo(model.IOSRequestStatusHandled); validationErr != nil {
+			return validationErr
+		}
+
+		requestLog.HandledAt = sql.NullTime{Time: handledAt, Valid: true}
+
+		err = tx.Model(&model.IOSDeviceRequestLog{}).
+			Where("request_id = ?", requestId).
+			Updates(map[string]interface{}{
+				"handled_at": requestLog.HandledAt,
+				"status":     requestLog.Status,
+			}).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		if updateType := model.IOSUpdateTypeOf(requestLog.RequestType); updateType != "" {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "device_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"created_at"}),
+			}).Omit(clause.Associations).Create(&model.IOSScheduledUpdateLog{
+				DeviceID:  requestLog.DeviceID,
+				Type:      updateType,
+				CreatedAt: handledAt,
+			}).Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if requestLog.RequestType != model.IOSLectureUpdateRequestType {
+			return nil
+		}
+
+		// An answering device refreshes all lectures it is enrolled in, not
+		// only the ones the request covered.
+		enrolled := tx.Model(&model.IOSDeviceLecture{}).
+			Select("lecture_id").
+			Where("device_id = ?", requestLog.DeviceID)
+
+		return tx.Model(&model.IOSLecture{}).
+			Where("last_request_id = ? OR id IN (?)", requestId, enrolled).
+			Update("last_update", handledAt).
+			Error
+	})
+
+	switch {
+	case validationErr != nil:
+		return nil, validationErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, &repository.Error{Kind: repository.ErrNotFound, Op: "handle request", Err: err}
+	case err != nil:
+		return nil, repository.QueryError("handle request", err)
+	}
+
+	return &requestLog, nil
+}
+
+// MarkRequestsAttempted moves the requests to status (sent or
+// delivery_failed) and counts the delivery attempt. Requests that can't
+// move to status, e.g. because they were answered in the meantime, are
+// left untouched.
+func (r *Repository) MarkRequestsAttempted(requestIds *[]string, status string) (int64, error) {
+	if len(*requestIds) == 0 {
+		return 0, nil
+	}
+
+	result := r.DB.Model(&model.IOSDeviceRequestLog{}).
+		Where("request_id IN ? AND status IN ?", *requestIds, model.IOSRequestStatusesTransitioningTo(status)).
+		Updates(map[string]interface{}{
+			"status":   status,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+
+	return result.RowsAffected, repository.QueryError("mark requests attempted", result.Error)
+}
+
+// GetRetryableRequests returns the requests whose delivery failed less than
+// maxAttempts times and that did not expire yet at now.
+func (r *Repository) GetRetryableRequests(maxAttempts int, now time.Time) (*[]model.IOSDeviceRequestLog, error) {
+	var requestLogs []model.IOSDeviceRequestLog
+
+	err := r.DB.Where("status = ? AND attempts < ? AND expires_at > ?", model.IOSRequestStatusDeliveryFailed, maxAttempts, now).
+		Find(&requestLogs).
+		Error
+
+	return &requestLogs, repository.QueryError("get retryable requests", err)
+}
+
+// ExpireRequests moves all open requests that expired before now to
+// expired and clears IOSLecture.LastRequestId of the lectures pointing at
+// them. Requests without ExpiresAt expire after the RequestExpiry of their
+// type.
+func (r *Repository) ExpireRequests(now time.Time) (int64, error) {
+	var expired int64
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("expires_at < ?", now)
+
+		for requestType, expiry := range r.RequestExpiry {
+			stale = stale.Or(
+				"expires_at IS NULL AND request_type = ? AND created_at < ?",
+				requestType,
+				now.Add(-expiry),
+			)
+		}
+
+		var requestIds []string
+
+		err := tx.Model(&model.IOSDeviceRequestLog{}).
+			Where("status IN ?", model.IOSOpenRequestStatuses).
+			Where(stale).
+			Pluck("request_id", &requestIds).
+			Error
+
+		if err != nil || len(requestIds) == 0 {
+			return err
+		}
+
+		for start := 0; start < len(requestIds); start += 1000 {
+			end := start + 1000
+
+			if end > len(requestIds) {
+				end = len(requestIds)
+			}
+
+			batch := requestIds[start:end]
+
+			result := tx.Model(&model.IOSDeviceRequestLog{}).
+				Where("request_id IN ? AND status IN ?", batch, model.IOSOpenRequestStatuses).
+				Update("status", model.IOSRequestStatusExpired)
+
+			if result.Error != nil {
+				return result.Error
+			}
+
+			expired += result.RowsAffected
+
+			err := tx.Model(&model.IOSLecture{}).
+				Where("last_request_id IN ?", batch).
+				Update("last_request_id", nil).
+				Error
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return expired, repository.QueryError("expire requests", err)
+}
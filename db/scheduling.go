@@ -0,0 +1,188 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+func (r *Repository) GetSchedulingPriorities() (*[]model.IOSSchedulingPriority, error) {
+	var priorities []model.IOSSchedulingPriority
+
+	err := r.DB.Find(&priorities).Error
+
+	return &priorities, repository.QueryError("get scheduling priorities", err)
+}
+
+func (r *Repository) CreateSchedulingPriorities(priorities *[]model.IOSSchedulingPriority) error {
+	if len(*priorities) == 0 {
+		return nil
+	}
+
+	return repository.QueryError("create scheduling priorities", r.DB.CreateInBatches(priorities, 1000).Error)
+}
+
+func (r *Repository) GetScheduledUpdateLogs(updateType string) (*[]model.IOSScheduledUpdateLog, error) {
+	var updateLogs []model.IOSScheduledUpdateLog
+
+	err := r.DB.Where("type = ?", updateType).Find(&updateLogs).Error
+
+	return &updateLogs, repository.QueryError("get scheduled update logs", err)
+}
+
+// RecordScheduledUpdates logs that the devices were updated at createdAt.
+// An existing log of the same device and type is moved to createdAt.
+func (r *Repository) RecordScheduledUpdates(deviceIds *[]string, updateType string, createdAt time.Time) error {
+	if len(*deviceIds) == 0 {
+		return nil
+	}
+
+	updateLogs := make([]model.IOSScheduledUpdateLog, 0, len(*deviceIds))
+
+	for _, deviceId := range *deviceIds {
+		updateLogs = append(updateLogs, model.IOSScheduledUpdateLog{
+			DeviceID:  deviceId,
+			Type:      updateType,
+			CreatedAt: createdAt,
+		})
+	}
+
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "device_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at"}),
+	}).Omit(clause.Associations).CreateInBatches(&updateLogs, 1000).Error
+
+	return repository.QueryError("record scheduled updates", err)
+}
+
+// RecordScheduledRun logs a run of the scheduler refreshing updateType at
+// createdAt. The log of a run has no device.
+func (r *Repository) RecordScheduledRun(updateType string, createdAt time.Time) error {
+	updateLog := model.IOSScheduledUpdateLog{
+		Type:      updateType,
+		CreatedAt: createdAt,
+	}
+
+	err := r.DB.Omit("DeviceID", clause.Associations).Create(&updateLog).Error
+
+	return repository.QueryError("record scheduled run", err)
+}
+
+// GetDevicesDueForUpdate returns up to limit active devices whose last
+// update of updateType was before before and that have no pending request
+// of requestType at at, the ones never updated first and then the longest
+// ago.
+func (r *Repository) GetDevicesDueForUpdate(
+	updateType string,
+	requestType string,
+	before time.Time,
+	at time.Time,
+	limit int,
+) (*[]string, error) {
+	var deviceIds []string
+
+	pending := r.DB.Model(&model.IOSDeviceRequestLog{}).
+		Select("device_id").
+		Where("request_type = ? AND status IN ? AND expires_at > ?", requestType, model.IOSOpenRequestStatuses, at)
+
+	err := r.DB.Table("ios_devices d").
+		Joins("LEFT JOIN ios_scheduled_update_logs l ON l.device_id = d.device_id AND l.type = ?", updateType).
+		Where("d.state = ? AND (l.created_at IS NULL OR l.created_at < ?)", model.IOSDeviceStateActive, before).
+		Where("d.device_id NOT IN (?)", pending).
+		Order("CASE WHEN l.created_at IS NULL THEN 0 ELSE 1 END, l.created_at").
+		Limit(limit).
+		Pluck("d.device_id", &deviceIds).
+		Error
+
+	return &deviceIds, repository.QueryError("get devices due for update", err)
+}
+
+// CreateScheduledRequests creates a request of requestType at at for every
+// device whose last update of updateType is before before and that has no
+// pending request of requestType, i.e. an open one that has not expired at
+// at. The IOSScheduledUpdateLog is only moved when the device answers, see
+// HandleRequest. Expired open requests of requestType to the claimed
+// devices are superseded.
+func (r *Repository) CreateScheduledRequests(
+	deviceIds *[]string,
+	updateType string,
+	requestType string,
+	before time.Time,
+	at time.Time,
+) (*[]model.IOSDeviceRequestLog, error) {
+	var requestLogs []model.IOSDeviceRequestLog
+
+	if len(*deviceIds) == 0 {
+		return &requestLogs, nil
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// The devices are locked, so that a concurrent planner waits and
+		// then sees the pending requests instead of claiming the devices
+		// as well.
+		var locked []string
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Model(&model.IOSDevice{}).
+			Where("device_id IN ?", *deviceIds).
+			Pluck("device_id", &locked).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		var pending []string
+
+		err = tx.Model(&model.IOSDeviceRequestLog{}).
+			Where("device_id IN ? AND request_type = ? AND status IN ? AND expires_at > ?", *deviceIds, requestType, model.IOSOpenRequestStatuses, at).
+			Pluck("device_id", &pending).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		var updated []string
+
+		err = tx.Model(&model.IOSScheduledUpdateLog{}).
+			Where("device_id IN ? AND type = ? AND created_at >= ?", *deviceIds, updateType, before).
+			Pluck("device_id", &updated).
+			Error
+
+		if err != nil {
+			return err
+		}
+
+		skipped := make(map[string]bool, len(pending)+len(updated))
+
+		for _, deviceId := range append(pending, updated...) {
+			skipped[deviceId] = true
+		}
+
+		for _, deviceId := range *deviceIds {
+			if !skipped[deviceId] {
+				skipped[deviceId] = true
+				requestLogs = append(requestLogs, r.RequestExpiry.NewRequestLog(deviceId, requestType, at))
+			}
+		}
+
+		if len(requestLogs) == 0 {
+			return nil
+		}
+
+		if err := tx.Omit(clause.Associations).CreateInBatches(&requestLogs, 1000).Error; err != nil {
+			return err
+		}
+
+		return supersede(tx, requestLogs)
+	})
+
+	if err != nil {
+		return nil, repository.QueryError("create scheduled requests", err)
+	}
+
+	return &requestLogs, nil
+}
@@ -0,0 +1,347 @@
+// Package dispatch pushes lecture update requests to the devices selected by
+// the solver in rounds. Lectures whose device did not answer within a round
+// are solved again without the non-responding devices.
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+// Config controls how many rounds are dispatched and how long each round
+// waits for the devices to answer.
+type Config struct {
+	// Timeout is how long a round waits for answers before falling back.
+	Timeout time.Duration
+	// PollInterval is how often the answers are checked during a round.
+	PollInterval time.Duration
+	// MaxRounds is the maximum number of rounds, including the first one.
+	MaxRounds int
+	// CoverageTarget is the fraction of lectures (0 to 1) that has to be
+	// refreshed before the dispatch stops early.
+	CoverageTarget float64
+	// Budget is the maximum number of pushes over all rounds. Zero means
+	// no limit.
+	Budget int
+	// Solver is the name of the registered solver selecting the devices of
+	// a round.
+	Solver string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:        2 * time.Minute,
+		PollInterval:   5 * time.Second,
+		MaxRounds:      3,
+		CoverageTarget: 1,
+		Solver:         "greedy",
+	}
+}
+
+// A Committer persists the assignments of a round as request logs, so that
+// the answers of the devices can be matched to the lectures they cover.
+type Committer interface {
+	Commit(assignments *[]solver.Assignment) (*[]model.IOSDeviceRequestLog, error)
+}
+
+// A Sender notifies the devices of the requests of a round. Sending stops
+// when ctx is canceled.
+type Sender interface {
+	Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error
+}
+
+// Responses reports the devices whose request out of requestIds was
+// answered.
+type Responses interface {
+	Responded(requestIds *[]string) (*[]string, error)
+}
+
+// RepositoryCommitter creates a LECTURE_UPDATE_REQUEST per selected device.
+type RepositoryCommitter struct {
+	Repository repository.RequestLogs
+}
+
+func (c RepositoryCommitter) Commit(assignments *[]solver.Assignment) (*[]model.IOSDeviceRequestLog, error) {
+	return c.Repository.CreateLectureUpdateRequests(LectureIdsByDevice(assignments))
+}
+
+// LogSender only logs the devices of a round. It is used as long as no push
+// delivery is configured.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error {
+	log.Infof("Round %d: would push to %d devices", round, len(*requests))
+
+	return nil
+}
+
+// RepositoryResponses reads the answers from the request logs.
+type RepositoryResponses struct {
+	Repository repository.RequestLogs
+}
+
+func (r RepositoryResponses) Responded(requestIds *[]string) (*[]string, error) {
+	return r.Repository.GetHandledRequestsDevices(requestIds)
+}
+
+// LectureIdsByDevice maps every selected device to the lectures it is
+// responsible for.
+func LectureIdsByDevice(assignments *[]solver.Assignment) map[string][]string {
+	lectureIdsByDevice := make(map[string][]string, len(*assignments))
+
+	for _, assignment := range *assignments {
+		lectureIdsByDevice[assignment.DeviceId] = assignment.LectureIds
+	}
+
+	return lectureIdsByDevice
+}
+
+// Round summarizes a single dispatch round.
+type Round struct {
+	Number    int
+	Selected  int
+	Responded int
+	Refreshed int
+	Remaining int
+	Duration  time.Duration
+}
+
+// Result is the outcome of all rounds of a dispatch.
+type Result struct {
+	Rounds    []Round
+	Lectures  int
+	Refreshed int
+	// Pushed are the devices that were sent a request.
+	Pushed []string
+}
+
+// Coverage returns the fraction of lectures that were refreshed.
+func (r *Result) Coverage() float64 {
+	if r.Lectures == 0 {
+		return 1
+	}
+
+	return float64(r.Refreshed) / float64(r.Lectures)
+}
+
+type Orchestrator struct {
+	Config    Config
+	Committer Committer
+	Sender    Sender
+	Responses Responses
+	// Now returns the current time the rounds are timed with.
+	Now func() time.Time
+	// After returns a channel receiving once d has passed, it waits
+	// between the polls of the answers.
+	After func(d time.Duration) <-chan time.Time
+}
+
+func NewOrchestrator(config Config, committer Committer, sender Sender, responses Responses) *Orchestrator {
+	return &Orchestrator{
+		Config:    config,
+		Committer: committer,
+		Sender:    sender,
+		Responses: responses,
+		Now:       time.Now,
+		After:     time.After,
+	}
+}
+
+// Run dispatches rounds until the coverage target is met, MaxRounds is
+// reached or no device is left that could cover a remaining lecture.
+func (o *Orchestrator) Run(
+	ctx context.Context,
+	lectures *[]model.IOSLecture,
+	deviceLectures *[]model.IOSDeviceLecture,
+) (*Result, error) {
+	result := Result{Lectures: len(*lectures)}
+	solve, err := solver.Get(o.Config.Solver)
+
+	if err != nil {
+		return &result, err
+	}
+
+	refreshed := make(map[string]bool)
+	excluded := make(map[string]bool)
+	enrolled := enrolledLectures(deviceLectures)
+
+	for round := 1; round <= o.Config.MaxRounds; round++ {
+		if result.Coverage() >= o.Config.CoverageTarget {
+			break
+		}
+
+		startTime := o.Now()
+
+		remaining := remainingLectures(lectures, refreshed)
+		lecturesMap := solver.LectureToOverlappedLectureMap(remaining)
+		devicesLecturesMap := solver.DevicesLecturesToMap(
+			availableDeviceLectures(deviceLectures, excluded),
+			lecturesMap,
+		)
+
+		assignments := solve(devicesLecturesMap, lecturesMap)
+
+		if len(*assignments) == 0 {
+			log.Infof("Round %d: no device left for %d remaining lectures", round, len(*remaining))
+			break
+		}
+
+		if o.Config.Budget > 0 {
+			left := o.Config.Budget - len(result.Pushed)
+
+			if left <= 0 {
+				log.Infof("Round %d: push budget of %d exhausted", round, o.Config.Budget)
+				break
+			}
+
+			// The devices covering the most lectures are kept.
+			assignments = solver.Truncate(assignments, left)
+		}
+
+		requests, err := o.Committer.Commit(assignments)
+
+		if err != nil {
+			return &result, err
+		}
+
+		if err := o.Sender.Send(ctx, round, requests); err != nil {
+			return &result, err
+		}
+
+		responded, err := o.await(ctx, requests)
+
+		if err != nil {
+			return &result, err
+		}
+
+		refreshedBefore := len(refreshed)
+
+		for _, assignment := range *assignments {
+			excluded[assignment.DeviceId] = true
+			result.Pushed = append(result.Pushed, assignment.DeviceId)
+
+			if !responded[assignment.DeviceId] {
+				continue
+			}
+
+			// A device refreshes all its lectures, not only the assigned ones.
+			for _, lectureId := range enrolled[assignment.DeviceId] {
+				refreshed[lectureId] = true
+			}
+		}
+
+		result.Refreshed = len(refreshed)
+		result.Rounds = append(result.Rounds, Round{
+			Number:    round,
+			Selected:  len(*assignments),
+			Responded: len(responded),
+			Refreshed: len(refreshed) - refreshedBefore,
+			Remaining: result.Lectures - len(refreshed),
+			Duration:  o.Now().Sub(startTime),
+		})
+
+		log.Infof(
+			"Round %d: %d/%d devices responded, %d lectures refreshed, %d remaining (coverage %.2f) in %s",
+			round,
+			len(responded),
+			len(*assignments),
+			len(refreshed)-refreshedBefore,
+			result.Lectures-len(refreshed),
+			result.Coverage(),
+			o.Now().Sub(startTime),
+		)
+	}
+
+	return &result, nil
+}
+
+// await polls the answers of the round until every device answered or the
+// timeout is reached.
+func (o *Orchestrator) await(
+	ctx context.Context,
+	requests *[]model.IOSDeviceRequestLog,
+) (map[string]bool, error) {
+	requestIds := make([]string, 0, len(*requests))
+
+	for _, request := range *requests {
+		requestIds = append(requestIds, request.RequestID)
+	}
+
+	deadline := o.Now().Add(o.Config.Timeout)
+
+	for {
+		responded, err := o.Responses.Responded(&requestIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		respondedMap := make(map[string]bool, len(*responded))
+
+		for _, deviceId := range *responded {
+			respondedMap[deviceId] = true
+		}
+
+		left := deadline.Sub(o.Now())
+
+		if left <= 0 || len(respondedMap) == len(requestIds) {
+			return respondedMap, nil
+		}
+
+		// The answers are polled a last time when the timeout is reached,
+		// as it may be shorter than the PollInterval.
+		wait := o.Config.PollInterval
+
+		if left < wait {
+			wait = left
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("dispatch canceled: %w", ctx.Err())
+		case <-o.After(wait):
+		}
+	}
+}
+
+func enrolledLectures(deviceLectures *[]model.IOSDeviceLecture) map[string][]string {
+	enrolled := make(map[string][]string)
+
+	for _, d := range *deviceLectures {
+		enrolled[d.DeviceId] = append(enrolled[d.DeviceId], d.LectureId)
+	}
+
+	return enrolled
+}
+
+func remainingLectures(lectures *[]model.IOSLecture, refreshed map[string]bool) *[]model.IOSLecture {
+	var remaining []model.IOSLecture
+
+	for _, lecture := range *lectures {
+		if !refreshed[lecture.Id] {
+			remaining = append(remaining, lecture)
+		}
+	}
+
+	return &remaining
+}
+
+func availableDeviceLectures(
+	deviceLectures *[]model.IOSDeviceLecture,
+	excluded map[string]bool,
+) *[]model.IOSDeviceLecture {
+	var available []model.IOSDeviceLecture
+
+	for _, d := range *deviceLectures {
+		if !excluded[d.DeviceId] {
+			available = append(available, d)
+		}
+	}
+
+	return &available
+}
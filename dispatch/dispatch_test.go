@@ -0,0 +1,270 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"test-student-lecture-selection-algorithm/solver"
+	"testing"
+	"time"
+)
+
+// enrollments are covered by device-a and device-d in the first round. If
+// device-a does not answer, device-b and device-c cover its lectures.
+var enrollments = map[string][]string{
+	"device-a": {"L1", "L2", "L3"},
+	"device-b": {"L1", "L2"},
+	"device-c": {"L3"},
+	"device-d": {"L4"},
+}
+
+func input() (*[]model.IOSLecture, *[]model.IOSDeviceLecture) {
+	lectures := []model.IOSLecture{{Id: "L1"}, {Id: "L2"}, {Id: "L3"}, {Id: "L4"}}
+	var deviceLectures []model.IOSDeviceLecture
+
+	for deviceId, lectureIds := range enrollments {
+		for _, lectureId := range lectureIds {
+			deviceLectures = append(deviceLectures, model.IOSDeviceLecture{DeviceId: deviceId, LectureId: lectureId})
+		}
+	}
+
+	return &lectures, &deviceLectures
+}
+
+// fake commits, sends and answers the requests of the responders. Its After
+// advances the clock instead of waiting.
+type fake struct {
+	clock      *scheduling.FixedClock
+	responders map[string]bool
+	// rounds are the devices pushed to in each round.
+	rounds [][]string
+	polls  int
+	// afterSend is called after the requests of a round were sent.
+	afterSend func()
+}
+
+func (f *fake) Commit(assignments *[]solver.Assignment) (*[]model.IOSDeviceRequestLog, error) {
+	requests := make([]model.IOSDeviceRequestLog, 0, len(*assignments))
+
+	for _, assignment := range *assignments {
+		requests = append(requests, model.IOSDeviceRequestLog{
+			RequestID: "request-" + assignment.DeviceId,
+			DeviceID:  assignment.DeviceId,
+		})
+	}
+
+	return &requests, nil
+}
+
+func (f *fake) Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error {
+	var deviceIds []string
+
+	for _, request := range *requests {
+		deviceIds = append(deviceIds, request.DeviceID)
+	}
+
+	f.rounds = append(f.rounds, deviceIds)
+
+	if f.afterSend != nil {
+		f.afterSend()
+	}
+
+	return nil
+}
+
+func (f *fake) Responded(requestIds *[]string) (*[]string, error) {
+	f.polls++
+
+	var responded []string
+
+	for _, requestId := range *requestIds {
+		deviceId := strings.TrimPrefix(requestId, "request-")
+
+		if f.responders[deviceId] {
+			responded = append(responded, deviceId)
+		}
+	}
+
+	return &responded, nil
+}
+
+func (f *fake) After(d time.Duration) <-chan time.Time {
+	f.clock.Advance(d)
+
+	c := make(chan time.Time, 1)
+	c <- f.clock.Now()
+
+	return c
+}
+
+func newTestOrchestrator(config Config, responders ...string) (*Orchestrator, *fake) {
+	f := &fake{
+		clock:      scheduling.NewFixedClock(time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC)),
+		responders: make(map[string]bool),
+	}
+
+	for _, deviceId := range responders {
+		f.responders[deviceId] = true
+	}
+
+	o := NewOrchestrator(config, f, f, f)
+	o.Now = f.clock.Now
+	o.After = f.After
+
+	return o, f
+}
+
+func TestRunFallsBackWithoutNonResponders(t *testing.T) {
+	o, f := newTestOrchestrator(DefaultConfig(), "device-b", "device-c", "device-d")
+	lectures, deviceLectures := input()
+	result, err := o.Run(context.Background(), lectures, deviceLectures)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [][]string{{"device-a", "device-d"}, {"device-b", "device-c"}}; !reflect.DeepEqual(f.rounds, want) {
+		t.Errorf("pushed %v instead of %v", f.rounds, want)
+	}
+
+	want := []Round{
+		{Number: 1, Selected: 2, Responded: 1, Refreshed: 1, Remaining: 3, Duration: 2 * time.Minute},
+		{Number: 2, Selected: 2, Responded: 2, Refreshed: 3, Remaining: 0},
+	}
+
+	if !reflect.DeepEqual(result.Rounds, want) {
+		t.Errorf("rounds %+v instead of %+v", result.Rounds, want)
+	}
+
+	if result.Coverage() != 1 {
+		t.Errorf("coverage %.2f instead of 1", result.Coverage())
+	}
+}
+
+func TestRunStopsAtCoverageTarget(t *testing.T) {
+	config := DefaultConfig()
+	config.CoverageTarget = 0.5
+	o, f := newTestOrchestrator(config, "device-a")
+	lectures, deviceLectures := input()
+	result, err := o.Run(context.Background(), lectures, deviceLectures)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Rounds) != 1 || len(f.rounds) != 1 {
+		t.Errorf("%d rounds instead of 1", len(result.Rounds))
+	}
+
+	if result.Refreshed != 3 {
+		t.Errorf("%d lectures refreshed instead of 3", result.Refreshed)
+	}
+}
+
+func TestRunStopsWhenNoDeviceIsLeft(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxRounds = 5
+	o, f := newTestOrchestrator(config)
+	lectures, deviceLectures := input()
+	result, err := o.Run(context.Background(), lectures, deviceLectures)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Rounds) != 2 || len(f.rounds) != 2 {
+		t.Errorf("%d rounds instead of 2", len(result.Rounds))
+	}
+
+	if len(result.Pushed) != 4 || result.Refreshed != 0 {
+		t.Errorf("%d pushed and %d refreshed instead of 4 and 0", len(result.Pushed), result.Refreshed)
+	}
+}
+
+func TestRunKeepsWithinTheBudget(t *testing.T) {
+	config := DefaultConfig()
+	config.Budget = 3
+	o, f := newTestOrchestrator(config, "device-b", "device-c", "device-d")
+	lectures, deviceLectures := input()
+	result, err := o.Run(context.Background(), lectures, deviceLectures)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// device-b covers more lectures than device-c and is kept.
+	if want := [][]string{{"device-a", "device-d"}, {"device-b"}}; !reflect.DeepEqual(f.rounds, want) {
+		t.Errorf("pushed %v instead of %v", f.rounds, want)
+	}
+
+	if result.Refreshed != 3 || len(result.Rounds) != 2 {
+		t.Errorf("%d lectures refreshed in %d rounds instead of 3 in 2", result.Refreshed, len(result.Rounds))
+	}
+}
+
+func TestAwaitPollsUntilTheTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		responders []string
+		polls      int
+		duration   time.Duration
+	}{
+		{"every device answered", 2 * time.Minute, []string{"device-a", "device-d"}, 1, 0},
+		{"timeout a multiple of the interval", 2 * time.Minute, []string{"device-d"}, 25, 2 * time.Minute},
+		{"timeout shorter than the interval", 12 * time.Second, []string{"device-d"}, 4, 12 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.MaxRounds = 1
+			config.Timeout = test.timeout
+			o, f := newTestOrchestrator(config, test.responders...)
+			lectures, deviceLectures := input()
+			result, err := o.Run(context.Background(), lectures, deviceLectures)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.polls != test.polls {
+				t.Errorf("polled %d times instead of %d", f.polls, test.polls)
+			}
+
+			if result.Rounds[0].Duration != test.duration {
+				t.Errorf("round took %s instead of %s", result.Rounds[0].Duration, test.duration)
+			}
+
+			if result.Rounds[0].Responded != len(test.responders) {
+				t.Errorf("%d devices responded instead of %d", result.Rounds[0].Responded, len(test.responders))
+			}
+		})
+	}
+}
+
+func TestAwaitStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	o, f := newTestOrchestrator(DefaultConfig())
+	f.afterSend = cancel
+	// The poll interval never passes, only the cancellation ends the round.
+	o.After = func(d time.Duration) <-chan time.Time {
+		return nil
+	}
+
+	lectures, deviceLectures := input()
+	result, err := o.Run(ctx, lectures, deviceLectures)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v instead of %v", err, context.Canceled)
+	}
+
+	if len(result.Rounds) != 0 || f.polls != 1 {
+		t.Errorf("%d rounds and %d polls instead of 0 and 1", len(result.Rounds), f.polls)
+	}
+}
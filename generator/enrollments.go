@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"test-student-lecture-selection-algorithm/model"
+)
+
+// facultyCodes prefix the lecture IDs of the first faculties, the others are
+// numbered.
+var facultyCodes = []string{"IN", "MA", "PH", "CH", "EI", "MW", "WI", "BV", "LS", "ED", "SP", "ME"}
+
+func facultyCode(faculty int) string {
+	if faculty < len(facultyCodes) {
+		return facultyCodes[faculty]
+	}
+
+	return fmt.Sprintf("F%02d", faculty)
+}
+
+// faculty holds the lectures of a faculty ordered by popularity and draws
+// them by their Zipf distributed popularity.
+type faculty struct {
+	lectureIds []string
+	zipf       *rand.Zipf
+}
+
+func (f *faculty) draw() string {
+	return f.lectureIds[f.zipf.Uint64()]
+}
+
+// lectures creates the lectures of the current semester and deals them to
+// the faculties. The faculties without lectures are left out.
+func (g *Generator) lectures() (*[]model.IOSLecture, []*faculty) {
+	year, semester := semesterAt(g.Config)
+	lectures := make([]model.IOSLecture, 0, g.Config.Lectures)
+	lectureIds := make([][]string, g.Config.Faculties)
+
+	for i := 0; i < g.Config.Lectures; i++ {
+		f := i % g.Config.Faculties
+		id := fmt.Sprintf("%s%04d", facultyCode(f), len(lectureIds[f])+1)
+
+		lectureIds[f] = append(lectureIds[f], id)
+		lectures = append(lectures, model.IOSLecture{
+			Id:         id,
+			Year:       int16(year),
+			Semester:   semester,
+			LastUpdate: g.Config.Now.Add(-g.duration(g.Config.History)),
+		})
+	}
+
+	var faculties []*faculty
+
+	for _, ids := range lectureIds {
+		if len(ids) == 0 {
+			continue
+		}
+
+		// The popularity rank of a lecture is independent of its number.
+		g.random.Shuffle(len(ids), func(i, j int) {
+			ids[i], ids[j] = ids[j], ids[i]
+		})
+
+		faculties = append(faculties, &faculty{
+			lectureIds: ids,
+			zipf:       rand.NewZipf(g.random, g.Config.ZipfExponent, 1, uint64(len(ids)-1)),
+		})
+	}
+
+	return &lectures, faculties
+}
+
+// semesterAt returns the semester running at config.Now. The winter
+// semester starts in October and belongs to the year it starts in.
+func semesterAt(config Config) (int, string) {
+	now := config.Now
+
+	switch {
+	case now.Month() >= 10:
+		return now.Year(), model.IOSLectureSemesterWinter
+	case now.Month() <= 3:
+		return now.Year() - 1, model.IOSLectureSemesterWinter
+	}
+
+	return now.Year(), model.IOSLectureSemesterSummer
+}
+
+// enrollments enrolls every device in a number of distinct lectures drawn by
+// courses. Each device belongs to a faculty, a lecture is taken from it with
+// the FacultyAffinity and from any other faculty otherwise.
+func (g *Generator) enrollments(devices *[]model.IOSDevice, faculties []*faculty) *[]model.IOSDeviceLecture {
+	var deviceLectures []model.IOSDeviceLecture
+
+	if len(faculties) == 0 {
+		return &deviceLectures
+	}
+
+	for _, device := range *devices {
+		home := g.random.Intn(len(faculties))
+		count := g.courses()
+
+		if count > g.Config.Lectures {
+			count = g.Config.Lectures
+		}
+
+		enrolled := make(map[string]bool, count)
+
+		// Popular lectures are drawn again and again, the attempts are
+		// limited so that a student of a small faculty is not stuck.
+		for attempts := 0; len(enrolled) < count && attempts < 20*count; attempts++ {
+			f := faculties[home]
+
+			if len(faculties) > 1 && g.random.Float64() >= g.Config.FacultyAffinity {
+				f = faculties[(home+1+g.random.Intn(len(faculties)-1))%len(faculties)]
+			}
+
+			lectureId := f.draw()
+
+			if enrolled[lectureId] {
+				continue
+			}
+
+			enrolled[lectureId] = true
+			deviceLectures = append(deviceLectures, model.IOSDeviceLecture{
+				DeviceId:  device.DeviceID,
+				LectureId: lectureId,
+			})
+		}
+	}
+
+	return &deviceLectures
+}
@@ -0,0 +1,369 @@
+// Package generator fills a repository with synthetic devices, lectures,
+// enrollments and request logs. The instances resemble real course data:
+// lectures belong to faculties, students mostly enroll in the lectures of
+// their own faculty, the popularity of the lectures follows a Zipf
+// distribution and devices answer requests according to response profiles.
+//
+// The same Config, including the Seed and Now, generates the same data.
+package generator
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+var errNotEmpty = errors.New("repository is not empty")
+
+// ResponseProfile describes how a share of the devices answers requests.
+type ResponseProfile struct {
+	Name string
+	// Share is the weight of the profile among all profiles.
+	Share float64
+	// ResponseRate is the probability that a request is handled.
+	ResponseRate float64
+	// MeanLatency is the mean time between the creation of a request and
+	// its answer. Latencies are exponentially distributed.
+	MeanLatency time.Duration
+}
+
+type Config struct {
+	// Seed of the random numbers.
+	Seed int64
+	// Now is the time the data is generated for.
+	Now      time.Time
+	Devices  int
+	Lectures int
+	// Faculties the lectures and the students are split into.
+	Faculties int
+	// FacultyAffinity is the probability that a lecture of a student is
+	// offered by the faculty of the student.
+	FacultyAffinity float64
+	// ZipfExponent skews the popularity of the lectures of a faculty, it has
+	// to be greater than one. The larger it is, the more students attend the
+	// most popular lectures.
+	ZipfExponent float64
+	// The number of lectures of a student is normally distributed with
+	// CoursesMean and CoursesStddev and limited to MinCourses and
+	// MaxCourses.
+	CoursesMean   float64
+	CoursesStddev float64
+	MinCourses    int
+	MaxCourses    int
+	// RequestsPerDevice is the mean number of requests in the history of a
+	// device.
+	RequestsPerDevice float64
+	// History is how far back the requests and the devices reach.
+	History  time.Duration
+	Profiles []ResponseProfile
+	// Bulk configures how the data is written.
+	Bulk repository.BulkConfig
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Seed:              1,
+		Now:               time.Now(),
+		Devices:           20000,
+		Lectures:          500,
+		Faculties:         10,
+		FacultyAffinity:   0.85,
+		ZipfExponent:      1.2,
+		CoursesMean:       5,
+		CoursesStddev:     1.5,
+		MinCourses:        1,
+		MaxCourses:        10,
+		RequestsPerDevice: 5,
+		History:           7 * 24 * time.Hour,
+		Profiles: []ResponseProfile{
+			{Name: "reliable", Share: 0.6, ResponseRate: 0.95, MeanLatency: 10 * time.Second},
+			{Name: "occasional", Share: 0.3, ResponseRate: 0.5, MeanLatency: 2 * time.Minute},
+			{Name: "dormant", Share: 0.1, ResponseRate: 0.02, MeanLatency: 10 * time.Minute},
+		},
+		Bulk: repository.DefaultBulkConfig(),
+	}
+}
+
+// Validate returns an error describing the first invalid setting of c.
+func (c *Config) Validate() error {
+	switch {
+	case c.Devices < 0 || c.Lectures < 0:
+		return fmt.Errorf("negative number of devices (%d) or lectures (%d)", c.Devices, c.Lectures)
+	case c.Faculties < 1:
+		return fmt.Errorf("at least one faculty is needed, got %d", c.Faculties)
+	case c.FacultyAffinity < 0 || c.FacultyAffinity > 1:
+		return fmt.Errorf("faculty affinity %g is not a probability", c.FacultyAffinity)
+	case c.ZipfExponent <= 1:
+		return fmt.Errorf("zipf exponent %g has to be greater than one", c.ZipfExponent)
+	case c.MinCourses < 1 || c.MaxCourses < c.MinCourses:
+		return fmt.Errorf("invalid range of courses per student %d-%d", c.MinCourses, c.MaxCourses)
+	case c.RequestsPerDevice < 0:
+		return fmt.Errorf("negative number of requests per device %g", c.RequestsPerDevice)
+	case c.History <= 0:
+		return fmt.Errorf("history %s has to be positive", c.History)
+	case len(c.Profiles) == 0:
+		return errors.New("at least one response profile is needed")
+	}
+
+	for _, profile := range c.Profiles {
+		if profile.Share < 0 || profile.ResponseRate < 0 || profile.ResponseRate > 1 || profile.MeanLatency < 0 {
+			return fmt.Errorf("invalid response profile %s", profile.Name)
+		}
+	}
+
+	return nil
+}
+
+// Stats counts the generated records.
+type Stats struct {
+	Devices     int
+	Lectures    int
+	Enrollments int
+	RequestLogs int
+	// Profiles counts the devices of every response profile.
+	Profiles map[string]int
+	Bulk     *repository.BulkStats
+}
+
+type Generator struct {
+	Config Config
+	random *rand.Rand
+}
+
+func NewGenerator(config Config) (*Generator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Generator{
+		Config: config,
+		random: rand.New(rand.NewSource(config.Seed)),
+	}, nil
+}
+
+// Generate writes the synthetic data to repo, which has to be empty.
+func (g *Generator) Generate(repo repository.Repository) (*Stats, error) {
+	existing, err := repo.GetDevices()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(*existing) > 0 {
+		return nil, fmt.Errorf("%w: %d devices", errNotEmpty, len(*existing))
+	}
+
+	stats := Stats{Profiles: make(map[string]int)}
+
+	lectures, faculties := g.lectures()
+	devices := g.devices()
+	deviceLectures := g.enrollments(devices, faculties)
+	requestLogs := g.requestLogs(devices, stats.Profiles)
+
+	stats.Lectures = len(*lectures)
+	stats.Devices = len(*devices)
+	stats.Enrollments = len(*deviceLectures)
+	stats.RequestLogs = len(*requestLogs)
+
+	stats.Bulk, err = repo.BulkLoad(&repository.Dataset{
+		Lectures:       *lectures,
+		Devices:        *devices,
+		DeviceLectures: *deviceLectures,
+		RequestLogs:    *requestLogs,
+	}, g.Config.Bulk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof(
+		"Generated %d devices, %d lectures, %d enrollments and %d request logs (seed %d)",
+		stats.Devices,
+		stats.Lectures,
+		stats.Enrollments,
+		stats.RequestLogs,
+		g.Config.Seed,
+	)
+
+	return &stats, nil
+}
+
+func (g *Generator) devices() *[]model.IOSDevice {
+	devices := make([]model.IOSDevice, 0, g.Config.Devices)
+	publicKey := make([]byte, 65)
+
+	for i := 0; i < g.Config.Devices; i++ {
+		g.random.Read(publicKey)
+
+		devices = append(devices, model.IOSDevice{
+			DeviceID:  g.uuid(),
+			CreatedAt: g.Config.Now.Add(-g.duration(g.Config.History)),
+			PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+			State:     model.IOSDeviceStateActive,
+		})
+	}
+
+	return &devices
+}
+
+// requestLogs creates the request history of every device according to the
+// response profile the device is drawn into. Requests that were not
+// answered are expired, or still sent if they can be answered at Now.
+func (g *Generator) requestLogs(devices *[]model.IOSDevice, profileCounts map[string]int) *[]model.IOSDeviceRequestLog {
+	var requestLogs []model.IOSDeviceRequestLog
+
+	for _, device := range *devices {
+		profile := g.profile()
+		profileCounts[profile.Name]++
+
+		count := g.random.Intn(int(2*g.Config.RequestsPerDevice) + 1)
+
+		for i := 0; i < count; i++ {
+			requestType := model.IOSLectureUpdateRequestType
+
+			if g.random.Float64() < 0.2 {
+				requestType = model.IOSTokenRequestType
+			}
+
+			createdAt := device.CreatedAt.Add(g.duration(g.Config.Now.Sub(device.CreatedAt)))
+			requestLog := model.NewIOSDeviceRequestLog(device.DeviceID, requestType, createdAt)
+			requestLog.RequestID = g.uuid()
+
+			latency := time.Duration(g.random.ExpFloat64() * float64(profile.MeanLatency))
+			handledAt := createdAt.Add(latency)
+
+			switch {
+			case g.random.Float64() < profile.ResponseRate && handledAt.Before(requestLog.Expiry()) && handledAt.Before(g.Config.Now):
+				requestLog.Status = model.IOSRequestStatusHandled
+				requestLog.HandledAt.Time = handledAt
+				requestLog.HandledAt.Valid = true
+			case requestLog.IsExpiredAt(g.Config.Now):
+				requestLog.Status = model.IOSRequestStatusExpired
+			default:
+				requestLog.Status = model.IOSRequestStatusSent
+			}
+
+			requestLogs = append(requestLogs, requestLog)
+		}
+	}
+
+	return &requestLogs
+}
+
+func (g *Generator) profile() ResponseProfile {
+	total := 0.0
+
+	for _, profile := range g.Config.Profiles {
+		total += profile.Share
+	}
+
+	pick := g.random.Float64() * total
+
+	for _, profile := range g.Config.Profiles {
+		if pick < profile.Share {
+			return profile
+		}
+
+		pick -= profile.Share
+	}
+
+	return g.Config.Profiles[len(g.Config.Profiles)-1]
+}
+
+// duration returns a uniformly distributed duration in [0, max).
+func (g *Generator) duration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(g.random.Int63n(int64(max)))
+}
+
+// courses draws the number of lectures of a student.
+func (g *Generator) courses() int {
+	count := int(math.Round(g.random.NormFloat64()*g.Config.CoursesStddev + g.Config.CoursesMean))
+
+	if count < g.Config.MinCourses {
+		return g.Config.MinCourses
+	}
+
+	if count > g.Config.MaxCourses {
+		return g.Config.MaxCourses
+	}
+
+	return count
+}
+
+// uuid returns a random (version 4) UUID drawn from the seeded random
+// numbers.
+func (g *Generator) uuid() string {
+	b := make([]byte, 16)
+
+	g.random.Read(b)
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ParseProfiles parses response profiles in the format
+// name:share:responseRate:meanLatency separated by commas, e.g.
+// "reliable:0.6:0.95:10s,dormant:0.4:0.02:10m".
+func ParseProfiles(s string) ([]ResponseProfile, error) {
+	var profiles []ResponseProfile
+
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("response profile %q is not name:share:responseRate:meanLatency", part)
+		}
+
+		share, err := strconv.ParseFloat(fields[1], 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("share of response profile %s: %w", fields[0], err)
+		}
+
+		responseRate, err := strconv.ParseFloat(fields[2], 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("response rate of response profile %s: %w", fields[0], err)
+		}
+
+		meanLatency, err := time.ParseDuration(fields[3])
+
+		if err != nil {
+			return nil, fmt.Errorf("mean latency of response profile %s: %w", fields[0], err)
+		}
+
+		profiles = append(profiles, ResponseProfile{
+			Name:         fields[0],
+			Share:        share,
+			ResponseRate: responseRate,
+			MeanLatency:  meanLatency,
+		})
+	}
+
+	return profiles, nil
+}
+
+// FormatProfiles formats profiles in the format read by ParseProfiles.
+func FormatProfiles(profiles []ResponseProfile) string {
+	parts := make([]string, 0, len(profiles))
+
+	for _, profile := range profiles {
+		parts = append(parts, fmt.Sprintf("%s:%g:%g:%s", profile.Name, profile.Share, profile.ResponseRate, profile.MeanLatency))
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"reflect"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+func testConfig(seed int64) Config {
+	config := DefaultConfig()
+	config.Seed = seed
+	config.Now = time.Date(2026, time.November, 2, 12, 0, 0, 0, time.UTC)
+	config.Devices = 200
+	config.Lectures = 40
+	config.Faculties = 4
+
+	return config
+}
+
+// generate returns everything Generate wrote to a new repository.
+func generate(t *testing.T, config Config) []interface{} {
+	t.Helper()
+
+	g, err := NewGenerator(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := memory.New()
+
+	if _, err := g.Generate(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := repo.GetDevices()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deviceLectures, err := repo.GetDeviceLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestLogs, err := repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []interface{}{*devices, *lectures, *deviceLectures, *requestLogs}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	first := generate(t, testConfig(7))
+	second := generate(t, testConfig(7))
+
+	for i := range first {
+		if !reflect.DeepEqual(first[i], second[i]) {
+			t.Errorf("the same seed and time generated different %T", first[i])
+		}
+	}
+
+	if reflect.DeepEqual(first[2], generate(t, testConfig(8))[2]) {
+		t.Error("different seeds generated the same enrollments")
+	}
+}
+
+func TestGenerateRejectsNonEmptyRepository(t *testing.T) {
+	g, err := NewGenerator(testConfig(1))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := memory.New()
+
+	if _, err := g.Generate(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Generate(repo); err == nil {
+		t.Error("generated data into a repository that is not empty")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(config *Config)
+	}{
+		{"negative devices", func(config *Config) { config.Devices = -1 }},
+		{"no faculty", func(config *Config) { config.Faculties = 0 }},
+		{"affinity above one", func(config *Config) { config.FacultyAffinity = 1.5 }},
+		{"zipf exponent of one", func(config *Config) { config.ZipfExponent = 1 }},
+		{"courses range", func(config *Config) { config.MinCourses, config.MaxCourses = 5, 4 }},
+		{"no history", func(config *Config) { config.History = 0 }},
+		{"no profile", func(config *Config) { config.Profiles = nil }},
+		{"response rate above one", func(config *Config) { config.Profiles[0].ResponseRate = 2 }},
+	}
+
+	for _, test := range tests {
+		config := testConfig(1)
+		test.modify(&config)
+
+		if _, err := NewGenerator(config); err == nil {
+			t.Errorf("%s: config was accepted", test.name)
+		}
+	}
+}
+
+func TestProfilesRoundTrip(t *testing.T) {
+	profiles := DefaultConfig().Profiles
+	parsed, err := ParseProfiles(FormatProfiles(profiles))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(parsed, profiles) {
+		t.Errorf("expected %+v, got %+v", profiles, parsed)
+	}
+
+	if _, err := ParseProfiles("reliable:0.6"); err == nil {
+		t.Error("incomplete profile was accepted")
+	}
+}
@@ -0,0 +1,117 @@
+// Package grades plans which devices are asked to refresh their grades.
+package grades
+
+import (
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"time"
+)
+
+type Config struct {
+	// BatchSize is the maximum number of devices asked per plan at the
+	// default priority.
+	BatchSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		BatchSize: 500,
+	}
+}
+
+type Planner struct {
+	Config     Config
+	Repository repository.Repository
+	Clock      scheduling.Clock
+}
+
+func NewPlanner(config Config, repo repository.Repository) *Planner {
+	return &Planner{
+		Config:     config,
+		Repository: repo,
+		Clock:      scheduling.SystemClock{},
+	}
+}
+
+// Interval returns how long the grades of a device are considered fresh at
+// priority. At the default priority and above it is the
+// IOSMinimumUpdateInterval, lower priorities stretch it proportionally.
+func Interval(priority int) time.Duration {
+	minimum := model.IOSMinimumUpdateInterval * time.Minute
+	defaultPriority := model.DefaultIOSSchedulingPriority().Priority
+
+	if priority >= defaultPriority {
+		return minimum
+	}
+
+	if priority <= 0 {
+		priority = 1
+	}
+
+	return minimum * time.Duration(defaultPriority) / time.Duration(priority)
+}
+
+// BatchSizeFor returns the maximum number of devices asked per plan at
+// priority. It scales with the priority like the push budget of the
+// scheduler.
+func (c *Config) BatchSizeFor(priority int) int {
+	batchSize := c.BatchSize * priority / model.DefaultIOSSchedulingPriority().Priority
+
+	if batchSize < 1 {
+		return 1
+	}
+
+	return batchSize
+}
+
+// Plan picks the devices whose grades are older than the interval of
+// priority and creates a CAMPUS_TOKEN_REQUEST for each of them. A planned
+// device is not planned again while its request is pending. The
+// IOSScheduledUpdateLog of the device is moved when it answers, so a device
+// that does not answer is planned again once its request expired. Nothing
+// is planned at priorities of zero or below.
+func (p *Planner) Plan(priority *model.IOSSchedulingPriority) (*[]model.IOSDeviceRequestLog, error) {
+	if priority.Priority <= 0 {
+		return &[]model.IOSDeviceRequestLog{}, nil
+	}
+
+	now := p.Clock.Now()
+	interval := Interval(priority.Priority)
+	before := now.Add(-interval)
+
+	due, err := p.Repository.GetDevicesDueForUpdate(
+		model.IOSUpdateTypeGrades,
+		model.IOSTokenRequestType,
+		before,
+		now,
+		p.Config.BatchSizeFor(priority.Priority),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := p.Repository.CreateScheduledRequests(
+		due,
+		model.IOSUpdateTypeGrades,
+		model.IOSTokenRequestType,
+		before,
+		now,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof(
+		"Planned grade refresh for %d/%d due devices (interval %s, priority %d)",
+		len(*requests),
+		len(*due),
+		interval,
+		priority.Priority,
+	)
+
+	return requests, nil
+}
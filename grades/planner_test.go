@@ -0,0 +1,188 @@
+package grades
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+func newTestPlanner(t *testing.T, batchSize int, deviceIds ...string) (*Planner, *scheduling.FixedClock) {
+	t.Helper()
+
+	repo := memory.New()
+	devices := make([]model.IOSDevice, 0, len(deviceIds))
+
+	for _, deviceId := range deviceIds {
+		devices = append(devices, model.IOSDevice{DeviceID: deviceId})
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := scheduling.NewFixedClock(start)
+	planner := NewPlanner(Config{BatchSize: batchSize}, repo)
+	planner.Clock = clock
+
+	return planner, clock
+}
+
+func plan(t *testing.T, p *Planner) map[string]string {
+	t.Helper()
+
+	requests, err := p.Plan(model.DefaultIOSSchedulingPriority())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planned := make(map[string]string, len(*requests))
+
+	for _, request := range *requests {
+		if request.RequestType != model.IOSTokenRequestType {
+			t.Errorf("planned a %s instead of a %s", request.RequestType, model.IOSTokenRequestType)
+		}
+
+		planned[request.DeviceID] = request.RequestID
+	}
+
+	return planned
+}
+
+func expectPlanned(t *testing.T, planned map[string]string, deviceIds ...string) {
+	t.Helper()
+
+	if len(planned) != len(deviceIds) {
+		t.Errorf("planned %v instead of %v", planned, deviceIds)
+		return
+	}
+
+	for _, deviceId := range deviceIds {
+		if _, ok := planned[deviceId]; !ok {
+			t.Errorf("planned %v instead of %v", planned, deviceIds)
+			return
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	minimum := model.IOSMinimumUpdateInterval * time.Minute
+	defaultPriority := model.DefaultIOSSchedulingPriority().Priority
+
+	for priority, want := range map[int]time.Duration{
+		defaultPriority + 3: minimum,
+		defaultPriority:     minimum,
+		1:                   minimum * time.Duration(defaultPriority),
+		0:                   minimum * time.Duration(defaultPriority),
+		-2:                  minimum * time.Duration(defaultPriority),
+	} {
+		if got := Interval(priority); got != want {
+			t.Errorf("interval at priority %d is %s instead of %s", priority, got, want)
+		}
+	}
+
+	if Interval(1) <= Interval(defaultPriority-1) {
+		t.Error("lower priorities do not stretch the interval")
+	}
+}
+
+func TestBatchSizeFor(t *testing.T) {
+	config := Config{BatchSize: 500}
+	defaultPriority := model.DefaultIOSSchedulingPriority().Priority
+
+	for priority, want := range map[int]int{
+		defaultPriority:     500,
+		defaultPriority * 2: 1000,
+		1:                   500 / defaultPriority,
+		0:                   1,
+		-1:                  1,
+	} {
+		if got := config.BatchSizeFor(priority); got != want {
+			t.Errorf("batch size at priority %d is %d instead of %d", priority, got, want)
+		}
+	}
+}
+
+func TestPlanNothingWithoutPriority(t *testing.T) {
+	planner, _ := newTestPlanner(t, 10, "device-a")
+
+	for _, priority := range []int{0, -1} {
+		requests, err := planner.Plan(&model.IOSSchedulingPriority{Priority: priority})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(*requests) != 0 {
+			t.Errorf("planned %d requests at priority %d", len(*requests), priority)
+		}
+	}
+}
+
+func TestPlanRespectsTheBatchSize(t *testing.T) {
+	planner, _ := newTestPlanner(t, 2, "device-a", "device-b", "device-c")
+
+	if planned := plan(t, planner); len(planned) != 2 {
+		t.Errorf("planned %v with a batch size of 2", planned)
+	}
+
+	// The batch is not taken by the devices that were asked already.
+	expectPlanned(t, plan(t, planner), "device-c")
+}
+
+func TestPlanAsksSilentDevicesAgainAfterTheExpiry(t *testing.T) {
+	planner, clock := newTestPlanner(t, 10, "device-a", "device-b")
+
+	first := plan(t, planner)
+	expectPlanned(t, first, "device-a", "device-b")
+
+	// device-a answers, device-b does not.
+	clock.Advance(time.Minute)
+	accept := func(requestLog *model.IOSDeviceRequestLog) error { return nil }
+
+	if _, err := planner.Repository.HandleRequest(first["device-a"], clock.Now(), accept); err != nil {
+		t.Fatal(err)
+	}
+
+	// device-b is not asked again while its request is pending.
+	clock.Advance(Interval(model.DefaultIOSSchedulingPriority().Priority))
+	expectPlanned(t, plan(t, planner))
+
+	// Once the request expired device-b is asked again, device-a only
+	// after the interval since its answer passed.
+	clock.Set(start.Add(model.DefaultIOSRequestExpiry()[model.IOSTokenRequestType] + time.Second))
+	expectPlanned(t, plan(t, planner), "device-a", "device-b")
+}
+
+func TestPlanWaitsForTheIntervalAfterAnAnswer(t *testing.T) {
+	planner, clock := newTestPlanner(t, 10, "device-a")
+	accept := func(requestLog *model.IOSDeviceRequestLog) error { return nil }
+
+	requestId := plan(t, planner)["device-a"]
+
+	if _, err := planner.Repository.HandleRequest(requestId, clock.Now(), accept); err != nil {
+		t.Fatal(err)
+	}
+
+	interval := Interval(1)
+
+	clock.Advance(interval - time.Minute)
+
+	if planned, err := planner.Plan(&model.IOSSchedulingPriority{Priority: 1}); err != nil {
+		t.Fatal(err)
+	} else if len(*planned) != 0 {
+		t.Errorf("device-a was asked again %s after its answer at an interval of %s", interval-time.Minute, interval)
+	}
+
+	clock.Advance(2 * time.Minute)
+
+	if planned, err := planner.Plan(&model.IOSSchedulingPriority{Priority: 1}); err != nil {
+		t.Fatal(err)
+	} else if len(*planned) != 1 {
+		t.Errorf("device-a was not asked again after the interval of %s", interval)
+	}
+}
@@ -0,0 +1,232 @@
+// Package lifecycle moves devices between the states active, suspect and
+// unregistered based on the feedback of the push delivery and the history of
+// their answers, and purges unregistered devices.
+//
+// Suspect devices are not selected, so they rarely get a request they could
+// answer. After a probation they are therefore tried again, unless they did
+// not answer a single request within the history, in which case they are
+// unregistered and purged after the retention period.
+package lifecycle
+
+import (
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"time"
+)
+
+type Config struct {
+	// SuspectAfter is the number of consecutive expired requests after which
+	// an active device becomes suspect.
+	SuspectAfter int
+	// History is how far back the requests are considered.
+	History time.Duration
+	// Probation is how long a device stays suspect before it is active
+	// again or unregistered if it did not answer within History.
+	Probation time.Duration
+	// Retention is how long unregistered devices are kept before they are
+	// purged.
+	Retention time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SuspectAfter: 5,
+		History:      14 * 24 * time.Hour,
+		Probation:    7 * 24 * time.Hour,
+		Retention:    30 * 24 * time.Hour,
+	}
+}
+
+// Tracker applies the lifecycle rules. It can be used as
+// push.FeedbackHandler.
+type Tracker struct {
+	Config     Config
+	Repository repository.Repository
+	// Clock tells the time the states change at.
+	Clock scheduling.Clock
+}
+
+func NewTracker(config Config, repo repository.Repository) *Tracker {
+	return &Tracker{
+		Config:     config,
+		Repository: repo,
+		Clock:      scheduling.SystemClock{},
+	}
+}
+
+// InvalidTokens unregisters the devices whose token was reported as invalid
+// by the push delivery.
+func (t *Tracker) InvalidTokens(deviceIds *[]string) error {
+	unregistered, err := t.Repository.SetDevicesState(deviceIds, model.IOSDeviceStateUnregistered, t.Clock.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if unregistered > 0 {
+		log.Infof("Unregistered %d devices reported by the push delivery", unregistered)
+	}
+
+	return nil
+}
+
+// EvaluateResponses marks active devices whose last SuspectAfter requests
+// expired as suspect and suspect devices that answered their latest request
+// as active again. Requests that are still open or were superseded are
+// ignored, as are the expired requests of an active device from before it
+// became active.
+//
+// Devices that are suspect for longer than Probation are active again if
+// they answered a request within History and unregistered otherwise.
+func (t *Tracker) EvaluateResponses() error {
+	now := t.Clock.Now()
+	requestLogs, err := t.Repository.GetRequestLogsSince(now.Add(-t.Config.History))
+
+	if err != nil {
+		return err
+	}
+
+	// unanswered are the creation times of the expired requests after the
+	// latest answer of each device.
+	unanswered := make(map[string][]time.Time)
+	answered := make(map[string]bool)
+
+	// The request logs are ordered newest first, so counting stops at the
+	// first answered request of a device.
+	for _, requestLog := range *requestLogs {
+		if answered[requestLog.DeviceID] {
+			continue
+		}
+
+		switch requestLog.Status {
+		case model.IOSRequestStatusHandled:
+			answered[requestLog.DeviceID] = true
+		case model.IOSRequestStatusExpired:
+			unanswered[requestLog.DeviceID] = append(unanswered[requestLog.DeviceID], requestLog.CreatedAt)
+		}
+	}
+
+	suspect, err := t.suspects(unanswered)
+
+	if err != nil {
+		return err
+	}
+
+	var recovered []string
+
+	for deviceId := range answered {
+		if len(unanswered[deviceId]) == 0 {
+			recovered = append(recovered, deviceId)
+		}
+	}
+
+	suspected, err := t.Repository.SetDevicesStateFrom(suspect, model.IOSDeviceStateActive, model.IOSDeviceStateSuspect, now)
+
+	if err != nil {
+		return err
+	}
+
+	reactivated, err := t.Repository.SetDevicesStateFrom(&recovered, model.IOSDeviceStateSuspect, model.IOSDeviceStateActive, now)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Marked %d devices as suspect and %d as active again", suspected, reactivated)
+
+	return t.endProbations(now, answered)
+}
+
+// suspects returns the active devices with at least SuspectAfter unanswered
+// requests since they became active.
+func (t *Tracker) suspects(unanswered map[string][]time.Time) (*[]string, error) {
+	var candidates []string
+
+	for deviceId, createdAt := range unanswered {
+		if len(createdAt) >= t.Config.SuspectAfter {
+			candidates = append(candidates, deviceId)
+		}
+	}
+
+	devices, err := t.Repository.GetDevicesByIds(&candidates)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suspect := []string{}
+
+	for _, device := range *devices {
+		if !device.IsActive() {
+			continue
+		}
+
+		count := 0
+
+		for _, createdAt := range unanswered[device.DeviceID] {
+			if !device.StateChangedAt.Valid || !createdAt.Before(device.StateChangedAt.Time) {
+				count++
+			}
+		}
+
+		if count >= t.Config.SuspectAfter {
+			suspect = append(suspect, device.DeviceID)
+		}
+	}
+
+	return &suspect, nil
+}
+
+// endProbations moves the devices suspect for longer than Probation to
+// active if they answered a request within History and to unregistered
+// otherwise.
+func (t *Tracker) endProbations(now time.Time, answered map[string]bool) error {
+	devices, err := t.Repository.GetDevicesInState(model.IOSDeviceStateSuspect, now.Add(-t.Config.Probation))
+
+	if err != nil {
+		return err
+	}
+
+	var retried []string
+	var silent []string
+
+	for _, device := range *devices {
+		if answered[device.DeviceID] {
+			retried = append(retried, device.DeviceID)
+		} else {
+			silent = append(silent, device.DeviceID)
+		}
+	}
+
+	reactivated, err := t.Repository.SetDevicesStateFrom(&retried, model.IOSDeviceStateSuspect, model.IOSDeviceStateActive, now)
+
+	if err != nil {
+		return err
+	}
+
+	unregistered, err := t.Repository.SetDevicesStateFrom(&silent, model.IOSDeviceStateSuspect, model.IOSDeviceStateUnregistered, now)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Ended the probation of %d devices and unregistered %d silent devices", reactivated, unregistered)
+
+	return nil
+}
+
+// Purge deletes the devices that are unregistered for longer than the
+// retention period.
+func (t *Tracker) Purge() error {
+	purged, err := t.Repository.PurgeUnregisteredDevices(t.Clock.Now().Add(-t.Config.Retention))
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Purged %d unregistered devices", purged)
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+// newTestTracker returns a tracker at start on a repository with the
+// devices.
+func newTestTracker(t *testing.T, devices ...model.IOSDevice) (*Tracker, *scheduling.FixedClock) {
+	t.Helper()
+
+	repo := memory.New()
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := scheduling.NewFixedClock(start)
+	tracker := NewTracker(DefaultConfig(), repo)
+	tracker.Clock = clock
+
+	return tracker, clock
+}
+
+func suspectSince(deviceId string, since time.Time) model.IOSDevice {
+	return model.IOSDevice{
+		DeviceID:       deviceId,
+		State:          model.IOSDeviceStateSuspect,
+		StateChangedAt: sql.NullTime{Time: since, Valid: true},
+	}
+}
+
+// createRequests creates a request of deviceId with every status, one hour
+// apart and the last one at end.
+func createRequests(t *testing.T, tracker *Tracker, deviceId string, end time.Time, statuses ...string) {
+	t.Helper()
+
+	requestLogs := make([]model.IOSDeviceRequestLog, 0, len(statuses))
+
+	for i, status := range statuses {
+		requestLog := model.NewIOSDeviceRequestLog(deviceId, model.IOSLectureUpdateRequestType, end.Add(time.Duration(i-len(statuses)+1)*time.Hour))
+		requestLog.Status = status
+		requestLogs = append(requestLogs, requestLog)
+	}
+
+	if err := tracker.Repository.CreateRequestLogs(&requestLogs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expired(count int) []string {
+	statuses := make([]string, count)
+
+	for i := range statuses {
+		statuses[i] = model.IOSRequestStatusExpired
+	}
+
+	return statuses
+}
+
+func expectStates(t *testing.T, tracker *Tracker, want map[string]string) {
+	t.Helper()
+
+	devices, err := tracker.Repository.GetDevices()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string, len(*devices))
+
+	for _, device := range *devices {
+		got[device.DeviceID] = device.State
+	}
+
+	for deviceId, state := range want {
+		if got[deviceId] != state {
+			t.Errorf("%s is %q instead of %q", deviceId, got[deviceId], state)
+		}
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("devices %v instead of %v", got, want)
+	}
+}
+
+func TestEvaluateResponsesSuspectsSilentDevices(t *testing.T) {
+	tracker, _ := newTestTracker(
+		t,
+		model.IOSDevice{DeviceID: "device-a"},
+		model.IOSDevice{DeviceID: "device-b"},
+		model.IOSDevice{DeviceID: "device-c"},
+		model.IOSDevice{DeviceID: "device-d"},
+	)
+
+	createRequests(t, tracker, "device-a", start.Add(-time.Hour), expired(5)...)
+	createRequests(t, tracker, "device-b", start.Add(-time.Hour), expired(4)...)
+	// The latest request of device-c was answered.
+	createRequests(t, tracker, "device-c", start.Add(-time.Hour), append(expired(5), model.IOSRequestStatusHandled)...)
+	// Open and superseded requests are not counted.
+	createRequests(
+		t,
+		tracker,
+		"device-d",
+		start.Add(-time.Hour),
+		append(expired(4), model.IOSRequestStatusSent, model.IOSRequestStatusSuperseded)...,
+	)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateSuspect,
+		"device-b": model.IOSDeviceStateActive,
+		"device-c": model.IOSDeviceStateActive,
+		"device-d": model.IOSDeviceStateActive,
+	})
+
+	devices, err := tracker.Repository.GetDevicesByIds(&[]string{"device-a"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changedAt := (*devices)[0].StateChangedAt; !changedAt.Valid || !changedAt.Time.Equal(start) {
+		t.Errorf("device-a became suspect at %v instead of %s", changedAt, start)
+	}
+}
+
+func TestEvaluateResponsesRecoversAnsweringDevices(t *testing.T) {
+	tracker, _ := newTestTracker(
+		t,
+		suspectSince("device-a", start.Add(-24*time.Hour)),
+		suspectSince("device-b", start.Add(-24*time.Hour)),
+	)
+
+	createRequests(t, tracker, "device-a", start.Add(-time.Hour), append(expired(5), model.IOSRequestStatusHandled)...)
+	createRequests(t, tracker, "device-b", start.Add(-time.Hour), model.IOSRequestStatusHandled, model.IOSRequestStatusExpired)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateActive,
+		"device-b": model.IOSDeviceStateSuspect,
+	})
+}
+
+func TestEvaluateResponsesEndsProbations(t *testing.T) {
+	tracker, clock := newTestTracker(
+		t,
+		suspectSince("device-a", start.Add(-8*24*time.Hour)),
+		suspectSince("device-b", start.Add(-8*24*time.Hour)),
+		suspectSince("device-c", start.Add(-2*24*time.Hour)),
+		model.IOSDevice{DeviceID: "device-d"},
+	)
+
+	// device-a answered within the history before it stopped answering.
+	createRequests(t, tracker, "device-a", start.Add(-8*24*time.Hour), append([]string{model.IOSRequestStatusHandled}, expired(5)...)...)
+	createRequests(t, tracker, "device-b", start.Add(-8*24*time.Hour), expired(5)...)
+	createRequests(t, tracker, "device-c", start.Add(-2*24*time.Hour), expired(5)...)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	// device-c is still in its probation.
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateActive,
+		"device-b": model.IOSDeviceStateUnregistered,
+		"device-c": model.IOSDeviceStateSuspect,
+		"device-d": model.IOSDeviceStateActive,
+	})
+
+	// The requests device-a left unanswered before its probation ended
+	// are not counted again.
+	clock.Advance(time.Hour)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateActive,
+		"device-b": model.IOSDeviceStateUnregistered,
+		"device-c": model.IOSDeviceStateSuspect,
+		"device-d": model.IOSDeviceStateActive,
+	})
+
+	// device-a is suspect again once it leaves new requests unanswered.
+	clock.Advance(5 * time.Hour)
+	createRequests(t, tracker, "device-a", clock.Now(), expired(5)...)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateSuspect,
+		"device-b": model.IOSDeviceStateUnregistered,
+		"device-c": model.IOSDeviceStateSuspect,
+		"device-d": model.IOSDeviceStateActive,
+	})
+}
+
+func TestPurgeDeletesSilentDevicesAfterTheRetention(t *testing.T) {
+	tracker, clock := newTestTracker(
+		t,
+		suspectSince("device-a", start.Add(-8*24*time.Hour)),
+		model.IOSDevice{DeviceID: "device-b"},
+	)
+
+	createRequests(t, tracker, "device-a", start.Add(-8*24*time.Hour), expired(5)...)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(tracker.Config.Retention - time.Minute)
+
+	if err := tracker.Purge(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateUnregistered,
+		"device-b": model.IOSDeviceStateActive,
+	})
+
+	clock.Advance(2 * time.Minute)
+
+	if err := tracker.Purge(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-b": model.IOSDeviceStateActive,
+	})
+}
+
+func TestInvalidTokensUnregistersDevices(t *testing.T) {
+	tracker, _ := newTestTracker(t, model.IOSDevice{DeviceID: "device-a"}, model.IOSDevice{DeviceID: "device-b"})
+
+	if err := tracker.InvalidTokens(&[]string{"device-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, tracker, map[string]string{
+		"device-a": model.IOSDeviceStateUnregistered,
+		"device-b": model.IOSDeviceStateActive,
+	})
+
+	devices, err := tracker.Repository.GetDevicesByIds(&[]string{"device-a"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changedAt := (*devices)[0].StateChangedAt; !changedAt.Valid || !changedAt.Time.Equal(start) {
+		t.Errorf("device-a was unregistered at %v instead of %s", changedAt, start)
+	}
+}
@@ -0,0 +1,141 @@
+// Package loader streams the enrollments out of the repository in chunks and
+// builds the representation of the solver from them. Only a single chunk of
+// enrollments is held in memory at a time, the representation itself shares
+// one LectureOverlapped per lecture between all devices.
+package loader
+
+import (
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+type Config struct {
+	// ChunkSize is the number of enrollments read per query.
+	ChunkSize int
+	// ProgressInterval is the minimum time between two progress reports.
+	ProgressInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ChunkSize:        10000,
+		ProgressInterval: 5 * time.Second,
+	}
+}
+
+// Progress tells how far a load got.
+type Progress struct {
+	Chunks  int
+	Rows    int
+	Elapsed time.Duration
+	// Building is the part of Elapsed spent on building the representation
+	// of the solver from the chunks, the rest was spent on the queries.
+	Building time.Duration
+	// Done is true for the report after the last chunk.
+	Done bool
+}
+
+type Loader struct {
+	Config     Config
+	Repository repository.Enrollments
+	// Report is called at most every ProgressInterval during a load and
+	// once after the last chunk.
+	Report func(progress Progress)
+	// Last is the progress after the last chunk of the last load.
+	Last Progress
+}
+
+func NewLoader(config Config, repo repository.Enrollments) *Loader {
+	return &Loader{
+		Config:     config,
+		Repository: repo,
+		Report:     logProgress,
+	}
+}
+
+func logProgress(progress Progress) {
+	if progress.Done {
+		log.Infof("Loaded %d enrollments in %d chunks in %s", progress.Rows, progress.Chunks, progress.Elapsed)
+		return
+	}
+
+	log.Infof("Loading enrollments: %d in %d chunks after %s", progress.Rows, progress.Chunks, progress.Elapsed)
+}
+
+// page reads the chunk of enrollments after after, see
+// repository.Enrollments.GetDeviceLecturesAfter.
+type page func(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error)
+
+// ReadyDevicesLectures builds the solver.DeviceToOverlappedLectures of the
+// enrollments of the ready devices like solver.DevicesLecturesToMap.
+// Enrollments of lectures that are not part of lectures are skipped.
+func (l *Loader) ReadyDevicesLectures(lectures *solver.LectureToOverlapped) (*solver.DeviceToOverlappedLectures, error) {
+	devicesLectures := make(solver.DeviceToOverlappedLectures)
+
+	err := l.each(l.Repository.GetReadyDeviceLecturesAfter, func(chunk *[]model.IOSDeviceLecture) {
+		solver.AddDevicesLectures(&devicesLectures, chunk, lectures)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &devicesLectures, nil
+}
+
+// each calls fn with every chunk of next until a chunk is not full.
+func (l *Loader) each(next page, fn func(chunk *[]model.IOSDeviceLecture)) error {
+	startTime := time.Now()
+	lastReport := startTime
+	progress := Progress{}
+	chunkSize := l.Config.ChunkSize
+
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	var after model.IOSDeviceLecture
+
+	for {
+		chunk, err := next(after, chunkSize)
+
+		if err != nil {
+			return err
+		}
+
+		if len(*chunk) > 0 {
+			buildStartTime := time.Now()
+			fn(chunk)
+			progress.Building += time.Now().Sub(buildStartTime)
+
+			progress.Chunks++
+			progress.Rows += len(*chunk)
+
+			last := (*chunk)[len(*chunk)-1]
+			after = model.IOSDeviceLecture{DeviceId: last.DeviceId, LectureId: last.LectureId}
+		}
+
+		progress.Elapsed = time.Now().Sub(startTime)
+		progress.Done = len(*chunk) < chunkSize
+
+		if progress.Done {
+			l.Last = progress
+			l.report(progress)
+			return nil
+		}
+
+		if time.Now().Sub(lastReport) >= l.Config.ProgressInterval {
+			l.report(progress)
+			lastReport = time.Now()
+		}
+	}
+}
+
+func (l *Loader) report(progress Progress) {
+	if l.Report != nil {
+		l.Report(progress)
+	}
+}
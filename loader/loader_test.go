@@ -0,0 +1,130 @@
+package loader
+
+import (
+	"sort"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/solver"
+	"testing"
+)
+
+// newTestRepository returns a repository with 7 enrollments of 3 devices, of
+// which device-c is suspect.
+func newTestRepository(t *testing.T) *memory.Repository {
+	t.Helper()
+
+	repo := memory.New()
+	devices := []model.IOSDevice{
+		{DeviceID: "device-a", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-b", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-c", State: model.IOSDeviceStateSuspect},
+	}
+	lectures := []model.IOSLecture{
+		{Id: "lecture-1", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-2", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-3", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-4", Semester: model.IOSLectureSemesterWinter},
+	}
+	deviceLectures := []model.IOSDeviceLecture{
+		{DeviceId: "device-b", LectureId: "lecture-2"},
+		{DeviceId: "device-a", LectureId: "lecture-3"},
+		{DeviceId: "device-a", LectureId: "lecture-1"},
+		{DeviceId: "device-b", LectureId: "lecture-1"},
+		{DeviceId: "device-c", LectureId: "lecture-4"},
+		{DeviceId: "device-a", LectureId: "lecture-2"},
+		{DeviceId: "device-b", LectureId: "lecture-3"},
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&lectures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDeviceLectures(&deviceLectures); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+// describe renders devicesLectures independent of the order of the map and
+// the enrollments.
+func describe(devicesLectures *solver.DeviceToOverlappedLectures) string {
+	var devices []string
+
+	for deviceId, lectures := range *devicesLectures {
+		lectureIds := make([]string, 0, len(lectures))
+
+		for _, lecture := range lectures {
+			lectureIds = append(lectureIds, lecture.LectureId)
+		}
+
+		sort.Strings(lectureIds)
+		devices = append(devices, deviceId+":"+strings.Join(lectureIds, ","))
+	}
+
+	sort.Strings(devices)
+
+	return strings.Join(devices, " ")
+}
+
+func TestReadyDevicesLecturesInChunks(t *testing.T) {
+	repo := newTestRepository(t)
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deviceLectures, err := repo.GetReadyDeviceLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := describe(solver.DevicesLecturesToMap(deviceLectures, solver.LectureToOverlappedLectureMap(lectures)))
+
+	// 6 enrollments of ready devices, a chunk size of 3 ends with an empty
+	// chunk.
+	for _, chunkSize := range []int{0, 1, 2, 3, 4, 6, 100} {
+		config := DefaultConfig()
+		config.ChunkSize = chunkSize
+		l := NewLoader(config, repo)
+
+		var reports []Progress
+		l.Report = func(progress Progress) {
+			reports = append(reports, progress)
+		}
+
+		lecturesMap := solver.LectureToOverlappedLectureMap(lectures)
+		devicesLectures, err := l.ReadyDevicesLectures(lecturesMap)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := describe(devicesLectures); got != want {
+			t.Errorf("chunk size %d: expected %s, got %s", chunkSize, want, got)
+		}
+
+		if l.Last.Rows != 6 || !l.Last.Done {
+			t.Errorf("chunk size %d: last progress is %+v", chunkSize, l.Last)
+		}
+
+		if len(reports) == 0 || reports[len(reports)-1] != l.Last {
+			t.Errorf("chunk size %d: the last progress was not reported: %+v", chunkSize, reports)
+		}
+
+		// Devices share the lectures of the map, so that the solver marks
+		// them covered for every device.
+		for _, lecture := range (*devicesLectures)["device-a"] {
+			if (*lecturesMap)[lecture.LectureId] != lecture {
+				t.Errorf("chunk size %d: %s is not shared with the lectures", chunkSize, lecture.LectureId)
+			}
+		}
+	}
+}
@@ -1,216 +1,889 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"test-student-lecture-selection-algorithm/activity"
+	"test-student-lecture-selection-algorithm/api"
+	"test-student-lecture-selection-algorithm/benchmark"
+	"test-student-lecture-selection-algorithm/callback"
 	"test-student-lecture-selection-algorithm/db"
-	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/dispatch"
+	"test-student-lecture-selection-algorithm/generator"
+	"test-student-lecture-selection-algorithm/grades"
+	"test-student-lecture-selection-algorithm/lifecycle"
+	"test-student-lecture-selection-algorithm/loader"
+	"test-student-lecture-selection-algorithm/push"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/repository/repotest"
+	"test-student-lecture-selection-algorithm/result"
+	"test-student-lecture-selection-algorithm/scenario"
+	"test-student-lecture-selection-algorithm/scheduler"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"test-student-lecture-selection-algorithm/selection"
+	"test-student-lecture-selection-algorithm/simulation"
+	"test-student-lecture-selection-algorithm/solver"
+	"test-student-lecture-selection-algorithm/sweeper"
 	"time"
 )
 
+// Exit codes of the command. Failures of the database are told apart, so
+// that a supervisor can decide whether a restart may help.
+const (
+	exitFailure             = 1
+	exitDatabaseUnavailable = 2
+	exitMigrationFailed     = 3
+	exitQueryFailed         = 4
+)
+
+// memoryScheme selects the in-memory repository instead of a database. It
+// starts empty, "memory:?generate=1" fills it with the default synthetic
+// data.
+const memoryScheme = "memory"
+
+var errPriorityIssues = errors.New("scheduling priorities have issues")
+
+var errMigrateUsage = errors.New("usage: migrate status | up [steps] | down [steps]")
+
+var errSelectionInvalid = errors.New("the selection doesn't cover all lectures")
+
 func main() {
-	db.Init()
+	if err := runCommand(os.Args[1:]); err != nil {
+		log.WithError(err).Error("Aborted")
+		os.Exit(exitCode(err))
+	}
+}
 
-	FindPerfectMatch()
+// isMemory tells whether dsn selects the in-memory repository.
+func isMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, memoryScheme+":")
 }
 
-type LectureOverlapped struct {
-	LectureId  string
-	Overlapped bool
+// parseMemoryDSN returns whether the in-memory repository of dsn is filled
+// with synthetic data. The only option is generate, e.g.
+// "memory:?generate=1".
+func parseMemoryDSN(dsn string) (bool, error) {
+	parsed, err := url.Parse(dsn)
+
+	if err != nil {
+		return false, fmt.Errorf("invalid dsn %q: %w", dsn, err)
+	}
+
+	if parsed.Opaque != "" || parsed.Host != "" || parsed.Path != "" {
+		return false, fmt.Errorf("invalid dsn %q: the in-memory repository has no name", dsn)
+	}
+
+	query := parsed.Query()
+	generate := false
+
+	for option := range query {
+		if option != "generate" {
+			return false, fmt.Errorf("invalid dsn %q: unknown option %s", dsn, option)
+		}
+
+		generate, err = strconv.ParseBool(query.Get(option))
+
+		if err != nil {
+			return false, fmt.Errorf("invalid dsn %q: %w", dsn, err)
+		}
+	}
+
+	return generate, nil
 }
 
-type LectureToOverlapped map[string]*LectureOverlapped
-type DeviceToOverlappedLectures map[string][]*LectureOverlapped
-type CompareFunc[T comparable] func(T) bool
+// openRepository opens the database of dsn. The scheme of dsn selects MySQL,
+// PostgreSQL or SQLite (see db.ParseDSN), the dsn "memory:" keeps all data in
+// memory, which allows running without a database. The in-memory repository
+// starts empty unless the dsn asks for synthetic data with generate. The
+// requests created by the repository expire after the requestExpiry of the
+// flags.
+func openRepository(dsn string) (repository.Repository, error) {
+	if !isMemory(dsn) {
+		repo, err := db.Open(dsn)
+
+		if err != nil {
+			return nil, err
+		}
 
-func FindPerfectMatch() {
-	totalStartTime := time.Now()
+		repo.RequestExpiry = requestExpiry()
 
-	lectures := db.GetLectures()
-	devices := db.GetReadyDevices()
-	devicesLectures := db.GetDeviceLectures()
-	maxAttendedLecturesCount := db.GetMaxAttendedLecturesCount()
+		return repo, nil
+	}
 
-	log.Infof("Time to execute SQL queries: %s", time.Now().Sub(totalStartTime))
+	generate, err := parseMemoryDSN(dsn)
 
-	startTimeToCreateMaps := time.Now()
+	if err != nil {
+		return nil, err
+	}
 
-	lecturesToOverlappedMap := lectureToOverlappedLectureMap(lectures)
-	devicesLecturesMap := devicesLecturesToMap(devicesLectures, lecturesToOverlappedMap)
-	devicesDetailsMap := devicesToMap(devices)
+	repo := memory.New()
+	repo.RequestExpiry = requestExpiry()
 
-	log.Infof("Time to create maps: %s", time.Now().Sub(startTimeToCreateMaps))
+	if !generate {
+		log.Info("Using the empty in-memory repository")
 
-	log.Infof("Lecture count: %d", len(*lectures))
-	log.Infof("Device count: %d", len(*devicesDetailsMap))
-	log.Infof("Device x lecture count: %d", len(*devicesLectures))
+		return repo, nil
+	}
 
-	log.Infof("------------------")
+	log.Info("Using the in-memory repository with synthetic data")
 
-	log.Infof("Searching for perfect student set...")
+	g, err := generator.NewGenerator(generator.DefaultConfig())
 
-	startTime := time.Now()
+	if err != nil {
+		return nil, err
+	}
 
-	overlappingStudents := getOverlapping(
-		devicesLecturesMap,
-		lectures,
-		maxAttendedLecturesCount,
-	)
+	if _, err := g.Generate(repo); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// GenerateData fills the empty database of dsn with synthetic data.
+func GenerateData(dsn string, config generator.Config) error {
+	if isMemory(dsn) {
+		return errors.New("data generated into the in-memory repository would be discarded")
+	}
+
+	g, err := generator.NewGenerator(config)
+
+	if err != nil {
+		return err
+	}
 
-	log.Infof("Found perfect set: %d (students) in %s", len(*overlappingStudents), time.Now().Sub(startTime))
+	repo, err := db.Open(dsn)
 
-	log.Infof("------------------")
+	if err != nil {
+		return err
+	}
 
-	log.Infof("Total execution time: %s", time.Now().Sub(totalStartTime))
+	stats, err := g.Generate(repo)
 
-	log.Infof("------------------")
+	if err != nil {
+		return err
+	}
 
-	log.Infof("Checking if all lectures are covered...")
+	for _, profile := range config.Profiles {
+		log.Infof("Response profile %s: %d devices", profile.Name, stats.Profiles[profile.Name])
+	}
 
-	log.Infof("All lectures are covered: %t", checkIfAggregatedStudentsHaveAllLectures(overlappingStudents))
+	logBulkStats(stats.Bulk)
+
+	return nil
 }
 
-func getOverlapping(
-	devicesLectures *DeviceToOverlappedLectures,
-	lectures *[]model.IOSLecture,
-	maxAttendedLecturesCount int,
-) *[]string {
-	var overlapped []*LectureOverlapped
-	var overlappingStudents []string
-	currentMaxAttended := maxAttendedLecturesCount
+// logBulkStats logs the throughput of a bulk load per table.
+func logBulkStats(stats *repository.BulkStats) {
+	for _, table := range stats.Tables {
+		log.Infof("Loaded %d rows into %s in %d batches in %s (%.0f rows/s)", table.Rows, table.Table, table.Batches, table.Elapsed, table.RowsPerSecond())
+	}
 
-	for len(overlapped) < len(*lectures) {
-		var newMax string
-		var overlappingLectures *[]*LectureOverlapped
+	log.Infof("Loaded %d rows in %s", stats.Rows(), stats.Elapsed)
+}
 
-		newMax, overlappingLectures = findBestNextMatch(
-			devicesLectures,
-			currentMaxAttended,
-		)
+// ExportScenario writes the data of the database of dsn to the scenario file
+// of path, "-" is the standard output. With anonymize the scenario can be
+// shared, see scenario.Scenario.Anonymize.
+func ExportScenario(dsn string, path string, anonymize bool) error {
+	repo, err := openRepository(dsn)
 
-		if newMax == "" {
-			break
-		}
+	if err != nil {
+		return err
+	}
 
-		delete(*devicesLectures, newMax)
+	s, err := scenario.Export(repo, time.Now())
 
-		overlappingStudents = append(overlappingStudents, newMax)
+	if err != nil {
+		return err
+	}
 
-		overlapped = append(overlapped, *overlappingLectures...)
+	if anonymize {
+		s, err = anonymizeScenario(s)
 
-		for _, lecture := range *overlappingLectures {
-			lecture.Overlapped = true
+		if err != nil {
+			return err
 		}
+	}
+
+	return saveScenario(s, path)
+}
+
+// ImportScenario fills the empty database of dsn with the scenario file of
+// path. With bulk.Upsert the database doesn't have to be empty.
+func ImportScenario(dsn string, path string, bulk repository.BulkConfig) error {
+	s, err := scenario.Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	if isMemory(dsn) {
+		return errors.New("a scenario imported into the in-memory repository would be discarded")
+	}
+
+	repo, err := db.Open(dsn)
+
+	if err != nil {
+		return err
+	}
+
+	stats, err := s.Import(repo, time.Now(), bulk)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Imported %d devices, %d lectures and %d requests from %s", len(s.Devices), len(s.Lectures), len(s.Requests), path)
+	logBulkStats(stats)
+
+	return nil
+}
+
+// AnonymizeScenario writes the scenario file of path anonymized to the file
+// of anonymizedPath.
+func AnonymizeScenario(path string, anonymizedPath string) error {
+	s, err := scenario.Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	s, err = anonymizeScenario(s)
+
+	if err != nil {
+		return err
+	}
+
+	return saveScenario(s, anonymizedPath)
+}
+
+// anonymizeScenario anonymizes s with the key of $ANONYMIZATION_KEY. Without
+// a key a random one is used, the pseudonyms of two such exports can't be
+// compared.
+func anonymizeScenario(s *scenario.Scenario) (*scenario.Scenario, error) {
+	key := []byte(os.Getenv("ANONYMIZATION_KEY"))
 
-		overlappingLecturesCount := len(*overlappingLectures)
+	if len(key) == 0 {
+		log.Warn("ANONYMIZATION_KEY is not set, using a random key")
 
-		if currentMaxAttended > overlappingLecturesCount {
-			currentMaxAttended = overlappingLecturesCount
+		key = make([]byte, 32)
+
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
 		}
 	}
 
-	return &overlappingStudents
+	return s.Anonymize(key)
+}
+
+func saveScenario(s *scenario.Scenario, path string) error {
+	if path == "-" {
+		return s.Write(os.Stdout)
+	}
+
+	if err := s.Save(path); err != nil {
+		return err
+	}
+
+	log.Infof("Wrote %d devices, %d lectures and %d requests to %s", len(s.Devices), len(s.Lectures), len(s.Requests), path)
+
+	return nil
+}
+
+// SolveScenarioFile runs the solver registered as solverName on the active
+// devices of the scenario file of path.
+func SolveScenarioFile(path string, solverName string) error {
+	s, err := scenario.Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	return SolveScenario(s, solverName)
 }
 
-func findBestNextMatch(
-	devicesLectures *DeviceToOverlappedLectures,
-	currentMaxAttended int,
-) (string, *[]*LectureOverlapped) {
-	maxAttends := 0
-	studentWithMaxAttends := ""
-	var overlappingLectures []*LectureOverlapped
+// SolveScenario runs the solver registered as solverName on the active
+// devices of s.
+func SolveScenario(s *scenario.Scenario, solverName string) error {
+	solve, err := solver.Get(solverName)
+
+	if err != nil {
+		return err
+	}
+
+	lectures, devicesLectures := s.SolverInput()
+	lowerBound := solver.LowerBound(devicesLectures)
+
+	log.Infof("Scenario %s: %d lectures, %d enrolled active devices", s.Name, len(*lectures), len(*devicesLectures))
+
+	startTime := time.Now()
+
+	assignments := solve(devicesLectures, lectures)
+
+	covered := 0
+
+	for _, assignment := range *assignments {
+		covered += len(assignment.LectureIds)
+	}
+
+	log.Infof("Found perfect set: %d (students) in %s with %s", len(*assignments), time.Now().Sub(startTime), solverName)
+	log.Infof("Covered %d of %d lectures, at least %d devices are needed", covered, len(*lectures), lowerBound)
+
+	return nil
+}
+
+// BenchmarkSolvers runs the solvers over the generated instance families
+// and the scenario files of paths and writes a comparison table in format to
+// the file of output, "-" is the standard output.
+func BenchmarkSolvers(solvers []string, families []benchmark.Family, paths []string, format string, output string) error {
+	var instances []*benchmark.Instance
+
+	for _, family := range families {
+		instance, err := family.Generate()
+
+		if err != nil {
+			return fmt.Errorf("instance family %s: %w", family.Name, err)
+		}
+
+		instances = append(instances, instance)
+	}
+
+	for _, path := range paths {
+		s, err := scenario.Load(path)
+
+		if err != nil {
+			return fmt.Errorf("scenario %s: %w", path, err)
+		}
+
+		instances = append(instances, benchmark.FromScenario(path, s))
+	}
 
-	for device, lectures := range *devicesLectures {
-		newAttends := filter(&lectures, func(lecture *LectureOverlapped) bool {
-			return !lecture.Overlapped
-		})
+	if len(instances) == 0 {
+		return errors.New("no instances to benchmark, select families or pass scenario files")
+	}
 
-		newAttendsCount := len(*newAttends)
+	var results []benchmark.Result
 
-		if newAttendsCount > maxAttends {
-			maxAttends = newAttendsCount
-			studentWithMaxAttends = device
-			overlappingLectures = *newAttends
+	for _, instance := range instances {
+		for _, name := range solvers {
+			result, err := benchmark.Run(instance, name)
 
-			if maxAttends == currentMaxAttended {
-				break
+			if err != nil {
+				return err
 			}
+
+			log.Infof("%s on %s: %d devices (lower bound %d)", name, instance.Name, result.Size, result.LowerBound)
+
+			results = append(results, *result)
 		}
 	}
 
-	return studentWithMaxAttends, &overlappingLectures
+	if output == "-" {
+		return benchmark.Write(os.Stdout, results, format)
+	}
+
+	file, err := os.Create(output)
+
+	if err != nil {
+		return err
+	}
+
+	if err := benchmark.Write(file, results, format); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
 }
 
-func filter[T comparable](s *[]T, fn CompareFunc[T]) *[]T {
-	var p []T
-	for _, v := range *s {
-		if fn(v) {
-			p = append(p, v)
+// Simulate replays a dispatch policy over a simulated period, starting from
+// the state of the repository of dsn or from the scenario file of path, and
+// logs the staleness and push distributions over all trials.
+func Simulate(dsn string, path string, config simulation.Config) error {
+	var s *scenario.Scenario
+
+	if path != "" {
+		var err error
+
+		s, err = scenario.Load(path)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		repo, err := openRepository(dsn)
+
+		if err != nil {
+			return err
 		}
+
+		s, err = scenario.Export(repo, time.Now())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	simulator, err := simulation.NewSimulator(config, s)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Learned a pooled response rate of %.2f from %d devices", simulator.Reliability.Pooled.ResponseRate, len(simulator.Reliability.Devices))
+
+	report, err := simulator.Run()
+
+	if err != nil {
+		return err
 	}
-	return &p
+
+	report.Log()
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
 
-func lectureToOverlappedLectureMap(l *[]model.IOSLecture) *LectureToOverlapped {
-	lectures := make(LectureToOverlapped)
+// MigrateDatabase lists the migrations of the database of dsn with "status",
+// applies the pending ones with "up" and reverts the last one with "down".
+// Both "up" and "down" take the number of migrations as optional argument.
+func MigrateDatabase(dsn string, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errMigrateUsage
+	}
+
+	steps := 0
+
+	if len(args) == 2 {
+		var err error
+
+		steps, err = strconv.Atoi(args[1])
 
-	for _, lecture := range *l {
-		overlapped := LectureOverlapped{
-			LectureId:  lecture.Id,
-			Overlapped: false,
+		if err != nil || steps < 1 {
+			return errMigrateUsage
 		}
+	}
 
-		lectures[lecture.Id] = &overlapped
+	if isMemory(dsn) {
+		return errors.New("the in-memory repository has no schema to migrate")
 	}
 
-	return &lectures
+	repo, err := db.Connect(dsn)
+
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := repo.MigrationStatus()
+
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			switch {
+			case status.Unknown:
+				log.Warnf("%04d_%s: applied at %s by a newer version", status.Version, status.Name, status.AppliedAt.Format(time.RFC3339))
+			case status.AppliedAt != nil:
+				log.Infof("%04d_%s: applied at %s", status.Version, status.Name, status.AppliedAt.Format(time.RFC3339))
+			default:
+				log.Infof("%04d_%s: pending", status.Version, status.Name)
+			}
+		}
+
+		return nil
+	case "up":
+		applied, err := repo.MigrateUp(steps)
+
+		log.Infof("Applied %d migrations", len(applied))
+
+		return err
+	case "down":
+		reverted, err := repo.MigrateDown(steps)
+
+		log.Infof("Reverted %d migrations", len(reverted))
+
+		return err
+	}
+
+	return errMigrateUsage
 }
 
-func devicesLecturesToMap(dl *[]model.IOSDeviceLecture, lectures *LectureToOverlapped) *DeviceToOverlappedLectures {
-	devicesLectures := make(DeviceToOverlappedLectures)
+// CheckRepository runs the conformance checks against a new in-memory
+// repository and, unless dsn is the in-memory one, against the database of
+// dsn, which has to be empty.
+func CheckRepository(dsn string) error {
+	if err := repotest.Check(memory.New()); err != nil {
+		return fmt.Errorf("in-memory repository: %w", err)
+	}
+
+	log.Info("In-memory repository passed the conformance checks")
+
+	if isMemory(dsn) {
+		return nil
+	}
 
-	for _, d := range *dl {
-		overlappedLecture := (*lectures)[d.LectureId]
+	repo, err := db.Open(dsn)
 
-		devicesLectures[d.DeviceId] = append(devicesLectures[d.DeviceId], overlappedLecture)
+	if err != nil {
+		return err
 	}
 
-	return &devicesLectures
+	if err := repotest.Check(repo); err != nil {
+		return fmt.Errorf("database repository: %w", err)
+	}
+
+	log.Info("Database repository passed the conformance checks")
+
+	return nil
 }
 
-func devicesToMap(d *[]model.IOSDevice) *map[string]model.IOSDevice {
-	devices := make(map[string]model.IOSDevice)
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrConnect):
+		return exitDatabaseUnavailable
+	case errors.Is(err, repository.ErrMigrate):
+		return exitMigrationFailed
+	case errors.Is(err, repository.ErrQuery):
+		return exitQueryFailed
+	}
 
-	for _, device := range *d {
-		devices[device.DeviceID] = device
+	return exitFailure
+}
+
+// FindPerfectMatch selects the devices covering the lectures, see
+// selection.Run. With a resultPath, the result of the run is written to it as
+// JSON, "-" is the standard output.
+func FindPerfectMatch(repo repository.Repository, options selection.Options, resultPath string) error {
+	outcome, err := selection.Run(repo, options)
+
+	if err != nil {
+		return err
+	}
+
+	if resultPath != "" {
+		return outcome.Run.Save(resultPath)
 	}
 
-	return &devices
+	return nil
 }
 
-func checkIfAggregatedStudentsHaveAllLectures(aggregatedStudents *[]string) bool {
-	lectures := db.GetLectures()
-	lecturesCount := len(*lectures)
-	deviceLectures := db.GetDeviceLectures()
+// DispatchPerfectMatch pushes to the perfect set in rounds and falls back to
+// other devices for the lectures whose device did not answer.
+func DispatchPerfectMatch(repo repository.Repository) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := dispatchConfig
 
-	lectureToOverlappedLectures := lectureToOverlappedLectureMap(lectures)
-	deviceLecturesMap := devicesLecturesToMap(deviceLectures, lectureToOverlappedLectures)
+	sender, closeSender, err := newSender(config, repo)
 
-	log.Infof("Lecture count: %d", lecturesCount)
-	log.Infof("Aggregated students count: %d", len(*aggregatedStudents))
+	if err != nil {
+		return fmt.Errorf("failed to set up push delivery: %w", err)
+	}
 
-	lectureDevicesMap := make(map[string][]string)
+	defer closeSender()
 
-	for student, lectures := range *deviceLecturesMap {
-		for _, l := range lectures {
-			lectureDevicesMap[l.LectureId] = append(lectureDevicesMap[l.LectureId], student)
-		}
+	orchestrator := dispatch.NewOrchestrator(
+		config,
+		dispatch.RepositoryCommitter{Repository: repo},
+		sender,
+		dispatch.RepositoryResponses{Repository: repo},
+	)
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		return err
+	}
+
+	deviceLectures, err := repo.GetReadyDeviceLectures()
+
+	if err != nil {
+		return err
+	}
+
+	result, err := orchestrator.Run(ctx, lectures, deviceLectures)
+
+	if result != nil {
+		log.Infof("Refreshed %d/%d lectures in %d rounds", result.Refreshed, result.Lectures, len(result.Rounds))
+	}
+
+	return err
+}
+
+// MaintainDevices suspects devices that stopped answering and purges the
+// devices that are unregistered for longer than the retention period.
+func MaintainDevices(repo repository.Repository, retention time.Duration) error {
+	config := lifecycle.DefaultConfig()
+	config.Retention = retention
+
+	tracker := lifecycle.NewTracker(config, repo)
+
+	if err := tracker.EvaluateResponses(); err != nil {
+		return err
+	}
+
+	return tracker.Purge()
+}
+
+// Schedule runs the scheduler until SIGTERM or an interrupt is received.
+func Schedule(repo repository.Repository) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := scheduler.DefaultConfig()
+	config.Dispatch = dispatchConfig
+
+	sender, closeSender, err := newSender(config.Dispatch, repo)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up push delivery: %w", err)
+	}
+
+	defer closeSender()
+
+	sweeperConfig := sweeper.DefaultConfig()
+	sweeperConfig.MaxAttempts = maxAttempts
+
+	s := scheduler.NewScheduler(config, repo, dispatch.RepositoryCommitter{Repository: repo}, sender, dispatch.RepositoryResponses{Repository: repo})
+	s.BeforeRun = sweeper.NewSweeper(sweeperConfig, repo, sender).Sweep
+	s.GradePlanner = grades.NewPlanner(grades.DefaultConfig(), repo)
+
+	activityJob, err := newActivityJob(repo)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up activity counters: %w", err)
+	}
+
+	go activityJob.Run(ctx)
+
+	s.Run(ctx)
+
+	return nil
+}
+
+// UpdateActivity recomputes the activity counters once.
+func UpdateActivity(repo repository.Repository) error {
+	job, err := newActivityJob(repo)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up activity counters: %w", err)
 	}
 
-	log.Infof("Lecture devices map count: %d", len(lectureDevicesMap))
+	return job.Update()
+}
+
+func newActivityJob(repo repository.Repository) (*activity.Job, error) {
+	config := activity.DefaultConfig()
+	config.TimeZone = activityZone
+
+	return activity.NewJob(config, repo)
+}
+
+// ValidatePriorities logs the issues of the scheduling priorities and
+// returns errPriorityIssues if there are any.
+func ValidatePriorities(repo repository.Repository) error {
+	priorities, err := repo.GetSchedulingPriorities()
+
+	if err != nil {
+		return err
+	}
+
+	issues := scheduling.Validate(priorities)
+
+	for _, issue := range issues {
+		log.Warn(issue.String())
+	}
+
+	log.Infof("Validated %d scheduling priorities: %d issues", len(*priorities), len(issues))
+
+	if len(issues) > 0 {
+		return errPriorityIssues
+	}
+
+	return nil
+}
+
+// VerifyResult checks the selection of the result file of path against the
+// current lectures and enrollments of the ready devices and returns
+// errSelectionInvalid if it no longer covers all lectures that can be
+// covered.
+func VerifyResult(repo repository.Repository, path string) error {
+	run, err := result.Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		return err
+	}
+
+	devicesLecturesMap, err := newLoader(repo).ReadyDevicesLectures(solver.LectureToOverlappedLectureMap(lectures))
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Verifying run %s of %s: %d devices", run.ID, run.StartedAt.Format(time.RFC3339), len(run.SelectedDevices))
+
+	if err := solver.Verify(run.Assignments(), solver.Enrolled(devicesLecturesMap)); err != nil {
+		log.WithError(err).Warn("The selection is not valid anymore")
+		return errSelectionInvalid
+	}
+
+	log.Infof("The selection covers all %d coverable lectures", len(run.Coverers))
+
+	return nil
+}
+
+// Serve accepts the answers of the devices at /callback and the requests of
+// the selection API below /api/ on address until SIGTERM or an interrupt is
+// received.
+func Serve(repo repository.Repository, address string, apiConfig api.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/callback", callback.NewHandler(repo))
+	mux.Handle("/api/", http.StripPrefix("/api", api.NewServer(apiConfig, repo)))
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	log.Infof("Listening on %s", address)
 
-	if len(lectureDevicesMap) != lecturesCount {
-		return false
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
 	}
 
-	return true
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
+
+// SweepRequests expires the stale requests and retries the failed
+// deliveries with the push delivery selected by the -push flag.
+func SweepRequests(repo repository.Repository) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sender, closeSender, err := newSender(dispatch.DefaultConfig(), repo)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up push delivery: %w", err)
+	}
+
+	defer closeSender()
+
+	config := sweeper.DefaultConfig()
+	config.MaxAttempts = maxAttempts
+
+	return sweeper.NewSweeper(config, repo, sender).Sweep(ctx)
+}
+
+// newSender creates the push delivery selected by the -push flag. APNs is
+// configured by the environment variables APNS_ENDPOINT, APNS_KEY_ID,
+// APNS_TEAM_ID, APNS_TOPIC and APNS_KEY_FILE. The returned function releases
+// the push delivery, e.g. stops the mock APNs server, and has to be called
+// once the sender is no longer used.
+func newSender(config dispatch.Config, repo repository.Repository) (dispatch.Sender, func(), error) {
+	noop := func() {}
+
+	if simulatedResponseRate > 0 {
+		return callback.NewSimulatedDevices(callback.NewHandler(repo), simulatedResponseRate, config.Timeout/2), noop, nil
+	}
+
+	apnsConfig := push.Config{
+		Endpoint: os.Getenv("APNS_ENDPOINT"),
+		KeyID:    os.Getenv("APNS_KEY_ID"),
+		TeamID:   os.Getenv("APNS_TEAM_ID"),
+		Topic:    os.Getenv("APNS_TOPIC"),
+	}
+
+	tracker := lifecycle.NewTracker(lifecycle.DefaultConfig(), repo)
+
+	switch pushDelivery {
+	case "log":
+		return dispatch.LogSender{}, noop, nil
+	case "apns":
+		p8, err := os.ReadFile(os.Getenv("APNS_KEY_FILE"))
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		apnsConfig.PrivateKey, err = push.LoadPrivateKey(p8)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if apnsConfig.Endpoint == "" {
+			apnsConfig.Endpoint = push.ProductionEndpoint
+		}
+
+		return push.NewSender(repo, push.NewClient(apnsConfig, nil), tracker, encrypt), noop, nil
+	case "mock-apns":
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		apnsConfig.PrivateKey = privateKey
+
+		mock := push.NewMockServer(privateKey)
+
+		return push.NewSender(repo, mock.Client(apnsConfig), tracker, encrypt), mock.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown push delivery %q", pushDelivery)
+}
+
+func newLoader(repo repository.Repository) *loader.Loader {
+	config := loader.DefaultConfig()
+	config.ChunkSize = chunkSize
+
+	return loader.NewLoader(config, repo)
 }
@@ -1,21 +1,36 @@
 package model
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 )
 
+const (
+	// IOSDeviceStateActive devices are considered by the selection.
+	IOSDeviceStateActive = "active"
+	// IOSDeviceStateSuspect devices did not answer their recent requests
+	// and are not selected until they answer again or their probation ends.
+	IOSDeviceStateSuspect = "suspect"
+	// IOSDeviceStateUnregistered devices were reported as invalid by the
+	// push delivery or stayed silent during their probation and are purged
+	// after the retention period.
+	IOSDeviceStateUnregistered = "unregistered"
+)
+
 // IOSDevice stores relevant device information.
 // E.g. the PublicKey which is used to encrypt push notifications
 // The DeviceID can be used to send push notifications via APNs
 type IOSDevice struct {
-	DeviceID          string    `gorm:"primary_key" json:"deviceId" faker:"uuid_hyphenated"`
-	CreatedAt         time.Time `gorm:"default:now()" json:"createdAt"`
-	PublicKey         string    `gorm:"not null" json:"publicKey" faker:"jwt"`
-	ActivityToday     int32     `gorm:"default:0" json:"activityToday" faker:"boundary_start=0, boundary_end=10"`
-	ActivityThisWeek  int32     `gorm:"default:0" json:"activityThisWeek" faker:"boundary_start=10, boundary_end=50"`
-	ActivityThisMonth int32     `gorm:"default:0" json:"activityThisMonth" faker:"boundary_start=50, boundary_end=100"`
-	ActivityThisYear  int32     `gorm:"default:0" json:"activityThisYear" faker:"boundary_start=100, boundary_end=1000"`
+	DeviceID          string       `gorm:"primary_key" json:"deviceId"`
+	CreatedAt         time.Time    `json:"createdAt"`
+	PublicKey         string       `gorm:"not null" json:"publicKey"`
+	ActivityToday     int32        `gorm:"default:0" json:"activityToday"`
+	ActivityThisWeek  int32        `gorm:"default:0" json:"activityThisWeek"`
+	ActivityThisMonth int32        `gorm:"default:0" json:"activityThisMonth"`
+	ActivityThisYear  int32        `gorm:"default:0" json:"activityThisYear"`
+	State             string       `gorm:"size:16;not null;default:'active';index;check:chk_ios_devices_state,state IN ('active', 'suspect', 'unregistered')" json:"state"`
+	StateChangedAt    sql.NullTime `json:"stateChangedAt"`
 }
 
 type IOSDeviceWithAvgResponseTime struct {
@@ -23,6 +38,11 @@ type IOSDeviceWithAvgResponseTime struct {
 	AvgResponseTime float64 `json:"avgResponseTime"`
 }
 
+// IsActive returns true if the device can be selected.
+func (device *IOSDevice) IsActive() bool {
+	return device.State == "" || device.State == IOSDeviceStateActive
+}
+
 func (device *IOSDevice) String() string {
 	return fmt.Sprintf("IOSDevice{DeviceID: %s}", device.DeviceID)
 }
@@ -1,7 +1,10 @@
 package model
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -10,6 +13,104 @@ const (
 	IOSLectureUpdateRequestType = "LECTURE_UPDATE_REQUEST"
 )
 
+const (
+	// IOSRequestStatusCreated requests were not pushed yet.
+	IOSRequestStatusCreated = "created"
+	// IOSRequestStatusSent requests were accepted by the push delivery.
+	IOSRequestStatusSent = "sent"
+	// IOSRequestStatusDeliveryFailed requests were rejected by the push
+	// delivery and can be retried.
+	IOSRequestStatusDeliveryFailed = "delivery_failed"
+	// IOSRequestStatusHandled requests were answered by the device.
+	IOSRequestStatusHandled = "handled"
+	// IOSRequestStatusExpired requests were not answered in time.
+	IOSRequestStatusExpired = "expired"
+	// IOSRequestStatusSuperseded requests were replaced by a newer request of
+	// the same type to the same device.
+	IOSRequestStatusSuperseded = "superseded"
+)
+
+// IOSRequestStatusTransitions lists the statuses a request can move to from
+// each status. Handled, expired and superseded requests are final.
+var IOSRequestStatusTransitions = map[string][]string{
+	IOSRequestStatusCreated: {
+		IOSRequestStatusSent,
+		IOSRequestStatusDeliveryFailed,
+		IOSRequestStatusHandled,
+		IOSRequestStatusExpired,
+		IOSRequestStatusSuperseded,
+	},
+	IOSRequestStatusSent: {
+		IOSRequestStatusSent,
+		IOSRequestStatusHandled,
+		IOSRequestStatusExpired,
+		IOSRequestStatusSuperseded,
+	},
+	// A device can answer a request whose push was reported as failed,
+	// e.g. because it was delivered by a retry in the meantime.
+	IOSRequestStatusDeliveryFailed: {
+		IOSRequestStatusSent,
+		IOSRequestStatusDeliveryFailed,
+		IOSRequestStatusHandled,
+		IOSRequestStatusExpired,
+		IOSRequestStatusSuperseded,
+	},
+}
+
+// IOSOpenRequestStatuses are the statuses of requests that still can be
+// answered.
+var IOSOpenRequestStatuses = []string{
+	IOSRequestStatusCreated,
+	IOSRequestStatusSent,
+	IOSRequestStatusDeliveryFailed,
+}
+
+// IOSRequestExpiry is how long a request of each RequestType can be answered
+// after it was created.
+type IOSRequestExpiry map[string]time.Duration
+
+// defaultIOSRequestExpiry is never modified, DefaultIOSRequestExpiry returns
+// copies of it.
+var defaultIOSRequestExpiry = IOSRequestExpiry{
+	IOSTokenRequestType:         time.Hour,
+	IOSLectureUpdateRequestType: 30 * time.Minute,
+}
+
+// DefaultIOSRequestExpiry returns the expiry of the requests unless it is
+// configured otherwise.
+func DefaultIOSRequestExpiry() IOSRequestExpiry {
+	expiry := make(IOSRequestExpiry, len(defaultIOSRequestExpiry))
+
+	for requestType, duration := range defaultIOSRequestExpiry {
+		expiry[requestType] = duration
+	}
+
+	return expiry
+}
+
+// Of returns the expiry of requestType. Types missing in e expire after the
+// default.
+func (e IOSRequestExpiry) Of(requestType string) time.Duration {
+	if expiry, ok := e[requestType]; ok {
+		return expiry
+	}
+
+	return defaultIOSRequestExpiry[requestType]
+}
+
+// NewRequestLog creates a request of requestType to the device that expires
+// after the expiry of requestType.
+func (e IOSRequestExpiry) NewRequestLog(deviceId string, requestType string, createdAt time.Time) IOSDeviceRequestLog {
+	return IOSDeviceRequestLog{
+		RequestID:   NewRequestID(),
+		DeviceID:    deviceId,
+		RequestType: requestType,
+		CreatedAt:   createdAt,
+		Status:      IOSRequestStatusCreated,
+		ExpiresAt:   sql.NullTime{Time: createdAt.Add(e.Of(requestType)), Valid: true},
+	}
+}
+
 // An IOSDeviceRequestLog is created when the backend wants to request data from the device.
 //
 // 1. The backend creates a new IOSDeviceRequestLog
@@ -20,10 +121,107 @@ const (
 // 3. The device receives the push notification and sends a request to the backend
 // containing the RequestID and the data.
 type IOSDeviceRequestLog struct {
-	RequestID   string       `gorm:"primary_key;default:UUID()" json:"requestId" faker:"-"`
-	DeviceID    string       `json:"deviceId" gorm:"size:200;not null" faker:"-"`
+	RequestID   string       `gorm:"primary_key" json:"requestId"`
+	DeviceID    string       `json:"deviceId" gorm:"size:200;not null;index"`
 	Device      IOSDevice    `json:"device" gorm:"constraint:OnDelete:CASCADE;" `
-	RequestType string       `json:"requestType" gorm:"not null;type:enum ('CAMPUS_TOKEN_REQUEST', 'LECTURE_UPDATE_REQUEST');"`
-	CreatedAt   time.Time    `gorm:"default:now()" json:"createdAt"`
-	HandledAt   sql.NullTime `json:"handledAt" gorm:"default:null"`
+	RequestType string       `json:"requestType" gorm:"size:32;not null;check:chk_ios_device_request_logs_request_type,request_type IN ('CAMPUS_TOKEN_REQUEST', 'LECTURE_UPDATE_REQUEST')"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	HandledAt   sql.NullTime `json:"handledAt"`
+	Status      string       `json:"status" gorm:"size:16;not null;default:'created';index;check:chk_ios_device_request_logs_status,status IN ('created', 'sent', 'delivery_failed', 'handled', 'expired', 'superseded')"`
+	Attempts    int          `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt   sql.NullTime `json:"expiresAt" gorm:"index"`
+}
+
+// CanTransitionTo returns true if the request can move to status according
+// to IOSRequestStatusTransitions.
+func (r *IOSDeviceRequestLog) CanTransitionTo(status string) bool {
+	from := r.Status
+
+	if from == "" {
+		from = IOSRequestStatusCreated
+	}
+
+	for _, to := range IOSRequestStatusTransitions[from] {
+		if to == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IOSRequestStatusesTransitioningTo returns the statuses that can move to
+// status.
+func IOSRequestStatusesTransitioningTo(status string) []string {
+	var statuses []string
+
+	for from, targets := range IOSRequestStatusTransitions {
+		for _, to := range targets {
+			if to == status {
+				statuses = append(statuses, from)
+			}
+		}
+	}
+
+	return statuses
+}
+
+// TransitionTo moves the request to status.
+func (r *IOSDeviceRequestLog) TransitionTo(status string) error {
+	if !r.CanTransitionTo(status) {
+		return fmt.Errorf("invalid request status transition from %s to %s", r.Status, status)
+	}
+
+	r.Status = status
+
+	return nil
+}
+
+// Expiry returns the time after which the request can't be answered
+// anymore. Requests created before ExpiresAt was introduced expire after
+// the default expiry of their type.
+func (r *IOSDeviceRequestLog) Expiry() time.Time {
+	if r.ExpiresAt.Valid {
+		return r.ExpiresAt.Time
+	}
+
+	return r.CreatedAt.Add(defaultIOSRequestExpiry.Of(r.RequestType))
+}
+
+// IsExpiredAt returns true if the request can't be answered at t.
+func (r *IOSDeviceRequestLog) IsExpiredAt(t time.Time) bool {
+	return r.Status == IOSRequestStatusExpired || t.After(r.Expiry())
+}
+
+// NewIOSDeviceRequestLog creates a request of requestType to the device
+// that expires after the default expiry of requestType, see
+// IOSRequestExpiry.NewRequestLog for a configured expiry.
+func NewIOSDeviceRequestLog(deviceId string, requestType string, createdAt time.Time) IOSDeviceRequestLog {
+	return defaultIOSRequestExpiry.NewRequestLog(deviceId, requestType, createdAt)
+}
+
+// BeforeCreate generates the RequestID of requests created without one.
+func (r *IOSDeviceRequestLog) BeforeCreate(*gorm.DB) error {
+	if r.RequestID == "" {
+		r.RequestID = NewRequestID()
+	}
+
+	return nil
+}
+
+// NewRequestID generates a random (version 4) UUID for a new
+// IOSDeviceRequestLog. The ID is generated by the application because the
+// ID of a row created by a database default can't be read back and not
+// every database has a UUID function.
+func NewRequestID() string {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %s", err))
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIOSRequestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		ok   bool
+	}{
+		{"", IOSRequestStatusSent, true},
+		{IOSRequestStatusCreated, IOSRequestStatusHandled, true},
+		{IOSRequestStatusSent, IOSRequestStatusSent, true},
+		{IOSRequestStatusSent, IOSRequestStatusDeliveryFailed, false},
+		{IOSRequestStatusDeliveryFailed, IOSRequestStatusSent, true},
+		{IOSRequestStatusDeliveryFailed, IOSRequestStatusHandled, true},
+		{IOSRequestStatusHandled, IOSRequestStatusExpired, false},
+		{IOSRequestStatusExpired, IOSRequestStatusHandled, false},
+		{IOSRequestStatusSuperseded, IOSRequestStatusSent, false},
+	}
+
+	for _, test := range tests {
+		request := IOSDeviceRequestLog{Status: test.from}
+
+		if ok := request.CanTransitionTo(test.to); ok != test.ok {
+			t.Errorf("transition from %q to %s: expected %t, got %t", test.from, test.to, test.ok, ok)
+		}
+	}
+}
+
+// Every open request has to be answerable, otherwise the callback rejects
+// answers the request log still waits for.
+func TestIOSOpenRequestStatusesCanBeHandled(t *testing.T) {
+	for _, status := range IOSOpenRequestStatuses {
+		request := IOSDeviceRequestLog{Status: status}
+
+		if !request.CanTransitionTo(IOSRequestStatusHandled) {
+			t.Errorf("open status %s can't move to handled", status)
+		}
+	}
+}
+
+func TestIOSDeviceRequestLogExpiry(t *testing.T) {
+	createdAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	request := NewIOSDeviceRequestLog("device", IOSLectureUpdateRequestType, createdAt)
+	expiry := DefaultIOSRequestExpiry()
+
+	if request.IsExpiredAt(createdAt.Add(expiry[IOSLectureUpdateRequestType])) {
+		t.Error("request expired at its expiry")
+	}
+
+	if !request.IsExpiredAt(createdAt.Add(expiry[IOSLectureUpdateRequestType] + time.Second)) {
+		t.Error("request did not expire after its expiry")
+	}
+
+	// Requests created before ExpiresAt was introduced expire after the
+	// expiry of their type.
+	legacy := IOSDeviceRequestLog{RequestType: IOSTokenRequestType, CreatedAt: createdAt}
+
+	if !legacy.Expiry().Equal(createdAt.Add(expiry[IOSTokenRequestType])) {
+		t.Errorf("request without ExpiresAt expires at %s", legacy.Expiry())
+	}
+}
+
+func TestIOSRequestExpiry(t *testing.T) {
+	createdAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiry := IOSRequestExpiry{IOSLectureUpdateRequestType: 5 * time.Minute}
+
+	request := expiry.NewRequestLog("device", IOSLectureUpdateRequestType, createdAt)
+
+	if !request.Expiry().Equal(createdAt.Add(5 * time.Minute)) {
+		t.Errorf("request expires at %s instead of after the configured 5m", request.Expiry())
+	}
+
+	// Types without a configured expiry expire after the default.
+	if got, want := expiry.Of(IOSTokenRequestType), DefaultIOSRequestExpiry()[IOSTokenRequestType]; got != want {
+		t.Errorf("token requests expire after %s instead of the default %s", got, want)
+	}
+
+	// Changing a copy of the defaults leaves the defaults untouched.
+	changed := DefaultIOSRequestExpiry()
+	changed[IOSTokenRequestType] = time.Minute
+
+	if DefaultIOSRequestExpiry()[IOSTokenRequestType] == time.Minute {
+		t.Error("the default expiry was changed through a copy")
+	}
+
+	if request := NewIOSDeviceRequestLog("device", IOSTokenRequestType, createdAt); request.Expiry().Equal(createdAt.Add(time.Minute)) {
+		t.Error("new requests expire after a changed copy of the defaults")
+	}
+}
@@ -10,10 +10,10 @@ const (
 )
 
 type IOSLecture struct {
-	Id            string               `gorm:"primaryKey"`
-	Year          int16                `faker:"boundary_start=2022, boundary_end=2023"`
-	Semester      string               `gorm:"type:enum ('winter', 'summer');" faker:"oneof: winter, summer"`
-	LastUpdate    time.Time            `gorm:"default:now()" faker:"-"`
-	LastRequestId *string              `gorm:"default:NULL;" faker:"-"`
-	LastRequest   *IOSDeviceRequestLog `gorm:"constraint:OnDelete:SET NULL;" faker:"-"`
+	Id            string `gorm:"primaryKey"`
+	Year          int16
+	Semester      string               `gorm:"size:8;check:chk_ios_lectures_semester,semester IN ('winter', 'summer')"`
+	LastUpdate    time.Time            `gorm:"autoCreateTime"`
+	LastRequestId *string              `gorm:"size:191"`
+	LastRequest   *IOSDeviceRequestLog `gorm:"constraint:OnDelete:SET NULL;"`
 }
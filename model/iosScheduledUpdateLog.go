@@ -7,22 +7,39 @@ import (
 
 const (
 	IOSUpdateTypeGrades      = "grades"
+	IOSUpdateTypeLectures    = "lectures"
 	IOSMinimumUpdateInterval = 30
 )
 
-// IOSScheduledUpdateLog logs the last time a device was updated.
+// IOSScheduledUpdateLog logs the last time a device was updated. There is at
+// most one log per device and Type. Logs without a device record the runs of
+// the scheduler.
 type IOSScheduledUpdateLog struct {
 	ID        uint32    `gorm:"primary_key;auto_increment;not_null" json:"id"`
-	DeviceID  string    `gorm:"index:idx_scheduled_update_log_device,unique" json:"deviceId"`
+	DeviceID  string    `gorm:"index:idx_scheduled_update_log_device_type,unique" json:"deviceId"`
 	Device    IOSDevice `gorm:"constraint:OnDelete:CASCADE;" json:"device"`
-	Type      string    `gorm:"type:enum ('grades');" json:"type"`
-	CreatedAt time.Time `gorm:"index:idx_scheduled_update_log_created,unique;default:now()" json:"createdAt"`
+	Type      string    `gorm:"size:16;index:idx_scheduled_update_log_device_type,unique;check:chk_ios_scheduled_update_logs_type,type IN ('grades', 'lectures')" json:"type"`
+	CreatedAt time.Time `gorm:"index:idx_scheduled_update_log_created_at" json:"createdAt"`
+}
+
+// IOSUpdateTypeOf returns the update type a device refreshes by answering a
+// request of requestType, or "" if the answer refreshes none.
+func IOSUpdateTypeOf(requestType string) string {
+	if requestType == IOSTokenRequestType {
+		return IOSUpdateTypeGrades
+	}
+
+	return ""
 }
 
 func (log *IOSScheduledUpdateLog) IsGrades() bool {
 	return log.Type == IOSUpdateTypeGrades
 }
 
+func (log *IOSScheduledUpdateLog) IsLectures() bool {
+	return log.Type == IOSUpdateTypeLectures
+}
+
 func (log *IOSScheduledUpdateLog) String() string {
 	return fmt.Sprintf("IOSScheduledUpdateLog{ID: %d, DeviceID: %s, Type: %s, CreatedAt: %s}", log.ID, log.DeviceID, log.Type, log.CreatedAt)
 }
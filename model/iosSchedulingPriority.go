@@ -2,35 +2,192 @@ package model
 
 import (
 	"fmt"
+	"math/bits"
+	"sync"
 	"time"
 )
 
 // IOSSchedulingPriority stores some default priorities for the scheduling of
 // grade updates.
+//
+// The days of the range are either given as calendar dates (FromMonth,
+// FromMonthDay, ToMonth, ToMonthDay) or, if FromMonth is zero, as year-day
+// numbers (FromDay, ToDay). Year-day numbers shift by one day after February
+// 29 in leap years, so calendar dates should be preferred.
+//
+// A range whose start is after its end wraps around, e.g. Dec 20 to Jan 7 or
+// 22:00 to 02:00. Both bounds are inclusive, an hour range of 22 to 2 covers
+// 22:00 until 02:59.
+//
+// The range is evaluated in TimeZone, an IANA time zone name. An empty
+// TimeZone means the local time zone of the server.
 type IOSSchedulingPriority struct {
-	ID       int `gorm:"primary_key;auto_increment;not_null" json:"id"`
-	FromDay  int `gorm:"not null" json:"from_day"`
-	ToDay    int `gorm:"not null" json:"to_day"`
-	FromHour int `gorm:"not null" json:"from_hour"`
-	ToHour   int `gorm:"not null" json:"to_hour"`
-	Priority int `gorm:"not null" json:"priority"`
+	ID           int `gorm:"primary_key;auto_increment;not_null" json:"id"`
+	FromDay      int `gorm:"not null" json:"from_day"`
+	ToDay        int `gorm:"not null" json:"to_day"`
+	FromMonth    int `gorm:"not null;default:0" json:"from_month"`
+	FromMonthDay int `gorm:"not null;default:0" json:"from_month_day"`
+	ToMonth      int `gorm:"not null;default:0" json:"to_month"`
+	ToMonthDay   int `gorm:"not null;default:0" json:"to_month_day"`
+	FromHour     int `gorm:"not null" json:"from_hour"`
+	ToHour       int `gorm:"not null" json:"to_hour"`
+	// Weekdays is a bit mask of the days of the week the priority applies to,
+	// bit 0 is Sunday as in time.Weekday. Zero means every day.
+	Weekdays uint8  `gorm:"not null;default:0" json:"weekdays"`
+	TimeZone string `gorm:"size:64;not null;default:''" json:"time_zone"`
+	Priority int    `gorm:"not null" json:"priority"`
+}
+
+// Weekdays that can be combined for IOSSchedulingPriority.Weekdays.
+const (
+	IOSWeekdaysMondayToFriday uint8 = 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday
+	IOSWeekdaysWeekend        uint8 = 1<<time.Saturday | 1<<time.Sunday
+)
+
+// referenceLeapYear is used to convert calendar dates to year days, so that
+// February 29 has its own year day.
+const referenceLeapYear = 2024
+
+var locations sync.Map
+
+// Location returns the time zone the range is evaluated in.
+func (p *IOSSchedulingPriority) Location() (*time.Location, error) {
+	if p.TimeZone == "" {
+		return time.Local, nil
+	}
+
+	if location, ok := locations.Load(p.TimeZone); ok {
+		return location.(*time.Location), nil
+	}
+
+	location, err := time.LoadLocation(p.TimeZone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Store(p.TimeZone, location)
+
+	return location, nil
 }
 
 // IsCurrentlyInRange returns true if the current time is in the range of the
 // scheduling priority.
 func (p *IOSSchedulingPriority) IsCurrentlyInRange() bool {
-	now := time.Now()
-	yearDay := now.YearDay()
+	return p.IsInRangeAt(time.Now())
+}
 
-	if p.FromDay <= yearDay && p.ToDay >= yearDay {
-		hour := now.Hour()
+// IsInRangeAt returns true if t is in the range of the scheduling priority.
+// It is never in range if the TimeZone is invalid.
+func (p *IOSSchedulingPriority) IsInRangeAt(t time.Time) bool {
+	location, err := p.Location()
+
+	if err != nil {
+		return false
+	}
+
+	t = t.In(location)
+
+	if p.Weekdays != 0 && p.Weekdays&(1<<t.Weekday()) == 0 {
+		return false
+	}
+
+	from, to := p.dayRange()
+
+	return inWrappingRange(p.yearDay(t), from, to) && inWrappingRange(t.Hour(), p.FromHour, p.ToHour)
+}
+
+// UsesCalendarDates returns true if the days are given as calendar dates.
+func (p *IOSSchedulingPriority) UsesCalendarDates() bool {
+	return p.FromMonth != 0
+}
+
+// dayRange returns the first and last day of the range as year days. Calendar
+// dates are converted to year days of a leap year.
+func (p *IOSSchedulingPriority) dayRange() (int, int) {
+	if !p.UsesCalendarDates() {
+		return p.FromDay, p.ToDay
+	}
+
+	return calendarYearDay(p.FromMonth, p.FromMonthDay), calendarYearDay(p.ToMonth, p.ToMonthDay)
+}
+
+// yearDay returns the year day of t comparable to dayRange.
+func (p *IOSSchedulingPriority) yearDay(t time.Time) int {
+	if !p.UsesCalendarDates() {
+		return t.YearDay()
+	}
+
+	return calendarYearDay(int(t.Month()), t.Day())
+}
 
-		if p.FromHour <= hour && p.ToHour >= hour {
-			return true
+func calendarYearDay(month int, day int) int {
+	return time.Date(referenceLeapYear, time.Month(month), day, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func inWrappingRange(value int, from int, to int) bool {
+	if from <= to {
+		return from <= value && value <= to
+	}
+
+	return value >= from || value <= to
+}
+
+func wrappingRangeLength(from int, to int, size int) int {
+	if from <= to {
+		return to - from + 1
+	}
+
+	return size - from + 1 + to
+}
+
+// Span returns the number of hours per year covered by the scheduling
+// priority. A smaller span means a more specific priority.
+func (p *IOSSchedulingPriority) Span() int {
+	from, to := p.dayRange()
+
+	days := wrappingRangeLength(from, to, 366)
+	hours := wrappingRangeLength(p.FromHour, p.ToHour, 24)
+
+	if p.Weekdays != 0 {
+		return days * hours * bits.OnesCount8(p.Weekdays&0x7f) / 7
+	}
+
+	return days * hours
+}
+
+// Validate returns an error if a field of the scheduling priority is out of
+// range.
+func (p *IOSSchedulingPriority) Validate() error {
+	if _, err := p.Location(); err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", p.TimeZone, err)
+	}
+
+	if p.FromHour < 0 || p.FromHour > 23 || p.ToHour < 0 || p.ToHour > 23 {
+		return fmt.Errorf("hours %d-%d are not between 0 and 23", p.FromHour, p.ToHour)
+	}
+
+	if p.Weekdays > 0x7f {
+		return fmt.Errorf("weekday mask %b has more than 7 bits", p.Weekdays)
+	}
+
+	if !p.UsesCalendarDates() {
+		if p.FromDay < 1 || p.FromDay > 366 || p.ToDay < 1 || p.ToDay > 366 {
+			return fmt.Errorf("days %d-%d are not between 1 and 366", p.FromDay, p.ToDay)
+		}
+
+		return nil
+	}
+
+	for _, date := range [][2]int{{p.FromMonth, p.FromMonthDay}, {p.ToMonth, p.ToMonthDay}} {
+		month, day := date[0], date[1]
+
+		if month < 1 || month > 12 || day < 1 || day > time.Date(referenceLeapYear, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			return fmt.Errorf("invalid calendar date %02d-%02d", month, day)
 		}
 	}
 
-	return false
+	return nil
 }
 
 // IsMorePreciseThan compares two Priorities and returns true if the current
@@ -53,13 +210,29 @@ func (p *IOSSchedulingPriority) IsMorePreciseThan(other *IOSSchedulingPriority)
 }
 
 func (p *IOSSchedulingPriority) String() string {
-	return fmt.Sprintf("Day: %d-%d, Hour: %d-%d, Priority: %d", p.FromDay, p.ToDay, p.FromHour, p.ToHour, p.Priority)
+	days := fmt.Sprintf("Day: %d-%d", p.FromDay, p.ToDay)
+
+	if p.UsesCalendarDates() {
+		days = fmt.Sprintf("Date: %02d-%02d to %02d-%02d", p.FromMonth, p.FromMonthDay, p.ToMonth, p.ToMonthDay)
+	}
+
+	s := fmt.Sprintf("%s, Hour: %d-%d, Priority: %d", days, p.FromHour, p.ToHour, p.Priority)
+
+	if p.Weekdays != 0 {
+		s += fmt.Sprintf(", Weekdays: %07b", p.Weekdays)
+	}
+
+	if p.TimeZone != "" {
+		s += ", TimeZone: " + p.TimeZone
+	}
+
+	return s
 }
 
 func DefaultIOSSchedulingPriority() *IOSSchedulingPriority {
 	return &IOSSchedulingPriority{
 		FromDay:  1,
-		ToDay:    365,
+		ToDay:    366,
 		FromHour: 0,
 		ToHour:   23,
 		Priority: 5,
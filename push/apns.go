@@ -0,0 +1,204 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	ProductionEndpoint  = "https://api.push.apple.com"
+	DevelopmentEndpoint = "https://api.sandbox.push.apple.com"
+)
+
+// Reasons returned by APNs that are handled explicitly. See
+// https://developer.apple.com/documentation/usernotifications/handling-notification-responses-from-apns
+const (
+	ReasonBadDeviceToken         = "BadDeviceToken"
+	ReasonUnregistered           = "Unregistered"
+	ReasonDeviceTokenNotForTopic = "DeviceTokenNotForTopic"
+	ReasonExpiredProviderToken   = "ExpiredProviderToken"
+	ReasonInvalidProviderToken   = "InvalidProviderToken"
+	ReasonTooManyRequests        = "TooManyRequests"
+)
+
+// Config contains everything needed for token-based authentication with
+// APNs.
+type Config struct {
+	// Endpoint is ProductionEndpoint, DevelopmentEndpoint or the URL of a
+	// MockServer.
+	Endpoint   string
+	KeyID      string
+	TeamID     string
+	Topic      string
+	PrivateKey *ecdsa.PrivateKey
+	// Expiration is how long APNs tries to deliver a notification.
+	Expiration time.Duration
+}
+
+// Response is the outcome of sending a notification to a single device.
+type Response struct {
+	DeviceToken string
+	StatusCode  int
+	ApnsID      string
+	Reason      string
+	// Timestamp is set if the reason is Unregistered and tells when the
+	// device token stopped being valid.
+	Timestamp time.Time
+}
+
+// Delivered returns true if APNs accepted the notification.
+func (r *Response) Delivered() bool {
+	return r.StatusCode == http.StatusOK
+}
+
+// InvalidToken returns true if APNs reported that the device token is no
+// longer valid for the topic. Only the reason Unregistered and the status
+// 410 tell this for sure.
+func (r *Response) InvalidToken() bool {
+	return r.Reason == ReasonUnregistered || r.StatusCode == http.StatusGone
+}
+
+// Misconfigured returns true if APNs rejected the device token for a reason
+// that a wrong Topic or an Endpoint of the wrong environment cause for every
+// device, e.g. sandbox tokens sent to production. The device tokens are
+// therefore not reported as invalid.
+func (r *Response) Misconfigured() bool {
+	return r.Reason == ReasonBadDeviceToken || r.Reason == ReasonDeviceTokenNotForTopic
+}
+
+func (r *Response) String() string {
+	if r.Delivered() {
+		return fmt.Sprintf("Response{DeviceToken: %s, Delivered}", r.DeviceToken)
+	}
+
+	return fmt.Sprintf("Response{DeviceToken: %s, StatusCode: %d, Reason: %s}", r.DeviceToken, r.StatusCode, r.Reason)
+}
+
+type errorBody struct {
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Client sends background notifications to APNs over HTTP/2.
+type Client struct {
+	Config     Config
+	HTTPClient *http.Client
+	tokens     *tokenSource
+}
+
+// NewClient creates a client for config. If httpClient is nil a client with
+// HTTP/2 enabled is used.
+func NewClient(config Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Transport: &http.Transport{ForceAttemptHTTP2: true},
+			Timeout:   30 * time.Second,
+		}
+	}
+
+	return &Client{
+		Config:     config,
+		HTTPClient: httpClient,
+		tokens: &tokenSource{
+			keyID:      config.KeyID,
+			teamID:     config.TeamID,
+			privateKey: config.PrivateKey,
+		},
+	}
+}
+
+// Send pushes a background notification to the device with deviceToken. An
+// error is only returned if APNs could not be reached, rejected
+// notifications are reported in the Response.
+func (c *Client) Send(ctx context.Context, deviceToken string, notification *Notification) (*Response, error) {
+	payload, err := json.Marshal(notification)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.send(ctx, deviceToken, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The provider token is refreshed and the notification is sent again once.
+	if response.Reason == ReasonExpiredProviderToken || response.Reason == ReasonInvalidProviderToken {
+		c.tokens.Invalidate()
+
+		return c.send(ctx, deviceToken, payload)
+	}
+
+	return response, nil
+}
+
+func (c *Client) send(ctx context.Context, deviceToken string, payload []byte) (*Response, error) {
+	token, err := c.tokens.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/3/device/%s", c.Config.Endpoint, deviceToken)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("authorization", "bearer "+token)
+	request.Header.Set("apns-topic", c.Config.Topic)
+	// Background notifications have to be sent with push type background
+	// and priority 5, otherwise APNs rejects or throttles them.
+	request.Header.Set("apns-push-type", "background")
+	request.Header.Set("apns-priority", "5")
+
+	if c.Config.Expiration > 0 {
+		request.Header.Set("apns-expiration", fmt.Sprint(time.Now().Add(c.Config.Expiration).Unix()))
+	}
+
+	httpResponse, err := c.HTTPClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer httpResponse.Body.Close()
+
+	response := Response{
+		DeviceToken: deviceToken,
+		StatusCode:  httpResponse.StatusCode,
+		ApnsID:      httpResponse.Header.Get("apns-id"),
+	}
+
+	if httpResponse.StatusCode == http.StatusOK {
+		return &response, nil
+	}
+
+	body, err := io.ReadAll(httpResponse.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var errBody errorBody
+
+	if err := json.Unmarshal(body, &errBody); err != nil {
+		return nil, fmt.Errorf("unexpected APNs response (%d): %s", httpResponse.StatusCode, body)
+	}
+
+	response.Reason = errBody.Reason
+
+	if errBody.Timestamp > 0 {
+		response.Timestamp = time.UnixMilli(errBody.Timestamp)
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,151 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockServer is a local stand-in for APNs. It accepts the same HTTP/2
+// requests, verifies the provider token and answers with the reasons
+// configured per device token, so the push delivery can be used offline.
+type MockServer struct {
+	Server *httptest.Server
+
+	publicKey *ecdsa.PublicKey
+
+	mutex         sync.Mutex
+	reasons       map[string]string
+	dropped       map[string]bool
+	notifications map[string][]Notification
+}
+
+// NewMockServer starts a TLS server with HTTP/2 enabled that verifies the
+// provider tokens with the public part of privateKey.
+func NewMockServer(privateKey *ecdsa.PrivateKey) *MockServer {
+	m := &MockServer{
+		publicKey:     &privateKey.PublicKey,
+		reasons:       make(map[string]string),
+		dropped:       make(map[string]bool),
+		notifications: make(map[string][]Notification),
+	}
+
+	m.Server = httptest.NewUnstartedServer(http.HandlerFunc(m.handle))
+	m.Server.EnableHTTP2 = true
+	m.Server.StartTLS()
+
+	return m
+}
+
+// Client returns an APNs client that sends to the mock server.
+func (m *MockServer) Client(config Config) *Client {
+	config.Endpoint = m.Server.URL
+
+	return NewClient(config, m.Server.Client())
+}
+
+// Reject lets the server answer every notification for deviceToken with
+// reason, e.g. ReasonBadDeviceToken or ReasonUnregistered.
+func (m *MockServer) Reject(deviceToken string, reason string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reasons[deviceToken] = reason
+}
+
+// Drop lets the server abort every notification for deviceToken without
+// an answer, as if the connection to APNs was lost.
+func (m *MockServer) Drop(deviceToken string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.dropped[deviceToken] = true
+}
+
+// Notifications returns the notifications accepted for deviceToken.
+func (m *MockServer) Notifications(deviceToken string) []Notification {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.notifications[deviceToken]
+}
+
+func (m *MockServer) Close() {
+	m.Server.Close()
+}
+
+func (m *MockServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		m.reject(w, http.StatusBadRequest, "BadProtocol")
+		return
+	}
+
+	deviceToken := strings.TrimPrefix(r.URL.Path, "/3/device/")
+
+	if r.Method != http.MethodPost || deviceToken == r.URL.Path || deviceToken == "" {
+		m.reject(w, http.StatusNotFound, "BadPath")
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+
+	if !verifyToken(token, m.publicKey) {
+		m.reject(w, http.StatusForbidden, ReasonInvalidProviderToken)
+		return
+	}
+
+	if r.Header.Get("apns-topic") == "" {
+		m.reject(w, http.StatusBadRequest, "MissingTopic")
+		return
+	}
+
+	if r.Header.Get("apns-push-type") != "background" || r.Header.Get("apns-priority") != "5" {
+		m.reject(w, http.StatusBadRequest, "InvalidPushType")
+		return
+	}
+
+	var notification Notification
+
+	if err := json.NewDecoder(r.Body).Decode(&notification); err != nil || notification.Aps.ContentAvailable != 1 {
+		m.reject(w, http.StatusBadRequest, "PayloadEmpty")
+		return
+	}
+
+	m.mutex.Lock()
+	reason, rejected := m.reasons[deviceToken]
+	dropped := m.dropped[deviceToken]
+
+	if !rejected && !dropped {
+		m.notifications[deviceToken] = append(m.notifications[deviceToken], notification)
+	}
+	m.mutex.Unlock()
+
+	if dropped {
+		// Resets the stream, the client gets an error instead of a response.
+		panic(http.ErrAbortHandler)
+	}
+
+	switch reason {
+	case "":
+		w.Header().Set("apns-id", r.Header.Get("apns-id"))
+		w.WriteHeader(http.StatusOK)
+	case ReasonUnregistered:
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		_ = json.NewEncoder(w).Encode(errorBody{Reason: reason, Timestamp: time.Now().UnixMilli()})
+	case ReasonTooManyRequests:
+		m.reject(w, http.StatusTooManyRequests, reason)
+	default:
+		m.reject(w, http.StatusBadRequest, reason)
+	}
+}
+
+func (m *MockServer) reject(w http.ResponseWriter, statusCode int, reason string) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(errorBody{Reason: reason})
+}
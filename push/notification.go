@@ -0,0 +1,63 @@
+// Package push delivers background push notifications to the devices via
+// the Apple Push Notification service (APNs).
+package push
+
+import (
+	"encoding/json"
+	"test-student-lecture-selection-algorithm/ios_crypto"
+	"test-student-lecture-selection-algorithm/model"
+)
+
+// Notification is the payload of a background push notification. The
+// RequestID is either sent as is or, if the device has a PublicKey and
+// encryption is enabled, encrypted in EncryptedRequest.
+type Notification struct {
+	Aps              Aps    `json:"aps"`
+	RequestID        string `json:"requestId,omitempty"`
+	RequestType      string `json:"requestType,omitempty"`
+	EncryptedRequest string `json:"encryptedRequest,omitempty"`
+}
+
+// Aps is the Apple defined part of the payload.
+type Aps struct {
+	ContentAvailable int `json:"content-available"`
+}
+
+type encryptedRequest struct {
+	RequestID   string `json:"requestId"`
+	RequestType string `json:"requestType"`
+}
+
+// NewNotification builds the background notification for a request.
+func NewNotification(request *model.IOSDeviceRequestLog) *Notification {
+	return &Notification{
+		Aps:         Aps{ContentAvailable: 1},
+		RequestID:   request.RequestID,
+		RequestType: request.RequestType,
+	}
+}
+
+// NewEncryptedNotification builds the background notification for a
+// request and encrypts the request with the PublicKey of the device using
+// ios_crypto.AsymmetricEncrypt.
+func NewEncryptedNotification(request *model.IOSDeviceRequestLog, publicKey string) (*Notification, error) {
+	plaintext, err := json.Marshal(encryptedRequest{
+		RequestID:   request.RequestID,
+		RequestType: request.RequestType,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := ios_crypto.AsymmetricEncrypt(string(plaintext), publicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notification{
+		Aps:              Aps{ContentAvailable: 1},
+		EncryptedRequest: encrypted.String(),
+	}, nil
+}
@@ -0,0 +1,236 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+)
+
+// ErrMisconfigured is returned when APNs rejected a device token in a way
+// that points to a wrong Topic or Endpoint, see Response.Misconfigured.
+var ErrMisconfigured = errors.New("APNs rejected the device token, check the topic and the endpoint")
+
+// A FeedbackHandler is told about the devices whose token was reported as
+// invalid.
+type FeedbackHandler interface {
+	InvalidTokens(deviceIds *[]string) error
+}
+
+// Sender delivers the requests of a dispatch round as background
+// notifications. It can be used as dispatch.Sender.
+type Sender struct {
+	Repository repository.Repository
+	Client     *Client
+	Feedback   FeedbackHandler
+	// Encrypt encrypts the request with the PublicKey of the device.
+	Encrypt bool
+	// Workers is the number of notifications sent concurrently.
+	Workers int
+}
+
+func NewSender(repo repository.Repository, client *Client, feedback FeedbackHandler, encrypt bool) *Sender {
+	return &Sender{
+		Repository: repo,
+		Client:     client,
+		Feedback:   feedback,
+		Encrypt:    encrypt,
+		Workers:    16,
+	}
+}
+
+// Send pushes the requests and logs how many were delivered. An error is
+// returned if not a single notification reached APNs or if the sending was
+// stopped because of ErrMisconfigured.
+func (s *Sender) Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error {
+	deliveries, err := s.SendAll(ctx, requests)
+
+	var sent []string
+	var failed []string
+	var invalidTokens []string
+
+	reached := 0
+	reported := make(map[string]bool)
+
+	for _, delivery := range *deliveries {
+		if delivery.Err != nil {
+			failed = append(failed, delivery.Request.RequestID)
+			continue
+		}
+
+		reached++
+
+		if delivery.Response.Delivered() {
+			sent = append(sent, delivery.Request.RequestID)
+			continue
+		}
+
+		failed = append(failed, delivery.Request.RequestID)
+
+		// A device can have several requests in a round, e.g. a token and a
+		// lecture update request, but is reported once.
+		if delivery.Response.InvalidToken() && !reported[delivery.Request.DeviceID] {
+			reported[delivery.Request.DeviceID] = true
+			invalidTokens = append(invalidTokens, delivery.Request.DeviceID)
+		}
+	}
+
+	if _, err := s.Repository.MarkRequestsAttempted(&sent, model.IOSRequestStatusSent); err != nil {
+		log.WithError(err).Error("Failed to mark requests as sent")
+	}
+
+	if _, err := s.Repository.MarkRequestsAttempted(&failed, model.IOSRequestStatusDeliveryFailed); err != nil {
+		log.WithError(err).Error("Failed to mark requests as delivery failed")
+	}
+
+	log.Infof(
+		"Round %d: delivered %d/%d notifications, %d invalid device tokens",
+		round,
+		len(sent),
+		len(*requests),
+		len(invalidTokens),
+	)
+
+	if s.Feedback != nil && len(invalidTokens) > 0 {
+		if err := s.Feedback.InvalidTokens(&invalidTokens); err != nil {
+			log.WithError(err).Error("Failed to report invalid device tokens")
+		}
+	}
+
+	if errors.Is(err, ErrMisconfigured) {
+		log.WithError(err).Errorf("Round %d: stopped sending", round)
+
+		return err
+	}
+
+	if err != nil && reached == 0 {
+		return err
+	}
+
+	if err != nil {
+		log.WithError(err).Warn("Some notifications could not be sent")
+	}
+
+	return nil
+}
+
+// Delivery is the response of APNs to the notification of a request.
+type Delivery struct {
+	Request  model.IOSDeviceRequestLog
+	Response Response
+	// Err is set if the notification did not reach APNs, the Response is
+	// empty then.
+	Err error
+}
+
+// SendAll pushes the requests concurrently. A delivery is returned for every
+// request that was sent, including the ones that did not reach APNs,
+// together with the first of their errors. The sending stops at the first misconfigured
+// response with ErrMisconfigured.
+func (s *Sender) SendAll(ctx context.Context, requests *[]model.IOSDeviceRequestLog) (*[]Delivery, error) {
+	publicKeys := make(map[string]string)
+
+	if s.Encrypt {
+		deviceIds := make([]string, 0, len(*requests))
+
+		for _, request := range *requests {
+			deviceIds = append(deviceIds, request.DeviceID)
+		}
+
+		devices, err := s.Repository.GetDevicesByIds(&deviceIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, device := range *devices {
+			publicKeys[device.DeviceID] = device.PublicKey
+		}
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	jobs := make(chan model.IOSDeviceRequestLog)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var deliveries []Delivery
+	var errs []error
+	var misconfigured *Response
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for request := range jobs {
+				response, err := s.send(ctx, &request, publicKeys[request.DeviceID])
+
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, err)
+					deliveries = append(deliveries, Delivery{Request: request, Err: err})
+				} else {
+					deliveries = append(deliveries, Delivery{Request: request, Response: *response})
+				}
+
+				if response != nil && response.Misconfigured() && misconfigured == nil {
+					misconfigured = response
+					stop()
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	// The requests not handed to a worker yet are dropped once ctx is
+	// canceled.
+	canceled := false
+
+	for _, request := range *requests {
+		if ctx.Err() != nil {
+			canceled = true
+			break
+		}
+
+		jobs <- request
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if misconfigured != nil {
+		return &deliveries, fmt.Errorf("%w: %s", ErrMisconfigured, misconfigured)
+	}
+
+	if canceled {
+		return &deliveries, fmt.Errorf("sending canceled: %w", ctx.Err())
+	}
+
+	if len(errs) > 0 {
+		return &deliveries, fmt.Errorf("failed to send %d notifications: %w", len(errs), errs[0])
+	}
+
+	return &deliveries, nil
+}
+
+func (s *Sender) send(ctx context.Context, request *model.IOSDeviceRequestLog, publicKey string) (*Response, error) {
+	notification := NewNotification(request)
+
+	if s.Encrypt {
+		encrypted, err := NewEncryptedNotification(request, publicKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt request for device %s: %w", request.DeviceID, err)
+		}
+
+		notification = encrypted
+	}
+
+	return s.Client.Send(ctx, request.DeviceID, notification)
+}
@@ -0,0 +1,282 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+type recordedFeedback struct {
+	deviceIds []string
+}
+
+func (f *recordedFeedback) InvalidTokens(deviceIds *[]string) error {
+	f.deviceIds = append(f.deviceIds, *deviceIds...)
+
+	return nil
+}
+
+// newTestSender returns a sender to a mock APNs server and a repository with
+// the devices of deviceIds, whose IDs are their device tokens.
+func newTestSender(t *testing.T, deviceIds ...string) (*Sender, *MockServer, *recordedFeedback) {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := NewMockServer(privateKey)
+	t.Cleanup(mock.Close)
+
+	repo := memory.New()
+	devices := make([]model.IOSDevice, 0, len(deviceIds))
+
+	for _, deviceId := range deviceIds {
+		devices = append(devices, model.IOSDevice{DeviceID: deviceId})
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	feedback := &recordedFeedback{}
+	client := mock.Client(Config{KeyID: "key", TeamID: "team", Topic: "de.tum.campusapp", PrivateKey: privateKey})
+
+	return NewSender(repo, client, feedback, false), mock, feedback
+}
+
+func createRequests(t *testing.T, s *Sender, requestLogs ...model.IOSDeviceRequestLog) *[]model.IOSDeviceRequestLog {
+	t.Helper()
+
+	if err := s.Repository.CreateRequestLogs(&requestLogs); err != nil {
+		t.Fatal(err)
+	}
+
+	return &requestLogs
+}
+
+func statuses(t *testing.T, s *Sender) map[string]string {
+	t.Helper()
+
+	requestLogs, err := s.Repository.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byRequest := make(map[string]string, len(*requestLogs))
+
+	for _, requestLog := range *requestLogs {
+		byRequest[requestLog.RequestID] = requestLog.Status
+	}
+
+	return byRequest
+}
+
+func TestSendMarksDeliveredAndRejectedRequests(t *testing.T) {
+	s, mock, feedback := newTestSender(t, "token-a", "token-b")
+	now := time.Now()
+	requests := createRequests(
+		t,
+		s,
+		model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, now),
+		model.NewIOSDeviceRequestLog("token-b", model.IOSLectureUpdateRequestType, now),
+	)
+
+	mock.Reject("token-b", ReasonUnregistered)
+
+	if err := s.Send(context.Background(), 1, requests); err != nil {
+		t.Fatal(err)
+	}
+
+	got := statuses(t, s)
+
+	if status := got[(*requests)[0].RequestID]; status != model.IOSRequestStatusSent {
+		t.Errorf("delivered request is %s instead of sent", status)
+	}
+
+	if status := got[(*requests)[1].RequestID]; status != model.IOSRequestStatusDeliveryFailed {
+		t.Errorf("rejected request is %s instead of delivery_failed", status)
+	}
+
+	if len(feedback.deviceIds) != 1 || feedback.deviceIds[0] != "token-b" {
+		t.Errorf("expected token-b to be reported as invalid, got %v", feedback.deviceIds)
+	}
+
+	notifications := mock.Notifications("token-a")
+
+	if len(notifications) != 1 || notifications[0].RequestID != (*requests)[0].RequestID || notifications[0].Aps.ContentAvailable != 1 {
+		t.Errorf("unexpected notifications of token-a: %+v", notifications)
+	}
+}
+
+func TestSendStopsWhenMisconfigured(t *testing.T) {
+	for _, reason := range []string{ReasonBadDeviceToken, ReasonDeviceTokenNotForTopic} {
+		t.Run(reason, func(t *testing.T) {
+			s, mock, feedback := newTestSender(t, "token-a", "token-b", "token-c")
+			s.Workers = 1
+			now := time.Now()
+			requests := createRequests(
+				t,
+				s,
+				model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, now),
+				model.NewIOSDeviceRequestLog("token-b", model.IOSLectureUpdateRequestType, now),
+				model.NewIOSDeviceRequestLog("token-c", model.IOSLectureUpdateRequestType, now),
+			)
+
+			// A wrong topic or endpoint rejects every device.
+			for _, request := range *requests {
+				mock.Reject(request.DeviceID, reason)
+			}
+
+			err := s.Send(context.Background(), 1, requests)
+
+			if !errors.Is(err, ErrMisconfigured) {
+				t.Fatalf("expected %v, got %v", ErrMisconfigured, err)
+			}
+
+			if len(feedback.deviceIds) != 0 {
+				t.Errorf("reported %v as invalid after a misconfiguration", feedback.deviceIds)
+			}
+
+			for _, deviceId := range []string{"token-b", "token-c"} {
+				if notifications := mock.Notifications(deviceId); len(notifications) != 0 {
+					t.Errorf("%d notifications were sent to %s after the misconfiguration", len(notifications), deviceId)
+				}
+			}
+
+			if status := statuses(t, s)[(*requests)[0].RequestID]; status != model.IOSRequestStatusDeliveryFailed {
+				t.Errorf("rejected request is %s instead of delivery_failed", status)
+			}
+		})
+	}
+}
+
+func TestSendMarksRequestsThatDidNotReachAPNs(t *testing.T) {
+	s, mock, feedback := newTestSender(t, "token-a", "token-b")
+	now := time.Now()
+	requests := createRequests(
+		t,
+		s,
+		model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, now),
+		model.NewIOSDeviceRequestLog("token-b", model.IOSLectureUpdateRequestType, now),
+	)
+
+	mock.Drop("token-b")
+
+	// One notification reached APNs, so the round goes on.
+	if err := s.Send(context.Background(), 1, requests); err != nil {
+		t.Fatal(err)
+	}
+
+	got := statuses(t, s)
+
+	if status := got[(*requests)[0].RequestID]; status != model.IOSRequestStatusSent {
+		t.Errorf("delivered request is %s instead of sent", status)
+	}
+
+	if status := got[(*requests)[1].RequestID]; status != model.IOSRequestStatusDeliveryFailed {
+		t.Errorf("dropped request is %s instead of delivery_failed", status)
+	}
+
+	if len(feedback.deviceIds) != 0 {
+		t.Errorf("reported %v as invalid after a lost connection", feedback.deviceIds)
+	}
+
+	// The sweeper retries the dropped request.
+	retryable, err := s.Repository.GetRetryableRequests(3, now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*retryable) != 1 || (*retryable)[0].RequestID != (*requests)[1].RequestID || (*retryable)[0].Attempts != 1 {
+		t.Errorf("retryable requests %+v instead of the dropped one", *retryable)
+	}
+
+	// Without a single notification reaching APNs the error is returned.
+	mock.Drop("token-a")
+
+	if err := s.Send(context.Background(), 2, requests); err == nil {
+		t.Error("no error although no notification reached APNs")
+	}
+}
+
+func TestSendStopsWhenCanceled(t *testing.T) {
+	s, mock, _ := newTestSender(t, "token-a")
+	requests := createRequests(t, s, model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, time.Now()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.Send(ctx, 1, requests)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation as error, got %v", err)
+	}
+
+	if notifications := mock.Notifications("token-a"); len(notifications) != 0 {
+		t.Errorf("%d notifications were sent after the cancellation", len(notifications))
+	}
+
+	if status := statuses(t, s)[(*requests)[0].RequestID]; status != model.IOSRequestStatusCreated {
+		t.Errorf("request that was not pushed is %s instead of created", status)
+	}
+}
+
+func TestMockServerRejectsForeignProviderTokens(t *testing.T) {
+	_, mock, _ := newTestSender(t, "token-a")
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := mock.Client(Config{KeyID: "key", TeamID: "team", Topic: "de.tum.campusapp", PrivateKey: otherKey})
+	request := model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, time.Now())
+
+	response, err := client.Send(context.Background(), "token-a", NewNotification(&request))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Delivered() || response.Reason != ReasonInvalidProviderToken {
+		t.Errorf("expected the token to be rejected as %s, got %s", ReasonInvalidProviderToken, response)
+	}
+
+	if notifications := mock.Notifications("token-a"); len(notifications) != 0 {
+		t.Errorf("notification with a foreign provider token was accepted")
+	}
+}
+
+func TestSendMarksEveryRequestOfADevice(t *testing.T) {
+	s, _, _ := newTestSender(t, "token-a")
+	now := time.Now()
+	requests := createRequests(
+		t,
+		s,
+		model.NewIOSDeviceRequestLog("token-a", model.IOSTokenRequestType, now),
+		model.NewIOSDeviceRequestLog("token-a", model.IOSLectureUpdateRequestType, now),
+	)
+
+	if err := s.Send(context.Background(), 1, requests); err != nil {
+		t.Fatal(err)
+	}
+
+	got := statuses(t, s)
+
+	for _, request := range *requests {
+		if status := got[request.RequestID]; status != model.IOSRequestStatusSent {
+			t.Errorf("%s of token-a is %s instead of sent", request.RequestType, status)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenLifetime is how long a provider token is reused. APNs rejects tokens
+// older than one hour and refreshing them more often than every 20 minutes.
+const tokenLifetime = 50 * time.Minute
+
+// LoadPrivateKey parses the PEM encoded (.p8) signing key downloaded from
+// the Apple developer account.
+func LoadPrivateKey(p8 []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(p8)
+
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, errors.New("failed to parse PKCS8 encoded private key: " + err.Error())
+	}
+
+	if privateKey, ok := key.(*ecdsa.PrivateKey); ok {
+		return privateKey, nil
+	}
+
+	return nil, errors.New("private key is not an ECDSA key")
+}
+
+// tokenSource creates and caches the JSON web token used for token-based
+// authentication with APNs.
+type tokenSource struct {
+	keyID      string
+	teamID     string
+	privateKey *ecdsa.PrivateKey
+
+	mutex    sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// Token returns the cached token or signs a new one if it is too old.
+func (s *tokenSource) Token() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token != "" && time.Now().Sub(s.issuedAt) < tokenLifetime {
+		return s.token, nil
+	}
+
+	issuedAt := time.Now()
+	token, err := s.sign(issuedAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	s.token = token
+	s.issuedAt = issuedAt
+
+	return token, nil
+}
+
+// Invalidate forces a new token on the next call of Token, e.g. after APNs
+// answered with ExpiredProviderToken.
+func (s *tokenSource) Invalidate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.token = ""
+}
+
+func (s *tokenSource) sign(issuedAt time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "ES256",
+		"kid": s.keyID,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": s.teamID,
+		"iat": issuedAt.Unix(),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.privateKey, digest[:])
+
+	if err != nil {
+		return "", err
+	}
+
+	// ES256 signatures are the fixed size concatenation of r and s.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyToken checks the signature of a token created by tokenSource. It is
+// used by the MockServer.
+func verifyToken(token string, publicKey *ecdsa.PublicKey) bool {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return false
+	}
+
+	header, claims, signature := parts[0], parts[1], parts[2]
+
+	rawSignature, err := base64.RawURLEncoding.DecodeString(signature)
+
+	if err != nil || len(rawSignature) != 64 {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(header + "." + claims))
+	r := new(big.Int).SetBytes(rawSignature[:32])
+	s := new(big.Int).SetBytes(rawSignature[32:])
+
+	return ecdsa.Verify(publicKey, digest[:], r, s)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+// Dataset is a set of records written together by BulkLoad. The tables are
+// written in the order of their foreign keys: lectures, devices, enrollments,
+// request logs and scheduling priorities. The lectures therefore can't refer
+// to requests of the same dataset by their LastRequestId.
+type Dataset struct {
+	Lectures       []model.IOSLecture
+	Devices        []model.IOSDevice
+	DeviceLectures []model.IOSDeviceLecture
+	RequestLogs    []model.IOSDeviceRequestLog
+	Priorities     []model.IOSSchedulingPriority
+}
+
+// Rows returns the number of records of the dataset.
+func (d *Dataset) Rows() int {
+	return len(d.Lectures) + len(d.Devices) + len(d.DeviceLectures) + len(d.RequestLogs) + len(d.Priorities)
+}
+
+type BulkConfig struct {
+	// BatchSize is the number of rows per INSERT statement.
+	BatchSize int
+	// Upsert replaces the records whose primary key exists instead of
+	// failing. The creation times of the replaced records are kept.
+	Upsert bool
+}
+
+func DefaultBulkConfig() BulkConfig {
+	return BulkConfig{BatchSize: 1000}
+}
+
+// Size returns the BatchSize, but at least one.
+func (c BulkConfig) Size() int {
+	if c.BatchSize < 1 {
+		return 1
+	}
+
+	return c.BatchSize
+}
+
+// Batches returns the number of batches rows are written in.
+func (c BulkConfig) Batches(rows int) int {
+	return (rows + c.Size() - 1) / c.Size()
+}
+
+// TableStats tells how fast the records of a table were written.
+type TableStats struct {
+	Table   string
+	Rows    int
+	Batches int
+	Elapsed time.Duration
+}
+
+func (s TableStats) RowsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.Rows) / s.Elapsed.Seconds()
+}
+
+// BulkStats has the TableStats of every table of a load that had records.
+type BulkStats struct {
+	Tables  []TableStats
+	Elapsed time.Duration
+}
+
+// Rows returns the number of records written.
+func (s *BulkStats) Rows() int {
+	rows := 0
+
+	for _, table := range s.Tables {
+		rows += table.Rows
+	}
+
+	return rows
+}
+
+// BulkLoader writes large amounts of records, e.g. generated data or
+// imported scenarios.
+type BulkLoader interface {
+	// BulkLoad writes the dataset in batches of config.BatchSize in a single
+	// transaction: either all records are written or none. The IDs of new
+	// scheduling priorities and missing request IDs are set in dataset.
+	BulkLoad(dataset *Dataset, config BulkConfig) (*BulkStats, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBulkConfigBatches(t *testing.T) {
+	tests := []struct {
+		batchSize int
+		rows      int
+		batches   int
+	}{
+		{1000, 0, 0},
+		{1000, 1, 1},
+		{1000, 1000, 1},
+		{1000, 1001, 2},
+		{2, 5, 3},
+		{0, 3, 3},
+		{-1, 3, 3},
+	}
+
+	for _, test := range tests {
+		config := BulkConfig{BatchSize: test.batchSize}
+
+		if batches := config.Batches(test.rows); batches != test.batches {
+			t.Errorf("%d rows in batches of %d: expected %d batches, got %d", test.rows, test.batchSize, test.batches, batches)
+		}
+	}
+}
+
+func TestBulkStats(t *testing.T) {
+	stats := BulkStats{Tables: []TableStats{
+		{Table: "devices", Rows: 500, Batches: 1, Elapsed: 250 * time.Millisecond},
+		{Table: "lectures", Rows: 20, Batches: 1},
+	}}
+
+	if rows := stats.Rows(); rows != 520 {
+		t.Errorf("expected 520 rows, got %d", rows)
+	}
+
+	if rate := stats.Tables[0].RowsPerSecond(); rate != 2000 {
+		t.Errorf("expected 2000 rows per second, got %g", rate)
+	}
+
+	if rate := stats.Tables[1].RowsPerSecond(); rate != 0 {
+		t.Errorf("table without elapsed time has a rate of %g", rate)
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConnect is the kind of errors while connecting to the database.
+	ErrConnect = errors.New("failed to connect database")
+	// ErrMigrate is the kind of errors while migrating the schema.
+	ErrMigrate = errors.New("failed to migrate database")
+	// ErrQuery is the kind of errors of failed queries.
+	ErrQuery = errors.New("query failed")
+	// ErrNotFound is the kind of errors for records that do not exist.
+	ErrNotFound = errors.New("record not found")
+)
+
+// Error is returned by the implementations of Repository. Its Kind can be
+// checked with errors.Is, the error of the driver is available with
+// errors.Unwrap.
+type Error struct {
+	Kind error
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Op, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// QueryError wraps err as ErrQuery of op. It returns nil if err is nil.
+func QueryError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: ErrQuery, Op: op, Err: err}
+}
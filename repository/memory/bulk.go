@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+// BulkLoad inserts the tables of the dataset like the Create methods. There
+// are no batches in memory, they are only counted. If a table fails, the
+// tables written before are restored.
+func (r *Repository) BulkLoad(dataset *repository.Dataset, config repository.BulkConfig) (*repository.BulkStats, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	startTime := time.Now()
+	stats := repository.BulkStats{}
+	backup := r.snapshot()
+
+	for i := range dataset.RequestLogs {
+		if dataset.RequestLogs[i].RequestID == "" {
+			dataset.RequestLogs[i].RequestID = model.NewRequestID()
+		}
+	}
+
+	tables := []struct {
+		name string
+		rows int
+		load func() error
+	}{
+		{"ios_lectures", len(dataset.Lectures), func() error {
+			lectures, err := r.replaceLectures(dataset.Lectures, config.Upsert)
+
+			if err != nil {
+				return err
+			}
+
+			return r.insertLectures(lectures)
+		}},
+		{"ios_devices", len(dataset.Devices), func() error {
+			return r.insertDevices(r.replaceDevices(dataset.Devices, config.Upsert))
+		}},
+		{"ios_device_lectures", len(dataset.DeviceLectures), func() error {
+			return r.insertDeviceLectures(r.replaceDeviceLectures(dataset.DeviceLectures, config.Upsert))
+		}},
+		{"ios_device_request_logs", len(dataset.RequestLogs), func() error {
+			requestLogs, err := r.replaceRequestLogs(dataset.RequestLogs, config.Upsert)
+
+			if err != nil {
+				return err
+			}
+
+			return r.insertRequestLogs(requestLogs)
+		}},
+		{"ios_scheduling_priorities", len(dataset.Priorities), func() error {
+			return r.upsertSchedulingPriorities(dataset.Priorities, config.Upsert)
+		}},
+	}
+
+	for _, table := range tables {
+		if table.rows == 0 {
+			continue
+		}
+
+		tableStartTime := time.Now()
+
+		if err := table.load(); err != nil {
+			r.restore(backup)
+			return nil, queryError("bulk load", err)
+		}
+
+		stats.Tables = append(stats.Tables, repository.TableStats{
+			Table:   table.name,
+			Rows:    table.rows,
+			Batches: config.Batches(table.rows),
+			Elapsed: time.Now().Sub(tableStartTime),
+		})
+	}
+
+	stats.Elapsed = time.Now().Sub(startTime)
+
+	return &stats, nil
+}
+
+// snapshot holds copies of the tables written by BulkLoad.
+type snapshot struct {
+	devices        []model.IOSDevice
+	lectures       []model.IOSLecture
+	deviceLectures []model.IOSDeviceLecture
+	requestLogs    []model.IOSDeviceRequestLog
+	priorities     []model.IOSSchedulingPriority
+	nextPriorityId int
+}
+
+func (r *Repository) snapshot() snapshot {
+	return snapshot{
+		devices:        append([]model.IOSDevice{}, r.devices...),
+		lectures:       append([]model.IOSLecture{}, r.lectures...),
+		deviceLectures: append([]model.IOSDeviceLecture{}, r.deviceLectures...),
+		requestLogs:    append([]model.IOSDeviceRequestLog{}, r.requestLogs...),
+		priorities:     append([]model.IOSSchedulingPriority{}, r.priorities...),
+		nextPriorityId: r.nextPriorityId,
+	}
+}
+
+func (r *Repository) restore(s snapshot) {
+	r.devices = s.devices
+	r.lectures = s.lectures
+	r.deviceLectures = s.deviceLectures
+	r.requestLogs = s.requestLogs
+	r.priorities = s.priorities
+	r.nextPriorityId = s.nextPriorityId
+}
+
+// The replace methods replace the records whose key exists if upsert is set
+// and return the records left to insert. Like the upsert of the gorm
+// implementation, the creation times of the replaced records are kept and
+// the defaults apply to the new values.
+
+func (r *Repository) replaceLectures(lectures []model.IOSLecture, upsert bool) (*[]model.IOSLecture, error) {
+	if !upsert {
+		return &lectures, nil
+	}
+
+	index := make(map[string]int, len(r.lectures))
+
+	for i, lecture := range r.lectures {
+		index[lecture.Id] = i
+	}
+
+	var rest []model.IOSLecture
+
+	for _, lecture := range lectures {
+		i, ok := index[lecture.Id]
+
+		if !ok {
+			rest = append(rest, lecture)
+			continue
+		}
+
+		if lecture.LastRequestId != nil && r.requestLogIndex(*lecture.LastRequestId) < 0 {
+			return nil, errForeignKey
+		}
+
+		lecture = copyLecture(lecture)
+		lecture.LastUpdate = r.lectures[i].LastUpdate
+		r.lectures[i] = lecture
+	}
+
+	return &rest, nil
+}
+
+func (r *Repository) replaceDevices(devices []model.IOSDevice, upsert bool) *[]model.IOSDevice {
+	if !upsert {
+		return &devices
+	}
+
+	index := make(map[string]int, len(r.devices))
+
+	for i, device := range r.devices {
+		index[device.DeviceID] = i
+	}
+
+	var rest []model.IOSDevice
+
+	for _, device := range devices {
+		i, ok := index[device.DeviceID]
+
+		if !ok {
+			rest = append(rest, device)
+			continue
+		}
+
+		if device.State == "" {
+			device.State = model.IOSDeviceStateActive
+		}
+
+		device.CreatedAt = r.devices[i].CreatedAt
+		r.devices[i] = device
+	}
+
+	return &rest
+}
+
+// replaceDeviceLectures leaves out the existing enrollments, they have no
+// columns besides their key.
+func (r *Repository) replaceDeviceLectures(deviceLectures []model.IOSDeviceLecture, upsert bool) *[]model.IOSDeviceLecture {
+	if !upsert {
+		return &deviceLectures
+	}
+
+	existing := make(map[[2]string]bool, len(r.deviceLectures))
+
+	for _, deviceLecture := range r.deviceLectures {
+		existing[[2]string{deviceLecture.DeviceId, deviceLecture.LectureId}] = true
+	}
+
+	var rest []model.IOSDeviceLecture
+
+	for _, deviceLecture := range deviceLectures {
+		if !existing[[2]string{deviceLecture.DeviceId, deviceLecture.LectureId}] {
+			rest = append(rest, deviceLecture)
+		}
+	}
+
+	return &rest
+}
+
+func (r *Repository) replaceRequestLogs(requestLogs []model.IOSDeviceRequestLog, upsert bool) (*[]model.IOSDeviceRequestLog, error) {
+	if !upsert {
+		return &requestLogs, nil
+	}
+
+	devices := r.deviceIdSet()
+	index := make(map[string]int, len(r.requestLogs))
+
+	for i, requestLog := range r.requestLogs {
+		index[requestLog.RequestID] = i
+	}
+
+	var rest []model.IOSDeviceRequestLog
+
+	for _, requestLog := range requestLogs {
+		i, ok := index[requestLog.RequestID]
+
+		if !ok {
+			rest = append(rest, requestLog)
+			continue
+		}
+
+		if !devices[requestLog.DeviceID] {
+			return nil, errForeignKey
+		}
+
+		if requestLog.Status == "" {
+			requestLog.Status = model.IOSRequestStatusCreated
+		}
+
+		requestLog.Device = model.IOSDevice{}
+		requestLog.CreatedAt = r.requestLogs[i].CreatedAt
+		r.requestLogs[i] = requestLog
+	}
+
+	return &rest, nil
+}
+
+// replaceSchedulingPriorities returns the indexes of the priorities left to
+// insert. Only priorities with an ID can replace others.
+func (r *Repository) replaceSchedulingPriorities(priorities []model.IOSSchedulingPriority, upsert bool) []int {
+	index := make(map[int]int, len(r.priorities))
+
+	for i, priority := range r.priorities {
+		index[priority.ID] = i
+	}
+
+	var rest []int
+
+	for j, priority := range priorities {
+		if i, ok := index[priority.ID]; upsert && ok && priority.ID != 0 {
+			r.priorities[i] = priority
+			continue
+		}
+
+		rest = append(rest, j)
+	}
+
+	return rest
+}
+
+// upsertSchedulingPriorities sets the IDs of the inserted priorities in
+// priorities.
+func (r *Repository) upsertSchedulingPriorities(priorities []model.IOSSchedulingPriority, upsert bool) error {
+	indexes := r.replaceSchedulingPriorities(priorities, upsert)
+	rest := make([]model.IOSSchedulingPriority, 0, len(indexes))
+
+	for _, j := range indexes {
+		rest = append(rest, priorities[j])
+	}
+
+	if err := r.insertSchedulingPriorities(&rest); err != nil {
+		return err
+	}
+
+	for k, j := range indexes {
+		priorities[j].ID = rest[k].ID
+	}
+
+	return nil
+}
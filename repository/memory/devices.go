@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"database/sql"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+func (r *Repository) GetDevices() (*[]model.IOSDevice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	devices := append([]model.IOSDevice{}, r.devices...)
+
+	return &devices, nil
+}
+
+func (r *Repository) GetDevicesByIds(deviceIds *[]string) (*[]model.IOSDevice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := toSet(deviceIds)
+	devices := []model.IOSDevice{}
+
+	for _, device := range r.devices {
+		if wanted[device.DeviceID] {
+			devices = append(devices, device)
+		}
+	}
+
+	return &devices, nil
+}
+
+func (r *Repository) GetReadyDevices() (*[]model.IOSDevice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	devices := []model.IOSDevice{}
+
+	for _, device := range r.devices {
+		if device.State == model.IOSDeviceStateActive {
+			devices = append(devices, device)
+		}
+	}
+
+	return &devices, nil
+}
+
+func (r *Repository) GetDevicesInState(state string, changedBefore time.Time) (*[]model.IOSDevice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	devices := []model.IOSDevice{}
+
+	for _, device := range r.devices {
+		if device.State == state && (!device.StateChangedAt.Valid || device.StateChangedAt.Time.Before(changedBefore)) {
+			devices = append(devices, device)
+		}
+	}
+
+	return &devices, nil
+}
+
+func (r *Repository) CreateDevices(devices *[]model.IOSDevice) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.insertDevices(devices); err != nil {
+		return queryError("create devices", err)
+	}
+
+	return nil
+}
+
+// insertDevices checks the keys of all devices before inserting any of them.
+func (r *Repository) insertDevices(devices *[]model.IOSDevice) error {
+	existing := r.deviceIdSet()
+	now := time.Now()
+
+	for _, device := range *devices {
+		if existing[device.DeviceID] {
+			return errDuplicateKey
+		}
+
+		existing[device.DeviceID] = true
+	}
+
+	for _, device := range *devices {
+		if device.State == "" {
+			device.State = model.IOSDeviceStateActive
+		}
+
+		if device.CreatedAt.IsZero() {
+			device.CreatedAt = now
+		}
+
+		r.devices = append(r.devices, device)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetDevicesState(deviceIds *[]string, state string, changedAt time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := toSet(deviceIds)
+
+	var changed int64
+
+	for i := range r.devices {
+		device := &r.devices[i]
+
+		if wanted[device.DeviceID] && device.State != state {
+			device.State = state
+			device.StateChangedAt = sql.NullTime{Time: changedAt, Valid: true}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+func (r *Repository) SetDevicesStateFrom(deviceIds *[]string, from string, to string, changedAt time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := toSet(deviceIds)
+
+	var changed int64
+
+	for i := range r.devices {
+		device := &r.devices[i]
+
+		if wanted[device.DeviceID] && device.State == from {
+			device.State = to
+			device.StateChangedAt = sql.NullTime{Time: changedAt, Valid: true}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+func (r *Repository) PurgeUnregisteredDevices(before time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	purged := make(map[string]bool)
+	devices := r.devices[:0]
+
+	for _, device := range r.devices {
+		if device.State == model.IOSDeviceStateUnregistered && device.StateChangedAt.Valid && device.StateChangedAt.Time.Before(before) {
+			purged[device.DeviceID] = true
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	r.devices = devices
+
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	deviceLectures := r.deviceLectures[:0]
+
+	for _, deviceLecture := range r.deviceLectures {
+		if !purged[deviceLecture.DeviceId] {
+			deviceLectures = append(deviceLectures, deviceLecture)
+		}
+	}
+
+	r.deviceLectures = deviceLectures
+
+	deletedRequests := make(map[string]bool)
+	requestLogs := r.requestLogs[:0]
+
+	for _, requestLog := range r.requestLogs {
+		if purged[requestLog.DeviceID] {
+			deletedRequests[requestLog.RequestID] = true
+			continue
+		}
+
+		requestLogs = append(requestLogs, requestLog)
+	}
+
+	r.requestLogs = requestLogs
+
+	for i := range r.lectures {
+		if r.lectures[i].LastRequestId != nil && deletedRequests[*r.lectures[i].LastRequestId] {
+			r.lectures[i].LastRequestId = nil
+		}
+	}
+
+	grades := r.grades[:0]
+
+	for _, grade := range r.grades {
+		if !purged[grade.DeviceID] {
+			grades = append(grades, grade)
+		}
+	}
+
+	r.grades = grades
+
+	updateLogs := r.updateLogs[:0]
+
+	for _, updateLog := range r.updateLogs {
+		if !purged[updateLog.DeviceID] {
+			updateLogs = append(updateLogs, updateLog)
+		}
+	}
+
+	r.updateLogs = updateLogs
+
+	return int64(len(purged)), nil
+}
+
+func (r *Repository) UpdateActivityCounters(day time.Time, week time.Time, month time.Time, year time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	type counters struct {
+		day, week, month, year int32
+	}
+
+	handled := make(map[string]*counters)
+
+	for _, requestLog := range r.requestLogs {
+		if requestLog.Status != model.IOSRequestStatusHandled || !requestLog.HandledAt.Valid {
+			continue
+		}
+
+		c, ok := handled[requestLog.DeviceID]
+
+		if !ok {
+			c = &counters{}
+			handled[requestLog.DeviceID] = c
+		}
+
+		at := requestLog.HandledAt.Time
+
+		if !at.Before(day) {
+			c.day++
+		}
+
+		if !at.Before(week) {
+			c.week++
+		}
+
+		if !at.Before(month) {
+			c.month++
+		}
+
+		if !at.Before(year) {
+			c.year++
+		}
+	}
+
+	for i := range r.devices {
+		c, ok := handled[r.devices[i].DeviceID]
+
+		if !ok {
+			c = &counters{}
+		}
+
+		r.devices[i].ActivityToday = c.day
+		r.devices[i].ActivityThisWeek = c.week
+		r.devices[i].ActivityThisMonth = c.month
+		r.devices[i].ActivityThisYear = c.year
+	}
+
+	return int64(len(r.devices)), nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+)
+
+func (r *Repository) GetGradesByDevice(deviceId string) (*[]model.IOSEncryptedGrade, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	grades := []model.IOSEncryptedGrade{}
+
+	for _, grade := range r.grades {
+		if grade.DeviceID == deviceId {
+			grades = append(grades, grade)
+		}
+	}
+
+	return &grades, nil
+}
+
+func (r *Repository) CreateGrades(grades *[]model.IOSEncryptedGrade) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	devices := r.deviceIdSet()
+	existing := make(map[uint]bool, len(r.grades))
+
+	for _, grade := range r.grades {
+		existing[grade.ID] = true
+	}
+
+	for _, grade := range *grades {
+		if grade.ID != 0 && existing[grade.ID] {
+			return queryError("create grades", errDuplicateKey)
+		}
+
+		if !devices[grade.DeviceID] {
+			return queryError("create grades", errForeignKey)
+		}
+
+		existing[grade.ID] = true
+	}
+
+	for i := range *grades {
+		grade := &(*grades)[i]
+
+		if grade.ID == 0 {
+			grade.ID = r.nextGradeId
+		}
+
+		if grade.ID >= r.nextGradeId {
+			r.nextGradeId = grade.ID + 1
+		}
+
+		stored := *grade
+		stored.Device = model.IOSDevice{}
+
+		r.grades = append(r.grades, stored)
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+func (r *Repository) GetLectures() (*[]model.IOSLecture, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	lectures := make([]model.IOSLecture, 0, len(r.lectures))
+
+	for _, lecture := range r.lectures {
+		lectures = append(lectures, copyLecture(lecture))
+	}
+
+	return &lectures, nil
+}
+
+func (r *Repository) CreateLectures(lectures *[]model.IOSLecture) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.insertLectures(lectures); err != nil {
+		return queryError("create lectures", err)
+	}
+
+	return nil
+}
+
+// insertLectures checks the keys of all lectures before inserting any of
+// them.
+func (r *Repository) insertLectures(lectures *[]model.IOSLecture) error {
+	existing := make(map[string]bool, len(r.lectures))
+
+	for _, lecture := range r.lectures {
+		existing[lecture.Id] = true
+	}
+
+	for _, lecture := range *lectures {
+		if existing[lecture.Id] {
+			return errDuplicateKey
+		}
+
+		if lecture.LastRequestId != nil && r.requestLogIndex(*lecture.LastRequestId) < 0 {
+			return errForeignKey
+		}
+
+		existing[lecture.Id] = true
+	}
+
+	now := time.Now()
+
+	for _, lecture := range *lectures {
+		lecture = copyLecture(lecture)
+
+		if lecture.LastUpdate.IsZero() {
+			lecture.LastUpdate = now
+		}
+
+		r.lectures = append(r.lectures, lecture)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetLecturesThatHaveAtLeastOneDevice() (*[]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	lectureIds := []string{}
+
+	for _, deviceLecture := range r.deviceLectures {
+		if !seen[deviceLecture.LectureId] {
+			seen[deviceLecture.LectureId] = true
+			lectureIds = append(lectureIds, deviceLecture.LectureId)
+		}
+	}
+
+	return &lectureIds, nil
+}
+
+func (r *Repository) GetDeviceLectures() (*[]model.IOSDeviceLecture, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deviceLectures := append([]model.IOSDeviceLecture{}, r.deviceLectures...)
+
+	return &deviceLectures, nil
+}
+
+func (r *Repository) GetReadyDeviceLectures() (*[]model.IOSDeviceLecture, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ready := r.readyDeviceIds()
+	deviceLectures := []model.IOSDeviceLecture{}
+
+	for _, deviceLecture := range r.deviceLectures {
+		if ready[deviceLecture.DeviceId] {
+			deviceLectures = append(deviceLectures, deviceLecture)
+		}
+	}
+
+	return &deviceLectures, nil
+}
+
+func (r *Repository) GetDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return deviceLecturesAfter(r.deviceLectures, nil, after, limit), nil
+}
+
+func (r *Repository) GetReadyDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return deviceLecturesAfter(r.deviceLectures, r.readyDeviceIds(), after, limit), nil
+}
+
+// deviceLecturesAfter returns up to limit enrollments of deviceLectures
+// ordered by device and lecture that come after the enrollment after. Only
+// the enrollments of the devices in devices are returned, unless devices is
+// nil.
+func deviceLecturesAfter(
+	deviceLectures []model.IOSDeviceLecture,
+	devices map[string]bool,
+	after model.IOSDeviceLecture,
+	limit int,
+) *[]model.IOSDeviceLecture {
+	page := []model.IOSDeviceLecture{}
+
+	for _, deviceLecture := range deviceLectures {
+		if devices != nil && !devices[deviceLecture.DeviceId] {
+			continue
+		}
+
+		if !deviceLectureLess(after, deviceLecture) {
+			continue
+		}
+
+		page = append(page, model.IOSDeviceLecture{DeviceId: deviceLecture.DeviceId, LectureId: deviceLecture.LectureId})
+	}
+
+	sort.Slice(page, func(i, j int) bool {
+		return deviceLectureLess(page[i], page[j])
+	})
+
+	if len(page) > limit {
+		page = page[:limit]
+	}
+
+	return &page
+}
+
+func deviceLectureLess(a model.IOSDeviceLecture, b model.IOSDeviceLecture) bool {
+	if a.DeviceId != b.DeviceId {
+		return a.DeviceId < b.DeviceId
+	}
+
+	return a.LectureId < b.LectureId
+}
+
+func (r *Repository) CreateDeviceLectures(deviceLectures *[]model.IOSDeviceLecture) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.insertDeviceLectures(deviceLectures); err != nil {
+		return queryError("create device lectures", err)
+	}
+
+	return nil
+}
+
+// insertDeviceLectures checks the keys of all deviceLectures before inserting
+// any of them.
+func (r *Repository) insertDeviceLectures(deviceLectures *[]model.IOSDeviceLecture) error {
+	devices := r.deviceIdSet()
+	lectures := make(map[string]bool, len(r.lectures))
+
+	for _, lecture := range r.lectures {
+		lectures[lecture.Id] = true
+	}
+
+	existing := make(map[[2]string]bool, len(r.deviceLectures))
+
+	for _, deviceLecture := range r.deviceLectures {
+		existing[[2]string{deviceLecture.DeviceId, deviceLecture.LectureId}] = true
+	}
+
+	for _, deviceLecture := range *deviceLectures {
+		key := [2]string{deviceLecture.DeviceId, deviceLecture.LectureId}
+
+		if existing[key] {
+			return errDuplicateKey
+		}
+
+		if !devices[deviceLecture.DeviceId] || !lectures[deviceLecture.LectureId] {
+			return errForeignKey
+		}
+
+		existing[key] = true
+	}
+
+	for _, deviceLecture := range *deviceLectures {
+		r.deviceLectures = append(r.deviceLectures, model.IOSDeviceLecture{
+			DeviceId:  deviceLecture.DeviceId,
+			LectureId: deviceLecture.LectureId,
+		})
+	}
+
+	return nil
+}
+
+func (r *Repository) GetMaxAttendedLecturesCount() (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ready := r.readyDeviceIds()
+	counts := make(map[string]int)
+	maxCount := 0
+
+	for _, deviceLecture := range r.deviceLectures {
+		if !ready[deviceLecture.DeviceId] {
+			continue
+		}
+
+		counts[deviceLecture.DeviceId]++
+
+		if counts[deviceLecture.DeviceId] > maxCount {
+			maxCount = counts[deviceLecture.DeviceId]
+		}
+	}
+
+	return maxCount, nil
+}
+
+func (r *Repository) readyDeviceIds() map[string]bool {
+	ready := make(map[string]bool)
+
+	for _, device := range r.devices {
+		if device.State == model.IOSDeviceStateActive {
+			ready[device.DeviceID] = true
+		}
+	}
+
+	return ready
+}
+
+// copyLecture copies the lecture without its association, so that
+// LastRequestId isn't shared with the caller.
+func copyLecture(lecture model.IOSLecture) model.IOSLecture {
+	if lecture.LastRequestId != nil {
+		lastRequestId := *lecture.LastRequestId
+		lecture.LastRequestId = &lastRequestId
+	}
+
+	lecture.LastRequest = nil
+
+	return lecture
+}
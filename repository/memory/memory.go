@@ -0,0 +1,114 @@
+// Package memory implements the repository.Repository in memory. It has the
+// semantics of the gorm implementation including its primary keys, foreign
+// keys and cascades, so that the algorithm can run without a database.
+package memory
+
+import (
+	"errors"
+	"sync"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+)
+
+var (
+	errDuplicateKey = errors.New("duplicate primary key")
+	errForeignKey   = errors.New("foreign key constraint fails")
+	errNoRequest    = errors.New("no request with this id")
+)
+
+// Repository is safe for concurrent use. Every method works on its own
+// copies of the records.
+type Repository struct {
+	mutex sync.Mutex
+
+	devices        []model.IOSDevice
+	lectures       []model.IOSLecture
+	deviceLectures []model.IOSDeviceLecture
+	requestLogs    []model.IOSDeviceRequestLog
+	updateLogs     []model.IOSScheduledUpdateLog
+	priorities     []model.IOSSchedulingPriority
+	grades         []model.IOSEncryptedGrade
+
+	nextUpdateLogId uint32
+	nextPriorityId  int
+	nextGradeId     uint
+
+	// RequestExpiry is how long the requests created by the repository can
+	// be answered.
+	RequestExpiry model.IOSRequestExpiry
+}
+
+func New() *Repository {
+	return &Repository{
+		RequestExpiry:   model.DefaultIOSRequestExpiry(),
+		nextUpdateLogId: 1,
+		nextPriorityId:  1,
+		nextGradeId:     1,
+	}
+}
+
+func queryError(op string, err error) error {
+	return repository.QueryError(op, err)
+}
+
+func (r *Repository) deviceIndex(deviceId string) int {
+	for i := range r.devices {
+		if r.devices[i].DeviceID == deviceId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *Repository) lectureIndex(lectureId string) int {
+	for i := range r.lectures {
+		if r.lectures[i].Id == lectureId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *Repository) requestLogIndex(requestId string) int {
+	for i := range r.requestLogs {
+		if r.requestLogs[i].RequestID == requestId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *Repository) deviceIdSet() map[string]bool {
+	deviceIds := make(map[string]bool, len(r.devices))
+
+	for _, device := range r.devices {
+		deviceIds[device.DeviceID] = true
+	}
+
+	return deviceIds
+}
+
+func toSet(values *[]string) map[string]bool {
+	set := make(map[string]bool, len(*values))
+
+	for _, value := range *values {
+		set[value] = true
+	}
+
+	return set
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ repository.Repository = (*Repository)(nil)
@@ -0,0 +1,311 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+func (r *Repository) GetRequestLogsSince(since time.Time) (*[]model.IOSDeviceRequestLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	requestLogs := []model.IOSDeviceRequestLog{}
+
+	for _, requestLog := range r.requestLogs {
+		if !requestLog.CreatedAt.Before(since) {
+			requestLogs = append(requestLogs, requestLog)
+		}
+	}
+
+	sort.SliceStable(requestLogs, func(i, j int) bool {
+		return requestLogs[i].CreatedAt.After(requestLogs[j].CreatedAt)
+	})
+
+	return &requestLogs, nil
+}
+
+func (r *Repository) GetHandledRequestsDevices(requestIds *[]string) (*[]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := toSet(requestIds)
+	deviceIds := []string{}
+
+	for _, requestLog := range r.requestLogs {
+		if wanted[requestLog.RequestID] && requestLog.HandledAt.Valid {
+			deviceIds = append(deviceIds, requestLog.DeviceID)
+		}
+	}
+
+	return &deviceIds, nil
+}
+
+func (r *Repository) CreateRequestLogs(requestLogs *[]model.IOSDeviceRequestLog) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range *requestLogs {
+		if (*requestLogs)[i].RequestID == "" {
+			(*requestLogs)[i].RequestID = model.NewRequestID()
+		}
+	}
+
+	if err := r.insertRequestLogs(requestLogs); err != nil {
+		return queryError("create request logs", err)
+	}
+
+	return nil
+}
+
+// insertRequestLogs checks the keys of all requestLogs before inserting any
+// of them.
+func (r *Repository) insertRequestLogs(requestLogs *[]model.IOSDeviceRequestLog) error {
+	devices := r.deviceIdSet()
+	existing := make(map[string]bool, len(r.requestLogs))
+
+	for _, requestLog := range r.requestLogs {
+		existing[requestLog.RequestID] = true
+	}
+
+	for _, requestLog := range *requestLogs {
+		if existing[requestLog.RequestID] {
+			return errDuplicateKey
+		}
+
+		if !devices[requestLog.DeviceID] {
+			return errForeignKey
+		}
+
+		existing[requestLog.RequestID] = true
+	}
+
+	now := time.Now()
+
+	for _, requestLog := range *requestLogs {
+		requestLog.Device = model.IOSDevice{}
+
+		if requestLog.Status == "" {
+			requestLog.Status = model.IOSRequestStatusCreated
+		}
+
+		if requestLog.CreatedAt.IsZero() {
+			requestLog.CreatedAt = now
+		}
+
+		r.requestLogs = append(r.requestLogs, requestLog)
+	}
+
+	return nil
+}
+
+// supersede moves the open requests of the same type to the devices of
+// requestLogs to superseded and points the lectures of the superseded
+// requests to the new request of their device.
+func (r *Repository) supersede(requestLogs *[]model.IOSDeviceRequestLog) {
+	replacements := make(map[string]*model.IOSDeviceRequestLog, len(*requestLogs))
+
+	for i := range *requestLogs {
+		replacements[(*requestLogs)[i].DeviceID] = &(*requestLogs)[i]
+	}
+
+	replaced := make(map[string]string)
+
+	for i := range r.requestLogs {
+		requestLog := &r.requestLogs[i]
+		replacement, ok := replacements[requestLog.DeviceID]
+
+		if ok && requestLog.RequestType == replacement.RequestType && contains(model.IOSOpenRequestStatuses, requestLog.Status) {
+			requestLog.Status = model.IOSRequestStatusSuperseded
+			replaced[requestLog.RequestID] = replacement.RequestID
+		}
+	}
+
+	for i := range r.lectures {
+		if r.lectures[i].LastRequestId == nil {
+			continue
+		}
+
+		if requestId, ok := replaced[*r.lectures[i].LastRequestId]; ok {
+			r.lectures[i].LastRequestId = &requestId
+		}
+	}
+}
+
+func (r *Repository) CreateLectureUpdateRequests(lectureIdsByDevice map[string][]string) (*[]model.IOSDeviceRequestLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var requestLogs []model.IOSDeviceRequestLog
+
+	now := time.Now()
+	devices := r.deviceIdSet()
+
+	for deviceId := range lectureIdsByDevice {
+		if !devices[deviceId] {
+			return nil, queryError("create lecture update requests", errForeignKey)
+		}
+
+		requestLogs = append(requestLogs, r.RequestExpiry.NewRequestLog(deviceId, model.IOSLectureUpdateRequestType, now))
+	}
+
+	if len(requestLogs) == 0 {
+		return &requestLogs, nil
+	}
+
+	r.supersede(&requestLogs)
+
+	if err := r.insertRequestLogs(&requestLogs); err != nil {
+		return nil, queryError("create lecture update requests", err)
+	}
+
+	for _, requestLog := range requestLogs {
+		lectureIds := lectureIdsByDevice[requestLog.DeviceID]
+		covered := toSet(&lectureIds)
+
+		for i := range r.lectures {
+			if covered[r.lectures[i].Id] {
+				requestId := requestLog.RequestID
+				r.lectures[i].LastRequestId = &requestId
+			}
+		}
+	}
+
+	return &requestLogs, nil
+}
+
+func (r *Repository) HandleRequest(
+	requestId string,
+	handledAt time.Time,
+	validate func(requestLog *model.IOSDeviceRequestLog) error,
+) (*model.IOSDeviceRequestLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.requestLogIndex(requestId)
+
+	if i < 0 {
+		return nil, &repository.Error{Kind: repository.ErrNotFound, Op: "handle request", Err: errNoRequest}
+	}
+
+	requestLog := r.requestLogs[i]
+
+	if err := validate(&requestLog); err != nil {
+		return nil, err
+	}
+
+	if err := requestLog.TransitionTo(model.IOSRequestStatusHandled); err != nil {
+		return nil, err
+	}
+
+	requestLog.HandledAt = sql.NullTime{Time: handledAt, Valid: true}
+
+	// Only the columns written by the gorm implementation are taken over
+	// from the copy validate may have changed.
+	r.requestLogs[i].HandledAt = requestLog.HandledAt
+	r.requestLogs[i].Status = requestLog.Status
+
+	if updateType := model.IOSUpdateTypeOf(requestLog.RequestType); updateType != "" {
+		if j := r.updateLogIndex(requestLog.DeviceID, updateType); j >= 0 {
+			r.updateLogs[j].CreatedAt = handledAt
+		} else {
+			r.insertUpdateLog(requestLog.DeviceID, updateType, handledAt)
+		}
+	}
+
+	if requestLog.RequestType == model.IOSLectureUpdateRequestType {
+		enrolled := make(map[string]bool)
+
+		for _, deviceLecture := range r.deviceLectures {
+			if deviceLecture.DeviceId == requestLog.DeviceID {
+				enrolled[deviceLecture.LectureId] = true
+			}
+		}
+
+		for j := range r.lectures {
+			lecture := &r.lectures[j]
+
+			if enrolled[lecture.Id] || (lecture.LastRequestId != nil && *lecture.LastRequestId == requestId) {
+				lecture.LastUpdate = handledAt
+			}
+		}
+	}
+
+	return &requestLog, nil
+}
+
+func (r *Repository) MarkRequestsAttempted(requestIds *[]string, status string) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := toSet(requestIds)
+	from := model.IOSRequestStatusesTransitioningTo(status)
+
+	var marked int64
+
+	for i := range r.requestLogs {
+		requestLog := &r.requestLogs[i]
+
+		if wanted[requestLog.RequestID] && contains(from, requestLog.Status) {
+			requestLog.Status = status
+			requestLog.Attempts++
+			marked++
+		}
+	}
+
+	return marked, nil
+}
+
+func (r *Repository) GetRetryableRequests(maxAttempts int, now time.Time) (*[]model.IOSDeviceRequestLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	requestLogs := []model.IOSDeviceRequestLog{}
+
+	for _, requestLog := range r.requestLogs {
+		if requestLog.Status == model.IOSRequestStatusDeliveryFailed &&
+			requestLog.Attempts < maxAttempts &&
+			requestLog.ExpiresAt.Valid &&
+			requestLog.ExpiresAt.Time.After(now) {
+			requestLogs = append(requestLogs, requestLog)
+		}
+	}
+
+	return &requestLogs, nil
+}
+
+func (r *Repository) ExpireRequests(now time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	expired := make(map[string]bool)
+
+	for i := range r.requestLogs {
+		requestLog := &r.requestLogs[i]
+
+		if !contains(model.IOSOpenRequestStatuses, requestLog.Status) {
+			continue
+		}
+
+		stale := requestLog.ExpiresAt.Valid && requestLog.ExpiresAt.Time.Before(now)
+
+		if expiry, ok := r.RequestExpiry[requestLog.RequestType]; ok && !requestLog.ExpiresAt.Valid {
+			stale = requestLog.CreatedAt.Before(now.Add(-expiry))
+		}
+
+		if stale {
+			requestLog.Status = model.IOSRequestStatusExpired
+			expired[requestLog.RequestID] = true
+		}
+	}
+
+	for i := range r.lectures {
+		if r.lectures[i].LastRequestId != nil && expired[*r.lectures[i].LastRequestId] {
+			r.lectures[i].LastRequestId = nil
+		}
+	}
+
+	return int64(len(expired)), nil
+}
@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+func (r *Repository) GetSchedulingPriorities() (*[]model.IOSSchedulingPriority, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	priorities := append([]model.IOSSchedulingPriority{}, r.priorities...)
+
+	return &priorities, nil
+}
+
+func (r *Repository) CreateSchedulingPriorities(priorities *[]model.IOSSchedulingPriority) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.insertSchedulingPriorities(priorities); err != nil {
+		return queryError("create scheduling priorities", err)
+	}
+
+	return nil
+}
+
+// insertSchedulingPriorities checks the keys of all priorities before
+// inserting any of them and sets the IDs of the new ones.
+func (r *Repository) insertSchedulingPriorities(priorities *[]model.IOSSchedulingPriority) error {
+	existing := make(map[int]bool, len(r.priorities))
+
+	for _, priority := range r.priorities {
+		existing[priority.ID] = true
+	}
+
+	for _, priority := range *priorities {
+		if priority.ID != 0 && existing[priority.ID] {
+			return errDuplicateKey
+		}
+
+		existing[priority.ID] = true
+	}
+
+	for i := range *priorities {
+		priority := &(*priorities)[i]
+
+		if priority.ID == 0 {
+			priority.ID = r.nextPriorityId
+		}
+
+		if priority.ID >= r.nextPriorityId {
+			r.nextPriorityId = priority.ID + 1
+		}
+
+		r.priorities = append(r.priorities, *priority)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetScheduledUpdateLogs(updateType string) (*[]model.IOSScheduledUpdateLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	updateLogs := []model.IOSScheduledUpdateLog{}
+
+	for _, updateLog := range r.updateLogs {
+		if updateLog.Type == updateType {
+			updateLogs = append(updateLogs, updateLog)
+		}
+	}
+
+	return &updateLogs, nil
+}
+
+// updateLogIndex returns the index of the log of the device and type, -1 if
+// there is none.
+func (r *Repository) updateLogIndex(deviceId string, updateType string) int {
+	for i := range r.updateLogs {
+		if r.updateLogs[i].DeviceID == deviceId && r.updateLogs[i].Type == updateType {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *Repository) insertUpdateLog(deviceId string, updateType string, createdAt time.Time) {
+	r.updateLogs = append(r.updateLogs, model.IOSScheduledUpdateLog{
+		ID:        r.nextUpdateLogId,
+		DeviceID:  deviceId,
+		Type:      updateType,
+		CreatedAt: createdAt,
+	})
+
+	r.nextUpdateLogId++
+}
+
+func (r *Repository) RecordScheduledUpdates(deviceIds *[]string, updateType string, createdAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	devices := r.deviceIdSet()
+
+	for _, deviceId := range *deviceIds {
+		if !devices[deviceId] {
+			return queryError("record scheduled updates", errForeignKey)
+		}
+	}
+
+	for _, deviceId := range *deviceIds {
+		if i := r.updateLogIndex(deviceId, updateType); i >= 0 {
+			r.updateLogs[i].CreatedAt = createdAt
+			continue
+		}
+
+		r.insertUpdateLog(deviceId, updateType, createdAt)
+	}
+
+	return nil
+}
+
+func (r *Repository) RecordScheduledRun(updateType string, createdAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.insertUpdateLog("", updateType, createdAt)
+
+	return nil
+}
+
+func (r *Repository) GetDevicesDueForUpdate(
+	updateType string,
+	requestType string,
+	before time.Time,
+	at time.Time,
+	limit int,
+) (*[]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	pending := r.pendingDevices(requestType, at)
+
+	type due struct {
+		deviceId  string
+		updated   bool
+		createdAt time.Time
+	}
+
+	var candidates []due
+
+	for _, device := range r.devices {
+		if device.State != model.IOSDeviceStateActive || pending[device.DeviceID] {
+			continue
+		}
+
+		i := r.updateLogIndex(device.DeviceID, updateType)
+
+		if i < 0 {
+			candidates = append(candidates, due{deviceId: device.DeviceID})
+			continue
+		}
+
+		if r.updateLogs[i].CreatedAt.Before(before) {
+			candidates = append(candidates, due{deviceId: device.DeviceID, updated: true, createdAt: r.updateLogs[i].CreatedAt})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].updated != candidates[j].updated {
+			return !candidates[i].updated
+		}
+
+		return candidates[i].createdAt.Before(candidates[j].createdAt)
+	})
+
+	if limit >= 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	deviceIds := make([]string, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		deviceIds = append(deviceIds, candidate.deviceId)
+	}
+
+	return &deviceIds, nil
+}
+
+func (r *Repository) CreateScheduledRequests(
+	deviceIds *[]string,
+	updateType string,
+	requestType string,
+	before time.Time,
+	at time.Time,
+) (*[]model.IOSDeviceRequestLog, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	skipped := r.pendingDevices(requestType, at)
+
+	for _, updateLog := range r.updateLogs {
+		if updateLog.Type == updateType && !updateLog.CreatedAt.Before(before) {
+			skipped[updateLog.DeviceID] = true
+		}
+	}
+
+	requestLogs := []model.IOSDeviceRequestLog{}
+
+	for _, deviceId := range *deviceIds {
+		if !skipped[deviceId] {
+			skipped[deviceId] = true
+			requestLogs = append(requestLogs, r.RequestExpiry.NewRequestLog(deviceId, requestType, at))
+		}
+	}
+
+	if len(requestLogs) == 0 {
+		return &requestLogs, nil
+	}
+
+	r.supersede(&requestLogs)
+
+	if err := r.insertRequestLogs(&requestLogs); err != nil {
+		return nil, queryError("create scheduled requests", err)
+	}
+
+	return &requestLogs, nil
+}
+
+// pendingDevices returns the devices with an open request of requestType
+// that has not expired at at.
+func (r *Repository) pendingDevices(requestType string, at time.Time) map[string]bool {
+	pending := make(map[string]bool)
+
+	for _, requestLog := range r.requestLogs {
+		if requestLog.RequestType == requestType && contains(model.IOSOpenRequestStatuses, requestLog.Status) && requestLog.ExpiresAt.Valid && requestLog.ExpiresAt.Time.After(at) {
+			pending[requestLog.DeviceID] = true
+		}
+	}
+
+	return pending
+}
@@ -0,0 +1,189 @@
+// Package repository defines how the algorithm reads and writes its data.
+// The gorm implementation lives in the db package, an in-memory
+// implementation with the same semantics in repository/memory. Both are
+// checked against each other by repository/repotest.
+//
+// Errors returned by the implementations are of type *Error, except for the
+// errors of the validate callback of HandleRequest which are returned as
+// is.
+package repository
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+// Devices stores the IOSDevice records.
+type Devices interface {
+	GetDevices() (*[]model.IOSDevice, error)
+	GetDevicesByIds(deviceIds *[]string) (*[]model.IOSDevice, error)
+	// GetReadyDevices returns the devices that can be selected, i.e. the
+	// ones in state active.
+	GetReadyDevices() (*[]model.IOSDevice, error)
+	// GetDevicesInState returns the devices in state whose state changed
+	// before changedBefore or was never changed.
+	GetDevicesInState(state string, changedBefore time.Time) (*[]model.IOSDevice, error)
+	// CreateDevices inserts the devices. Devices without State are active,
+	// devices without CreatedAt are created now.
+	CreateDevices(devices *[]model.IOSDevice) error
+	// SetDevicesState moves the devices to state and returns the number of
+	// devices that changed. Devices that already are in state keep their
+	// StateChangedAt.
+	SetDevicesState(deviceIds *[]string, state string, changedAt time.Time) (int64, error)
+	// SetDevicesStateFrom moves the devices that are in state from to state
+	// to and returns the number of devices that changed.
+	SetDevicesStateFrom(deviceIds *[]string, from string, to string, changedAt time.Time) (int64, error)
+	// PurgeUnregisteredDevices deletes the devices that are unregistered
+	// since before together with their enrollments, request logs, grades
+	// and scheduled update logs.
+	PurgeUnregisteredDevices(before time.Time) (int64, error)
+	// UpdateActivityCounters sets the activity counters of every device to
+	// the number of its requests handled since the start of the day, week,
+	// month and year.
+	UpdateActivityCounters(day time.Time, week time.Time, month time.Time, year time.Time) (int64, error)
+}
+
+// Lectures stores the IOSLecture records.
+type Lectures interface {
+	GetLectures() (*[]model.IOSLecture, error)
+	// CreateLectures inserts the lectures. Lectures without LastUpdate are
+	// updated now.
+	CreateLectures(lectures *[]model.IOSLecture) error
+	GetLecturesThatHaveAtLeastOneDevice() (*[]string, error)
+}
+
+// Enrollments stores which device attends which lecture.
+type Enrollments interface {
+	GetDeviceLectures() (*[]model.IOSDeviceLecture, error)
+	// GetReadyDeviceLectures returns the enrollments of the devices returned
+	// by GetReadyDevices.
+	GetReadyDeviceLectures() (*[]model.IOSDeviceLecture, error)
+	// GetDeviceLecturesAfter returns up to limit enrollments ordered by
+	// device and lecture that come after the enrollment after. The zero
+	// IOSDeviceLecture starts at the first enrollment. Paging through the
+	// enrollments this way reads each of them once, but not from a single
+	// snapshot.
+	GetDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error)
+	// GetReadyDeviceLecturesAfter pages through the enrollments returned by
+	// GetReadyDeviceLectures like GetDeviceLecturesAfter.
+	GetReadyDeviceLecturesAfter(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error)
+	// CreateDeviceLectures inserts the enrollments. The device and the
+	// lecture of every enrollment have to exist.
+	CreateDeviceLectures(deviceLectures *[]model.IOSDeviceLecture) error
+	// GetMaxAttendedLecturesCount returns the number of lectures of the
+	// ready device with the most enrollments, zero if there is none.
+	GetMaxAttendedLecturesCount() (int, error)
+}
+
+// RequestLogs stores the IOSDeviceRequestLog records.
+type RequestLogs interface {
+	// GetRequestLogsSince returns all requests created at or after since,
+	// the newest first.
+	GetRequestLogsSince(since time.Time) (*[]model.IOSDeviceRequestLog, error)
+	// GetHandledRequestsDevices returns the devices of the requests out of
+	// requestIds that were already answered.
+	GetHandledRequestsDevices(requestIds *[]string) (*[]string, error)
+	// CreateRequestLogs inserts the requests. The device of every request
+	// has to exist. Requests without RequestID get a new one.
+	CreateRequestLogs(requestLogs *[]model.IOSDeviceRequestLog) error
+	// CreateLectureUpdateRequests creates a LECTURE_UPDATE_REQUEST for every
+	// device of lectureIdsByDevice, supersedes the open ones of the devices
+	// and points IOSLecture.LastRequestId of the lectures to the request of
+	// the device that covers them. Lectures of a superseded request point to
+	// the new request of its device. Either everything or nothing is
+	// written. The requests created by the repository expire after its
+	// configured model.IOSRequestExpiry.
+	CreateLectureUpdateRequests(lectureIdsByDevice map[string][]string) (*[]model.IOSDeviceRequestLog, error)
+	// HandleRequest marks the request as handled at handledAt after validate
+	// accepted it. For a LECTURE_UPDATE_REQUEST the LastUpdate of the
+	// lectures the request is responsible for and of all lectures the
+	// device is enrolled in is set to handledAt as well. For a request
+	// refreshing an update type, see model.IOSUpdateTypeOf, the log of the
+	// device is moved to handledAt.
+	//
+	// An error of kind ErrNotFound is returned if the request does not
+	// exist and the error of validate is returned as is.
+	HandleRequest(
+		requestId string,
+		handledAt time.Time,
+		validate func(requestLog *model.IOSDeviceRequestLog) error,
+	) (*model.IOSDeviceRequestLog, error)
+	// MarkRequestsAttempted moves the requests to status (sent or
+	// delivery_failed) and counts the delivery attempt. Requests that can't
+	// move to status are left untouched.
+	MarkRequestsAttempted(requestIds *[]string, status string) (int64, error)
+	// GetRetryableRequests returns the requests whose delivery failed less
+	// than maxAttempts times and that did not expire yet at now.
+	GetRetryableRequests(maxAttempts int, now time.Time) (*[]model.IOSDeviceRequestLog, error)
+	// ExpireRequests moves all open requests that expired before now to
+	// expired and clears IOSLecture.LastRequestId of the lectures pointing
+	// at them. Requests without ExpiresAt expire after the configured
+	// model.IOSRequestExpiry of their type.
+	ExpireRequests(now time.Time) (int64, error)
+}
+
+// ScheduledUpdateLogs stores when a device was updated last.
+type ScheduledUpdateLogs interface {
+	GetScheduledUpdateLogs(updateType string) (*[]model.IOSScheduledUpdateLog, error)
+	// RecordScheduledUpdates logs that the devices were updated at
+	// createdAt. An existing log of the same device and type is moved to
+	// createdAt.
+	RecordScheduledUpdates(deviceIds *[]string, updateType string, createdAt time.Time) error
+	// RecordScheduledRun logs a run of the scheduler refreshing updateType
+	// at createdAt. The log of a run has no device.
+	RecordScheduledRun(updateType string, createdAt time.Time) error
+	// GetDevicesDueForUpdate returns up to limit active devices whose last
+	// update of updateType was before before and that have no pending
+	// request of requestType at at, the ones never updated first and then
+	// the longest ago. A device that does not answer is therefore due again
+	// once its request expired.
+	GetDevicesDueForUpdate(
+		updateType string,
+		requestType string,
+		before time.Time,
+		at time.Time,
+		limit int,
+	) (*[]string, error)
+	// CreateScheduledRequests creates a request of requestType at at for
+	// every device whose last update of updateType is before before and
+	// that has no pending request of requestType, i.e. an open one that
+	// has not expired at at. The log of the update is only moved when the
+	// device answers, see HandleRequest, so a device that does not answer
+	// is planned again once its request expired. Devices claimed
+	// concurrently are skipped. Expired open requests of requestType to
+	// the claimed devices are superseded, their lectures point to the new
+	// request of the device.
+	CreateScheduledRequests(
+		deviceIds *[]string,
+		updateType string,
+		requestType string,
+		before time.Time,
+		at time.Time,
+	) (*[]model.IOSDeviceRequestLog, error)
+}
+
+// SchedulingPriorities stores the IOSSchedulingPriority records.
+type SchedulingPriorities interface {
+	GetSchedulingPriorities() (*[]model.IOSSchedulingPriority, error)
+	// CreateSchedulingPriorities inserts the priorities and sets their ID.
+	CreateSchedulingPriorities(priorities *[]model.IOSSchedulingPriority) error
+}
+
+// Grades stores the IOSEncryptedGrade records.
+type Grades interface {
+	GetGradesByDevice(deviceId string) (*[]model.IOSEncryptedGrade, error)
+	// CreateGrades inserts the grades and sets their ID. The device of every
+	// grade has to exist.
+	CreateGrades(grades *[]model.IOSEncryptedGrade) error
+}
+
+type Repository interface {
+	Devices
+	Lectures
+	Enrollments
+	RequestLogs
+	ScheduledUpdateLogs
+	SchedulingPriorities
+	Grades
+	BulkLoader
+}
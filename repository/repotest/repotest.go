@@ -0,0 +1,1221 @@
+// Package repotest checks that an implementation of repository.Repository
+// has the semantics the algorithm relies on. Every implementation has to
+// pass Check, which is how the gorm and the in-memory implementation are
+// kept identical.
+package repotest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+var errRejected = errors.New("rejected by validate")
+
+type checker struct {
+	repo repository.Repository
+	now  time.Time
+	errs []string
+}
+
+// Check runs the conformance checks against repo and returns an error
+// listing every failed check. The checks write to repo, so it has to be an
+// empty repository created for the check.
+func Check(repo repository.Repository) error {
+	c := &checker{
+		repo: repo,
+		now:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	devices, err := repo.GetDevices()
+
+	if err != nil {
+		return err
+	}
+
+	if len(*devices) > 0 {
+		return fmt.Errorf("repository is not empty: %d devices", len(*devices))
+	}
+
+	checks := []struct {
+		name string
+		run  func()
+	}{
+		{"devices", c.checkDevices},
+		{"lectures", c.checkLectures},
+		{"enrollments", c.checkEnrollments},
+		{"lecture update requests", c.checkLectureUpdateRequests},
+		{"delivery attempts", c.checkDeliveryAttempts},
+		{"expiry", c.checkExpiry},
+		{"scheduled updates", c.checkScheduledUpdates},
+		{"scheduling priorities", c.checkSchedulingPriorities},
+		{"grades", c.checkGrades},
+		{"activity counters", c.checkActivityCounters},
+		{"purge", c.checkPurge},
+		{"bulk load", c.checkBulkLoad},
+	}
+
+	for _, check := range checks {
+		failed := len(c.errs)
+
+		check.run()
+
+		for i := failed; i < len(c.errs); i++ {
+			c.errs[i] = check.name + ": " + c.errs[i]
+		}
+	}
+
+	if len(c.errs) > 0 {
+		return fmt.Errorf("%d checks failed:\n%s", len(c.errs), strings.Join(c.errs, "\n"))
+	}
+
+	return nil
+}
+
+// CheckRequestExpiry checks that repo creates and expires the requests
+// after expiry. repo has to be an empty repository configured with expiry.
+func CheckRequestExpiry(repo repository.Repository, expiry model.IOSRequestExpiry) error {
+	c := &checker{
+		repo: repo,
+		now:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	c.checkRequestExpiry(expiry)
+
+	if len(c.errs) > 0 {
+		return fmt.Errorf("%d checks failed:\n%s", len(c.errs), strings.Join(c.errs, "\n"))
+	}
+
+	return nil
+}
+
+func (c *checker) checkRequestExpiry(expiry model.IOSRequestExpiry) {
+	if !c.createDevices("device-a", "device-b") {
+		return
+	}
+
+	lectureRequests, err := c.repo.CreateLectureUpdateRequests(map[string][]string{"device-a": {}})
+
+	if !c.ok("create lecture update requests", err) {
+		return
+	}
+
+	tokenRequests, err := c.repo.CreateScheduledRequests(&[]string{"device-b"}, model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now, c.now)
+
+	if !c.ok("create scheduled requests", err) {
+		return
+	}
+
+	for _, request := range append(*lectureRequests, *tokenRequests...) {
+		if got := request.ExpiresAt.Time.Sub(request.CreatedAt); !request.ExpiresAt.Valid || got != expiry[request.RequestType] {
+			c.errorf("%s expires after %s instead of %s", request.RequestType, got, expiry[request.RequestType])
+		}
+	}
+
+	// Requests without ExpiresAt expire after the configured expiry as
+	// well.
+	legacy := []model.IOSDeviceRequestLog{
+		{
+			RequestID:   model.NewRequestID(),
+			DeviceID:    "device-a",
+			RequestType: model.IOSLectureUpdateRequestType,
+			CreatedAt:   c.now.Add(-expiry[model.IOSLectureUpdateRequestType] - time.Minute),
+		},
+		{
+			RequestID:   model.NewRequestID(),
+			DeviceID:    "device-b",
+			RequestType: model.IOSTokenRequestType,
+			CreatedAt:   c.now.Add(-expiry[model.IOSTokenRequestType] + time.Minute),
+		},
+	}
+
+	if !c.ok("create request logs", c.repo.CreateRequestLogs(&legacy)) {
+		return
+	}
+
+	expired, err := c.repo.ExpireRequests(c.now)
+
+	if c.ok("expire requests", err) && expired != 1 {
+		c.errorf("expired %d instead of 1 request without ExpiresAt", expired)
+	}
+
+	c.expectStatus(legacy[0].RequestID, model.IOSRequestStatusExpired)
+	c.expectStatus(legacy[1].RequestID, model.IOSRequestStatusCreated)
+}
+
+func (c *checker) errorf(format string, args ...interface{}) {
+	c.errs = append(c.errs, fmt.Sprintf(format, args...))
+}
+
+// ok records err and returns false if it is not nil.
+func (c *checker) ok(op string, err error) bool {
+	if err != nil {
+		c.errorf("%s: unexpected error: %s", op, err)
+		return false
+	}
+
+	return true
+}
+
+// failsWith records an error unless err is of kind.
+func (c *checker) failsWith(op string, err error, kind error) {
+	if !errors.Is(err, kind) {
+		c.errorf("%s: expected error of kind %q, got %v", op, kind, err)
+	}
+}
+
+func (c *checker) expectStrings(op string, got *[]string, want ...string) {
+	sorted := append([]string{}, *got...)
+	sort.Strings(sorted)
+	sort.Strings(want)
+
+	if strings.Join(sorted, ",") != strings.Join(want, ",") {
+		c.errorf("%s: expected %v, got %v", op, want, sorted)
+	}
+}
+
+func deviceIds(devices *[]model.IOSDevice) *[]string {
+	ids := make([]string, 0, len(*devices))
+
+	for _, device := range *devices {
+		ids = append(ids, device.DeviceID)
+	}
+
+	return &ids
+}
+
+func (c *checker) createDevices(ids ...string) bool {
+	devices := make([]model.IOSDevice, 0, len(ids))
+
+	for _, id := range ids {
+		devices = append(devices, model.IOSDevice{DeviceID: id, PublicKey: "key-" + id})
+	}
+
+	return c.ok("create devices", c.repo.CreateDevices(&devices))
+}
+
+func (c *checker) createLectures(ids ...string) bool {
+	lectures := make([]model.IOSLecture, 0, len(ids))
+
+	for _, id := range ids {
+		lectures = append(lectures, model.IOSLecture{
+			Id:         id,
+			Year:       2023,
+			Semester:   model.IOSLectureSemesterWinter,
+			LastUpdate: c.now.Add(-24 * time.Hour),
+		})
+	}
+
+	return c.ok("create lectures", c.repo.CreateLectures(&lectures))
+}
+
+func (c *checker) enroll(deviceId string, lectureIds ...string) bool {
+	deviceLectures := make([]model.IOSDeviceLecture, 0, len(lectureIds))
+
+	for _, lectureId := range lectureIds {
+		deviceLectures = append(deviceLectures, model.IOSDeviceLecture{DeviceId: deviceId, LectureId: lectureId})
+	}
+
+	return c.ok("create device lectures", c.repo.CreateDeviceLectures(&deviceLectures))
+}
+
+func (c *checker) device(id string) *model.IOSDevice {
+	devices, err := c.repo.GetDevicesByIds(&[]string{id})
+
+	if !c.ok("get devices by ids", err) || len(*devices) != 1 {
+		c.errorf("device %s not found", id)
+		return nil
+	}
+
+	return &(*devices)[0]
+}
+
+func (c *checker) lecture(id string) *model.IOSLecture {
+	lectures, err := c.repo.GetLectures()
+
+	if !c.ok("get lectures", err) {
+		return nil
+	}
+
+	for _, lecture := range *lectures {
+		if lecture.Id == id {
+			return &lecture
+		}
+	}
+
+	c.errorf("lecture %s not found", id)
+
+	return nil
+}
+
+// requestLog returns the request with requestId out of all requests.
+func (c *checker) requestLog(requestId string) *model.IOSDeviceRequestLog {
+	requestLogs, err := c.repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if !c.ok("get request logs since", err) {
+		return nil
+	}
+
+	for _, requestLog := range *requestLogs {
+		if requestLog.RequestID == requestId {
+			return &requestLog
+		}
+	}
+
+	c.errorf("request %s not found", requestId)
+
+	return nil
+}
+
+// updateLog returns the log of updateType of the device.
+func (c *checker) updateLog(deviceId string, updateType string) *model.IOSScheduledUpdateLog {
+	updateLogs, err := c.repo.GetScheduledUpdateLogs(updateType)
+
+	if !c.ok("get scheduled update logs", err) {
+		return nil
+	}
+
+	for _, updateLog := range *updateLogs {
+		if updateLog.DeviceID == deviceId {
+			return &updateLog
+		}
+	}
+
+	c.errorf("no %s log of %s", updateType, deviceId)
+
+	return nil
+}
+
+// requestsOfType returns all requests of requestType.
+func (c *checker) requestsOfType(requestType string) *[]model.IOSDeviceRequestLog {
+	var requests []model.IOSDeviceRequestLog
+
+	requestLogs, err := c.repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if c.ok("get request logs since", err) {
+		for _, requestLog := range *requestLogs {
+			if requestLog.RequestType == requestType {
+				requests = append(requests, requestLog)
+			}
+		}
+	}
+
+	return &requests
+}
+
+func (c *checker) expectStatus(requestId string, status string) {
+	if requestLog := c.requestLog(requestId); requestLog != nil && requestLog.Status != status {
+		c.errorf("request %s: expected status %s, got %s", requestId, status, requestLog.Status)
+	}
+}
+
+func (c *checker) checkDevices() {
+	if !c.createDevices("device-a", "device-b", "device-c") {
+		return
+	}
+
+	devices, err := c.repo.GetDevices()
+
+	if c.ok("get devices", err) {
+		c.expectStrings("get devices", deviceIds(devices), "device-a", "device-b", "device-c")
+
+		for _, device := range *devices {
+			if device.State != model.IOSDeviceStateActive {
+				c.errorf("new device %s is %q instead of active", device.DeviceID, device.State)
+			}
+
+			if device.CreatedAt.IsZero() {
+				c.errorf("new device %s has no CreatedAt", device.DeviceID)
+			}
+		}
+	}
+
+	c.failsWith("create duplicate device", c.repo.CreateDevices(&[]model.IOSDevice{{DeviceID: "device-a", PublicKey: "key"}}), repository.ErrQuery)
+
+	devices, err = c.repo.GetDevicesByIds(&[]string{"device-a", "device-c", "unknown"})
+
+	if c.ok("get devices by ids", err) {
+		c.expectStrings("get devices by ids", deviceIds(devices), "device-a", "device-c")
+	}
+
+	devices, err = c.repo.GetDevicesByIds(&[]string{})
+
+	if c.ok("get devices by no ids", err) && len(*devices) != 0 {
+		c.errorf("get devices by no ids returned %d devices", len(*devices))
+	}
+
+	changed, err := c.repo.SetDevicesState(&[]string{"device-b", "device-c"}, model.IOSDeviceStateSuspect, c.now)
+
+	if c.ok("set devices state", err) && changed != 2 {
+		c.errorf("set devices state changed %d instead of 2 devices", changed)
+	}
+
+	changed, err = c.repo.SetDevicesState(&[]string{"device-b"}, model.IOSDeviceStateSuspect, c.now.Add(time.Hour))
+
+	if c.ok("set devices state again", err) && changed != 0 {
+		c.errorf("set devices state to the same state changed %d devices", changed)
+	}
+
+	if device := c.device("device-b"); device != nil && (!device.StateChangedAt.Valid || !device.StateChangedAt.Time.Equal(c.now)) {
+		c.errorf("device-b changed state at %v instead of %s", device.StateChangedAt, c.now)
+	}
+
+	changed, err = c.repo.SetDevicesStateFrom(&[]string{"device-a", "device-c"}, model.IOSDeviceStateSuspect, model.IOSDeviceStateActive, c.now)
+
+	if c.ok("set devices state from", err) && changed != 1 {
+		c.errorf("set devices state from suspect changed %d instead of 1 device", changed)
+	}
+
+	devices, err = c.repo.GetReadyDevices()
+
+	if c.ok("get ready devices", err) {
+		c.expectStrings("get ready devices", deviceIds(devices), "device-a", "device-c")
+	}
+
+	devices, err = c.repo.GetDevicesInState(model.IOSDeviceStateSuspect, c.now.Add(time.Minute))
+
+	if c.ok("get devices in state", err) {
+		c.expectStrings("get devices in state", deviceIds(devices), "device-b")
+	}
+
+	devices, err = c.repo.GetDevicesInState(model.IOSDeviceStateSuspect, c.now)
+
+	if c.ok("get devices in state", err) && len(*devices) != 0 {
+		c.errorf("get devices in state returned %v, which changed state at the boundary", *deviceIds(devices))
+	}
+
+	// Devices that never changed their state are included.
+	devices, err = c.repo.GetDevicesInState(model.IOSDeviceStateActive, c.now.Add(-time.Hour))
+
+	if c.ok("get devices in state", err) {
+		c.expectStrings("get devices in state never changed", deviceIds(devices), "device-a")
+	}
+}
+
+func (c *checker) checkLectures() {
+	if !c.createLectures("lecture-1", "lecture-2", "lecture-3") {
+		return
+	}
+
+	lectures, err := c.repo.GetLectures()
+
+	if c.ok("get lectures", err) && len(*lectures) != 3 {
+		c.errorf("get lectures returned %d instead of 3 lectures", len(*lectures))
+	}
+
+	if lecture := c.lecture("lecture-1"); lecture != nil {
+		if lecture.LastRequestId != nil {
+			c.errorf("new lecture has LastRequestId %s", *lecture.LastRequestId)
+		}
+
+		if !lecture.LastUpdate.Equal(c.now.Add(-24 * time.Hour)) {
+			c.errorf("new lecture has LastUpdate %s instead of %s", lecture.LastUpdate, c.now.Add(-24*time.Hour))
+		}
+	}
+
+	c.failsWith("create duplicate lecture", c.repo.CreateLectures(&[]model.IOSLecture{{Id: "lecture-1", Semester: model.IOSLectureSemesterSummer}}), repository.ErrQuery)
+}
+
+// checkEnrollmentPages pages through the enrollments created by
+// checkEnrollments.
+func (c *checker) checkEnrollmentPages() {
+	pages := []struct {
+		name   string
+		get    func(after model.IOSDeviceLecture, limit int) (*[]model.IOSDeviceLecture, error)
+		after  model.IOSDeviceLecture
+		limit  int
+		expect []string
+	}{
+		{"first page", c.repo.GetDeviceLecturesAfter, model.IOSDeviceLecture{}, 3, []string{"device-a/lecture-1", "device-a/lecture-2", "device-b/lecture-2"}},
+		{"last page", c.repo.GetDeviceLecturesAfter, model.IOSDeviceLecture{DeviceId: "device-b", LectureId: "lecture-2"}, 3, []string{"device-b/lecture-3"}},
+		{"page after the last enrollment", c.repo.GetDeviceLecturesAfter, model.IOSDeviceLecture{DeviceId: "device-b", LectureId: "lecture-3"}, 3, []string{}},
+		{"ready page", c.repo.GetReadyDeviceLecturesAfter, model.IOSDeviceLecture{DeviceId: "device-a", LectureId: "lecture-1"}, 3, []string{"device-a/lecture-2"}},
+	}
+
+	for _, page := range pages {
+		deviceLectures, err := page.get(page.after, page.limit)
+
+		if !c.ok(page.name, err) {
+			continue
+		}
+
+		keys := []string{}
+
+		for _, deviceLecture := range *deviceLectures {
+			keys = append(keys, deviceLecture.DeviceId+"/"+deviceLecture.LectureId)
+		}
+
+		if strings.Join(keys, ",") != strings.Join(page.expect, ",") {
+			c.errorf("%s returned %v instead of %v", page.name, keys, page.expect)
+		}
+	}
+}
+
+func (c *checker) checkEnrollments() {
+	if !c.enroll("device-a", "lecture-1", "lecture-2") || !c.enroll("device-b", "lecture-2", "lecture-3") {
+		return
+	}
+
+	c.failsWith(
+		"enroll unknown device",
+		c.repo.CreateDeviceLectures(&[]model.IOSDeviceLecture{{DeviceId: "unknown", LectureId: "lecture-1"}}),
+		repository.ErrQuery,
+	)
+
+	c.failsWith(
+		"enroll twice",
+		c.repo.CreateDeviceLectures(&[]model.IOSDeviceLecture{{DeviceId: "device-a", LectureId: "lecture-1"}}),
+		repository.ErrQuery,
+	)
+
+	deviceLectures, err := c.repo.GetDeviceLectures()
+
+	if c.ok("get device lectures", err) && len(*deviceLectures) != 4 {
+		c.errorf("get device lectures returned %d instead of 4 enrollments", len(*deviceLectures))
+	}
+
+	// device-b is suspect, so only the enrollments of device-a are ready.
+	deviceLectures, err = c.repo.GetReadyDeviceLectures()
+
+	if c.ok("get ready device lectures", err) {
+		var lectureIds []string
+
+		for _, deviceLecture := range *deviceLectures {
+			if deviceLecture.DeviceId != "device-a" {
+				c.errorf("get ready device lectures returned enrollment of %s", deviceLecture.DeviceId)
+			}
+
+			lectureIds = append(lectureIds, deviceLecture.LectureId)
+		}
+
+		c.expectStrings("get ready device lectures", &lectureIds, "lecture-1", "lecture-2")
+	}
+
+	c.checkEnrollmentPages()
+
+	lectureIds, err := c.repo.GetLecturesThatHaveAtLeastOneDevice()
+
+	if c.ok("get lectures that have at least one device", err) {
+		c.expectStrings("get lectures that have at least one device", lectureIds, "lecture-1", "lecture-2", "lecture-3")
+	}
+
+	maxCount, err := c.repo.GetMaxAttendedLecturesCount()
+
+	if c.ok("get max attended lectures count", err) && maxCount != 2 {
+		c.errorf("get max attended lectures count returned %d instead of 2", maxCount)
+	}
+}
+
+func (c *checker) checkLectureUpdateRequests() {
+	first, err := c.repo.CreateLectureUpdateRequests(map[string][]string{"device-a": {"lecture-1"}})
+
+	if !c.ok("create lecture update requests", err) || len(*first) != 1 {
+		return
+	}
+
+	requests, err := c.repo.CreateLectureUpdateRequests(map[string][]string{"device-a": {"lecture-2"}})
+
+	if !c.ok("create lecture update requests", err) || len(*requests) != 1 {
+		return
+	}
+
+	request := (*requests)[0]
+
+	if request.RequestID == "" || request.Status != model.IOSRequestStatusCreated || !request.ExpiresAt.Valid {
+		c.errorf("created request is incomplete: %+v", request)
+	}
+
+	c.expectStatus((*first)[0].RequestID, model.IOSRequestStatusSuperseded)
+
+	if lecture := c.lecture("lecture-2"); lecture != nil && (lecture.LastRequestId == nil || *lecture.LastRequestId != request.RequestID) {
+		c.errorf("lecture-2 does not point to request %s", request.RequestID)
+	}
+
+	// lecture-1 was covered by the superseded request, device-a answers it
+	// with the new one.
+	if lecture := c.lecture("lecture-1"); lecture != nil && (lecture.LastRequestId == nil || *lecture.LastRequestId != request.RequestID) {
+		c.errorf("lecture-1 does not point from the superseded request to request %s", request.RequestID)
+	}
+
+	_, err = c.repo.CreateLectureUpdateRequests(map[string][]string{"unknown": {"lecture-1"}})
+	c.failsWith("create lecture update request for unknown device", err, repository.ErrQuery)
+
+	accept := func(requestLog *model.IOSDeviceRequestLog) error { return nil }
+
+	_, err = c.repo.HandleRequest("unknown", c.now, accept)
+	c.failsWith("handle unknown request", err, repository.ErrNotFound)
+
+	_, err = c.repo.HandleRequest(request.RequestID, c.now, func(requestLog *model.IOSDeviceRequestLog) error {
+		if requestLog.DeviceID != "device-a" {
+			c.errorf("validate got request of %s instead of device-a", requestLog.DeviceID)
+		}
+
+		return errRejected
+	})
+
+	if err != errRejected {
+		c.errorf("handle rejected request: expected the error of validate as is, got %v", err)
+	}
+
+	c.expectStatus(request.RequestID, model.IOSRequestStatusCreated)
+
+	handled, err := c.repo.HandleRequest(request.RequestID, c.now, accept)
+
+	if c.ok("handle request", err) && (handled.Status != model.IOSRequestStatusHandled || !handled.HandledAt.Time.Equal(c.now)) {
+		c.errorf("handled request is %s at %v", handled.Status, handled.HandledAt)
+	}
+
+	c.expectStatus(request.RequestID, model.IOSRequestStatusHandled)
+
+	// device-a refreshes lecture-1 as well, although the request only
+	// covered lecture-2.
+	for _, lectureId := range []string{"lecture-1", "lecture-2"} {
+		if lecture := c.lecture(lectureId); lecture != nil && !lecture.LastUpdate.Equal(c.now) {
+			c.errorf("%s was updated at %s instead of %s", lectureId, lecture.LastUpdate, c.now)
+		}
+	}
+
+	if lecture := c.lecture("lecture-3"); lecture != nil && lecture.LastUpdate.Equal(c.now) {
+		c.errorf("lecture-3 was updated by a request it is not covered by")
+	}
+
+	_, err = c.repo.HandleRequest(request.RequestID, c.now, accept)
+
+	if err == nil || errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrQuery) {
+		c.errorf("handle request twice: expected a transition error, got %v", err)
+	}
+
+	deviceIds, err := c.repo.GetHandledRequestsDevices(&[]string{request.RequestID, (*first)[0].RequestID})
+
+	if c.ok("get handled requests devices", err) {
+		c.expectStrings("get handled requests devices", deviceIds, "device-a")
+	}
+}
+
+func (c *checker) checkDeliveryAttempts() {
+	requestLogs := []model.IOSDeviceRequestLog{
+		model.NewIOSDeviceRequestLog("device-c", model.IOSTokenRequestType, c.now),
+		model.NewIOSDeviceRequestLog("device-c", model.IOSTokenRequestType, c.now),
+		model.NewIOSDeviceRequestLog("device-c", model.IOSTokenRequestType, c.now),
+		{DeviceID: "device-c", RequestType: model.IOSTokenRequestType, CreatedAt: c.now},
+	}
+
+	requestLogs[2].Status = model.IOSRequestStatusHandled
+
+	if !c.ok("create request logs", c.repo.CreateRequestLogs(&requestLogs)) {
+		return
+	}
+
+	if requestLogs[3].RequestID == "" {
+		c.errorf("create request logs did not set the RequestID")
+	} else {
+		c.expectStatus(requestLogs[3].RequestID, model.IOSRequestStatusCreated)
+	}
+
+	c.failsWith(
+		"create request log for unknown device",
+		c.repo.CreateRequestLogs(&[]model.IOSDeviceRequestLog{model.NewIOSDeviceRequestLog("unknown", model.IOSTokenRequestType, c.now)}),
+		repository.ErrQuery,
+	)
+
+	ids := []string{requestLogs[0].RequestID, requestLogs[1].RequestID, requestLogs[2].RequestID}
+
+	marked, err := c.repo.MarkRequestsAttempted(&ids, model.IOSRequestStatusDeliveryFailed)
+
+	if c.ok("mark requests attempted", err) && marked != 2 {
+		c.errorf("marked %d instead of 2 requests as delivery failed", marked)
+	}
+
+	c.expectStatus(requestLogs[2].RequestID, model.IOSRequestStatusHandled)
+
+	marked, err = c.repo.MarkRequestsAttempted(&[]string{requestLogs[1].RequestID}, model.IOSRequestStatusDeliveryFailed)
+
+	if c.ok("mark requests attempted", err) && marked != 1 {
+		c.errorf("marked %d instead of 1 request as delivery failed again", marked)
+	}
+
+	if requestLog := c.requestLog(requestLogs[1].RequestID); requestLog != nil && requestLog.Attempts != 2 {
+		c.errorf("request has %d instead of 2 attempts", requestLog.Attempts)
+	}
+
+	retryable, err := c.repo.GetRetryableRequests(2, c.now)
+
+	if c.ok("get retryable requests", err) {
+		var retryableIds []string
+
+		for _, requestLog := range *retryable {
+			retryableIds = append(retryableIds, requestLog.RequestID)
+		}
+
+		c.expectStrings("get retryable requests", &retryableIds, requestLogs[0].RequestID)
+	}
+
+	retryable, err = c.repo.GetRetryableRequests(3, c.now.Add(2*time.Hour))
+
+	if c.ok("get expired retryable requests", err) && len(*retryable) != 0 {
+		c.errorf("get retryable requests returned %d expired requests", len(*retryable))
+	}
+
+	marked, err = c.repo.MarkRequestsAttempted(&[]string{requestLogs[0].RequestID}, model.IOSRequestStatusSent)
+
+	if c.ok("mark requests attempted", err) && marked != 1 {
+		c.errorf("marked %d instead of 1 request as sent", marked)
+	}
+}
+
+func (c *checker) checkExpiry() {
+	legacy := model.IOSDeviceRequestLog{
+		RequestID:   model.NewRequestID(),
+		DeviceID:    "device-c",
+		RequestType: model.IOSTokenRequestType,
+		CreatedAt:   c.now.Add(-model.DefaultIOSRequestExpiry()[model.IOSTokenRequestType] - time.Minute),
+	}
+
+	if !c.ok("create request logs", c.repo.CreateRequestLogs(&[]model.IOSDeviceRequestLog{legacy})) {
+		return
+	}
+
+	requests, err := c.repo.CreateLectureUpdateRequests(map[string][]string{"device-c": {"lecture-3"}})
+
+	if !c.ok("create lecture update requests", err) {
+		return
+	}
+
+	request := (*requests)[0]
+
+	expired, err := c.repo.ExpireRequests(c.now)
+
+	if c.ok("expire requests", err) && expired != 1 {
+		c.errorf("expired %d instead of 1 request without ExpiresAt", expired)
+	}
+
+	c.expectStatus(legacy.RequestID, model.IOSRequestStatusExpired)
+	c.expectStatus(request.RequestID, model.IOSRequestStatusCreated)
+
+	// The token requests of checkDeliveryAttempts expire after an hour.
+	expired, err = c.repo.ExpireRequests(request.ExpiresAt.Time.Add(time.Hour + time.Second))
+
+	if c.ok("expire requests", err) && expired != 4 {
+		c.errorf("expired %d instead of 4 requests", expired)
+	}
+
+	c.expectStatus(request.RequestID, model.IOSRequestStatusExpired)
+
+	if lecture := c.lecture("lecture-3"); lecture != nil && lecture.LastRequestId != nil {
+		c.errorf("lecture-3 still points to expired request %s", *lecture.LastRequestId)
+	}
+
+	requestLogs, err := c.repo.GetRequestLogsSince(c.now.Add(-time.Minute))
+
+	if c.ok("get request logs since", err) {
+		for i, requestLog := range *requestLogs {
+			if requestLog.CreatedAt.Before(c.now.Add(-time.Minute)) {
+				c.errorf("get request logs since returned request created at %s", requestLog.CreatedAt)
+			}
+
+			if i > 0 && requestLog.CreatedAt.After((*requestLogs)[i-1].CreatedAt) {
+				c.errorf("get request logs since is not ordered newest first")
+			}
+		}
+	}
+}
+
+func (c *checker) checkScheduledUpdates() {
+	if !c.createDevices("device-d", "device-e", "device-f") {
+		return
+	}
+
+	old := c.now.Add(-2 * time.Hour)
+	older := c.now.Add(-3 * time.Hour)
+
+	err := c.repo.RecordScheduledUpdates(&[]string{"device-d"}, model.IOSUpdateTypeGrades, c.now)
+
+	if !c.ok("record scheduled updates", err) {
+		return
+	}
+
+	if !c.ok("record scheduled updates", c.repo.RecordScheduledUpdates(&[]string{"device-d"}, model.IOSUpdateTypeGrades, old)) {
+		return
+	}
+
+	if !c.ok("record scheduled updates", c.repo.RecordScheduledUpdates(&[]string{"device-e"}, model.IOSUpdateTypeGrades, older)) {
+		return
+	}
+
+	if !c.ok("record scheduled updates", c.repo.RecordScheduledUpdates(&[]string{"device-f"}, model.IOSUpdateTypeLectures, c.now)) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		if !c.ok("record scheduled run", c.repo.RecordScheduledRun(model.IOSUpdateTypeLectures, old)) {
+			return
+		}
+	}
+
+	c.failsWith(
+		"record scheduled update of unknown device",
+		c.repo.RecordScheduledUpdates(&[]string{"unknown"}, model.IOSUpdateTypeGrades, c.now),
+		repository.ErrQuery,
+	)
+
+	updateLogs, err := c.repo.GetScheduledUpdateLogs(model.IOSUpdateTypeGrades)
+
+	if c.ok("get scheduled update logs", err) {
+		if len(*updateLogs) != 2 {
+			c.errorf("recording twice created %d instead of 2 logs", len(*updateLogs))
+		}
+
+		for _, updateLog := range *updateLogs {
+			if updateLog.DeviceID == "device-d" && !updateLog.CreatedAt.Equal(old) {
+				c.errorf("log of device-d was not moved to %s: %s", old, updateLog.CreatedAt)
+			}
+		}
+	}
+
+	updateLogs, err = c.repo.GetScheduledUpdateLogs(model.IOSUpdateTypeLectures)
+
+	if c.ok("get scheduled update logs", err) {
+		runs := 0
+
+		for _, updateLog := range *updateLogs {
+			if updateLog.DeviceID == "" && updateLog.CreatedAt.Equal(old) {
+				runs++
+			}
+		}
+
+		if runs != 2 || len(*updateLogs) != 3 {
+			c.errorf("expected the log of device-f and 2 logs of runs, got %v", *updateLogs)
+		}
+	}
+
+	// device-a, device-c and device-f were never updated, device-b is
+	// suspect.
+	due, err := c.repo.GetDevicesDueForUpdate(model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now.Add(-time.Hour), c.now, -1)
+
+	if c.ok("get devices due for update", err) {
+		if len(*due) != 5 {
+			c.errorf("get devices due for update returned %v instead of 5 devices", *due)
+		} else {
+			c.expectStrings("never updated devices", &[]string{(*due)[0], (*due)[1], (*due)[2]}, "device-a", "device-c", "device-f")
+
+			if (*due)[3] != "device-e" || (*due)[4] != "device-d" {
+				c.errorf("updated devices are not ordered oldest first: %v", (*due)[3:])
+			}
+		}
+	}
+
+	due, err = c.repo.GetDevicesDueForUpdate(model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now.Add(-150*time.Minute), c.now, 10)
+
+	if c.ok("get devices due for update", err) && (len(*due) != 4 || (*due)[3] != "device-e") {
+		c.errorf("get devices due for update before %s returned %v", c.now.Add(-150*time.Minute), *due)
+	}
+
+	due, err = c.repo.GetDevicesDueForUpdate(model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now, c.now, 2)
+
+	if c.ok("get devices due for update", err) && len(*due) != 2 {
+		c.errorf("get devices due for update ignored the limit: %v", *due)
+	}
+
+	open := []model.IOSDeviceRequestLog{model.NewIOSDeviceRequestLog("device-e", model.IOSTokenRequestType, older)}
+
+	if !c.ok("create request logs", c.repo.CreateRequestLogs(&open)) {
+		return
+	}
+
+	before := c.now.Add(-150 * time.Minute)
+
+	requests, err := c.repo.CreateScheduledRequests(
+		&[]string{"device-d", "device-e", "device-f"},
+		model.IOSUpdateTypeGrades,
+		model.IOSTokenRequestType,
+		before,
+		c.now,
+	)
+
+	if c.ok("create scheduled requests", err) {
+		var claimed []string
+
+		for _, request := range *requests {
+			claimed = append(claimed, request.DeviceID)
+
+			if request.RequestType != model.IOSTokenRequestType || !request.CreatedAt.Equal(c.now) {
+				c.errorf("scheduled request is incomplete: %+v", request)
+			}
+		}
+
+		c.expectStrings("claimed devices", &claimed, "device-e", "device-f")
+	}
+
+	c.expectStatus(open[0].RequestID, model.IOSRequestStatusSuperseded)
+
+	// The logs are only moved when the devices answer.
+	if updateLog := c.updateLog("device-e", model.IOSUpdateTypeGrades); updateLog != nil && !updateLog.CreatedAt.Equal(older) {
+		c.errorf("log of device-e was moved to %s before it answered", updateLog.CreatedAt)
+	}
+
+	requests, err = c.repo.CreateScheduledRequests(&[]string{"device-e", "device-f"}, model.IOSUpdateTypeGrades, model.IOSTokenRequestType, before, c.now)
+
+	if c.ok("create scheduled requests again", err) && len(*requests) != 0 {
+		c.errorf("claimed %d devices with pending requests", len(*requests))
+	}
+
+	due, err = c.repo.GetDevicesDueForUpdate(model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now, c.now, -1)
+
+	if c.ok("get devices due for update", err) {
+		for _, deviceId := range *due {
+			if deviceId == "device-e" || deviceId == "device-f" {
+				c.errorf("%s is due although its request is pending", deviceId)
+			}
+		}
+	}
+
+	pending := make(map[string]string)
+
+	for _, request := range *c.requestsOfType(model.IOSTokenRequestType) {
+		if request.Status == model.IOSRequestStatusCreated {
+			pending[request.DeviceID] = request.RequestID
+		}
+	}
+
+	accept := func(requestLog *model.IOSDeviceRequestLog) error { return nil }
+	answeredAt := c.now.Add(10 * time.Minute)
+
+	if _, err := c.repo.HandleRequest(pending["device-e"], answeredAt, accept); !c.ok("handle scheduled request", err) {
+		return
+	}
+
+	if updateLog := c.updateLog("device-e", model.IOSUpdateTypeGrades); updateLog != nil && !updateLog.CreatedAt.Equal(answeredAt) {
+		c.errorf("log of device-e is at %s instead of its answer at %s", updateLog.CreatedAt, answeredAt)
+	}
+
+	// device-f did not answer and is claimed again once its request
+	// expired, device-e is fresh.
+	later := c.now.Add(2 * time.Hour)
+	requests, err = c.repo.CreateScheduledRequests(&[]string{"device-e", "device-f"}, model.IOSUpdateTypeGrades, model.IOSTokenRequestType, c.now, later)
+
+	if c.ok("create scheduled requests after the expiry", err) {
+		var claimed []string
+
+		for _, request := range *requests {
+			claimed = append(claimed, request.DeviceID)
+		}
+
+		c.expectStrings("devices claimed after the expiry", &claimed, "device-f")
+	}
+
+	c.expectStatus(pending["device-f"], model.IOSRequestStatusSuperseded)
+
+	_, err = c.repo.CreateScheduledRequests(&[]string{"unknown"}, model.IOSUpdateTypeGrades, model.IOSTokenRequestType, before, c.now)
+	c.failsWith("create scheduled request for unknown device", err, repository.ErrQuery)
+}
+
+func (c *checker) checkSchedulingPriorities() {
+	priorities := []model.IOSSchedulingPriority{
+		*model.DefaultIOSSchedulingPriority(),
+		{FromMonth: 12, FromMonthDay: 20, ToMonth: 1, ToMonthDay: 7, FromHour: 0, ToHour: 23, Weekdays: model.IOSWeekdaysWeekend, TimeZone: "Europe/Berlin", Priority: 1},
+	}
+
+	if !c.ok("create scheduling priorities", c.repo.CreateSchedulingPriorities(&priorities)) {
+		return
+	}
+
+	if priorities[0].ID == 0 || priorities[0].ID == priorities[1].ID {
+		c.errorf("created priorities have IDs %d and %d", priorities[0].ID, priorities[1].ID)
+	}
+
+	stored, err := c.repo.GetSchedulingPriorities()
+
+	if !c.ok("get scheduling priorities", err) {
+		return
+	}
+
+	if len(*stored) != 2 {
+		c.errorf("get scheduling priorities returned %d instead of 2 priorities", len(*stored))
+		return
+	}
+
+	for _, priority := range *stored {
+		if priority.ID == priorities[1].ID && priority != priorities[1] {
+			c.errorf("stored priority %s differs from %s", &priority, &priorities[1])
+		}
+	}
+}
+
+func (c *checker) checkGrades() {
+	grades := []model.IOSEncryptedGrade{
+		{DeviceID: "device-a", LectureTitle: "Analysis", Grade: "1.3", IsEncrypted: false},
+		{DeviceID: "device-a", LectureTitle: "Algebra", Grade: "2.0", IsEncrypted: true},
+		{DeviceID: "device-c", LectureTitle: "Analysis", Grade: "1.7", IsEncrypted: true},
+	}
+
+	if !c.ok("create grades", c.repo.CreateGrades(&grades)) {
+		return
+	}
+
+	if grades[0].ID == 0 || grades[0].ID == grades[1].ID {
+		c.errorf("created grades have IDs %d and %d", grades[0].ID, grades[1].ID)
+	}
+
+	c.failsWith(
+		"create grade of unknown device",
+		c.repo.CreateGrades(&[]model.IOSEncryptedGrade{{DeviceID: "unknown", LectureTitle: "Analysis", Grade: "1.0"}}),
+		repository.ErrQuery,
+	)
+
+	stored, err := c.repo.GetGradesByDevice("device-a")
+
+	if c.ok("get grades by device", err) {
+		if len(*stored) != 2 || (*stored)[0].LectureTitle != "Analysis" || (*stored)[1].Grade != "2.0" {
+			c.errorf("get grades by device returned %+v", *stored)
+		}
+	}
+}
+
+func (c *checker) checkActivityCounters() {
+	year := c.now.AddDate(-1, 0, 0)
+	month := c.now.Add(-20 * 24 * time.Hour)
+	week := c.now.Add(-3 * 24 * time.Hour)
+	day := c.now.Add(-time.Hour)
+
+	var requestLogs []model.IOSDeviceRequestLog
+
+	for _, handledAt := range []time.Time{c.now, week.Add(time.Hour), month.Add(time.Hour), month.Add(-time.Hour)} {
+		requestLog := model.NewIOSDeviceRequestLog("device-f", model.IOSTokenRequestType, handledAt)
+		requestLog.Status = model.IOSRequestStatusHandled
+		requestLog.HandledAt = sql.NullTime{Time: handledAt, Valid: true}
+
+		requestLogs = append(requestLogs, requestLog)
+	}
+
+	if !c.ok("create request logs", c.repo.CreateRequestLogs(&requestLogs)) {
+		return
+	}
+
+	if _, err := c.repo.UpdateActivityCounters(day, week, month, year); !c.ok("update activity counters", err) {
+		return
+	}
+
+	if device := c.device("device-f"); device != nil {
+		got := [4]int32{device.ActivityToday, device.ActivityThisWeek, device.ActivityThisMonth, device.ActivityThisYear}
+
+		if got != [4]int32{1, 2, 3, 4} {
+			c.errorf("device-f has activity counters %v instead of [1 2 3 4]", got)
+		}
+	}
+
+	// device-a answered a lecture update request at c.now.
+	if device := c.device("device-a"); device != nil && device.ActivityToday != 1 {
+		c.errorf("device-a has %d instead of 1 handled request today", device.ActivityToday)
+	}
+
+	// device-e answered a scheduled token request, device-d none.
+	if device := c.device("device-e"); device != nil && device.ActivityThisYear != 1 {
+		c.errorf("device-e has %d instead of 1 handled request this year", device.ActivityThisYear)
+	}
+
+	if device := c.device("device-d"); device != nil && device.ActivityThisYear != 0 {
+		c.errorf("device-d has %d handled requests this year instead of none", device.ActivityThisYear)
+	}
+}
+
+func (c *checker) checkPurge() {
+	if !c.createDevices("device-g") || !c.enroll("device-g", "lecture-3") {
+		return
+	}
+
+	requests, err := c.repo.CreateLectureUpdateRequests(map[string][]string{"device-g": {"lecture-3"}})
+
+	if !c.ok("create lecture update requests", err) {
+		return
+	}
+
+	grades := []model.IOSEncryptedGrade{{DeviceID: "device-g", LectureTitle: "Analysis", Grade: "4.0"}}
+
+	if !c.ok("create grades", c.repo.CreateGrades(&grades)) {
+		return
+	}
+
+	if !c.ok("record scheduled updates", c.repo.RecordScheduledUpdates(&[]string{"device-g"}, model.IOSUpdateTypeLectures, c.now)) {
+		return
+	}
+
+	_, err = c.repo.SetDevicesState(&[]string{"device-g"}, model.IOSDeviceStateUnregistered, c.now.Add(-time.Hour))
+
+	if !c.ok("set devices state", err) {
+		return
+	}
+
+	_, err = c.repo.SetDevicesState(&[]string{"device-d"}, model.IOSDeviceStateUnregistered, c.now)
+
+	if !c.ok("set devices state", err) {
+		return
+	}
+
+	purged, err := c.repo.PurgeUnregisteredDevices(c.now.Add(-time.Minute))
+
+	if c.ok("purge unregistered devices", err) && purged != 1 {
+		c.errorf("purged %d instead of 1 device", purged)
+	}
+
+	devices, err := c.repo.GetDevicesByIds(&[]string{"device-g", "device-d"})
+
+	if c.ok("get devices by ids", err) {
+		c.expectStrings("devices left after purge", deviceIds(devices), "device-d")
+	}
+
+	deviceLectures, err := c.repo.GetDeviceLectures()
+
+	if c.ok("get device lectures", err) {
+		for _, deviceLecture := range *deviceLectures {
+			if deviceLecture.DeviceId == "device-g" {
+				c.errorf("enrollment of purged device was kept")
+			}
+		}
+	}
+
+	requestLogs, err := c.repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if c.ok("get request logs since", err) {
+		for _, requestLog := range *requestLogs {
+			if requestLog.DeviceID == "device-g" {
+				c.errorf("request log of purged device was kept")
+			}
+		}
+	}
+
+	if lecture := c.lecture("lecture-3"); lecture != nil && lecture.LastRequestId != nil && *lecture.LastRequestId == (*requests)[0].RequestID {
+		c.errorf("lecture-3 still points to a request of the purged device")
+	}
+
+	stored, err := c.repo.GetGradesByDevice("device-g")
+
+	if c.ok("get grades by device", err) && len(*stored) != 0 {
+		c.errorf("grades of purged device were kept")
+	}
+
+	updateLogs, err := c.repo.GetScheduledUpdateLogs(model.IOSUpdateTypeLectures)
+
+	if c.ok("get scheduled update logs", err) {
+		for _, updateLog := range *updateLogs {
+			if updateLog.DeviceID == "device-g" {
+				c.errorf("scheduled update log of purged device was kept")
+			}
+		}
+	}
+}
+
+func (c *checker) checkBulkLoad() {
+	dataset := repository.Dataset{
+		Lectures: []model.IOSLecture{
+			{Id: "bulk-lecture-1", Year: 2023, Semester: model.IOSLectureSemesterSummer},
+			{Id: "bulk-lecture-2", Year: 2023, Semester: model.IOSLectureSemesterSummer},
+		},
+		Devices: []model.IOSDevice{
+			{DeviceID: "bulk-a", PublicKey: "key-bulk-a"},
+			{DeviceID: "bulk-b", PublicKey: "key-bulk-b"},
+			{DeviceID: "bulk-c", PublicKey: "key-bulk-c"},
+		},
+		DeviceLectures: []model.IOSDeviceLecture{
+			{DeviceId: "bulk-a", LectureId: "bulk-lecture-1"},
+			{DeviceId: "bulk-b", LectureId: "bulk-lecture-1"},
+			{DeviceId: "bulk-b", LectureId: "bulk-lecture-2"},
+		},
+		RequestLogs: []model.IOSDeviceRequestLog{
+			model.NewIOSDeviceRequestLog("bulk-a", model.IOSLectureUpdateRequestType, c.now),
+			{DeviceID: "bulk-b", RequestType: model.IOSTokenRequestType, CreatedAt: c.now},
+		},
+		Priorities: []model.IOSSchedulingPriority{*model.DefaultIOSSchedulingPriority()},
+	}
+
+	stats, err := c.repo.BulkLoad(&dataset, repository.BulkConfig{BatchSize: 2})
+
+	if !c.ok("bulk load", err) {
+		return
+	}
+
+	if stats.Rows() != dataset.Rows() || len(stats.Tables) != 5 || stats.Tables[1].Batches != 2 {
+		c.errorf("bulk load reported %d rows in %+v instead of %d rows", stats.Rows(), stats.Tables, dataset.Rows())
+	}
+
+	if dataset.RequestLogs[1].RequestID == "" || dataset.Priorities[0].ID == 0 {
+		c.errorf("bulk load did not set the request ID %q or the priority ID %d", dataset.RequestLogs[1].RequestID, dataset.Priorities[0].ID)
+	}
+
+	devices, err := c.repo.GetDevicesByIds(&[]string{"bulk-a", "bulk-b", "bulk-c"})
+
+	if c.ok("get devices by ids", err) {
+		c.expectStrings("bulk loaded devices", deviceIds(devices), "bulk-a", "bulk-b", "bulk-c")
+	}
+
+	// The failing enrollment rolls back the devices loaded before it.
+	_, err = c.repo.BulkLoad(&repository.Dataset{
+		Devices:        []model.IOSDevice{{DeviceID: "bulk-d", PublicKey: "key-bulk-d"}},
+		DeviceLectures: []model.IOSDeviceLecture{{DeviceId: "bulk-d", LectureId: "unknown"}},
+	}, repository.DefaultBulkConfig())
+	c.failsWith("bulk load of unknown lecture", err, repository.ErrQuery)
+
+	devices, err = c.repo.GetDevicesByIds(&[]string{"bulk-d"})
+
+	if c.ok("get devices by ids", err) && len(*devices) != 0 {
+		c.errorf("failed bulk load kept device bulk-d")
+	}
+
+	before := c.device("bulk-a")
+
+	if before == nil {
+		return
+	}
+
+	handled := dataset.RequestLogs[0]
+	handled.Status = model.IOSRequestStatusHandled
+	handled.HandledAt = sql.NullTime{Time: c.now, Valid: true}
+
+	upsert := repository.Dataset{
+		Devices:        []model.IOSDevice{{DeviceID: "bulk-a", PublicKey: "new-key-bulk-a", State: model.IOSDeviceStateSuspect}},
+		DeviceLectures: []model.IOSDeviceLecture{{DeviceId: "bulk-a", LectureId: "bulk-lecture-1"}},
+		RequestLogs:    []model.IOSDeviceRequestLog{handled},
+	}
+
+	_, err = c.repo.BulkLoad(&upsert, repository.DefaultBulkConfig())
+	c.failsWith("bulk load of existing records", err, repository.ErrQuery)
+
+	config := repository.DefaultBulkConfig()
+	config.Upsert = true
+
+	if _, err := c.repo.BulkLoad(&upsert, config); !c.ok("bulk upsert", err) {
+		return
+	}
+
+	if device := c.device("bulk-a"); device != nil {
+		if device.PublicKey != "new-key-bulk-a" || device.State != model.IOSDeviceStateSuspect {
+			c.errorf("bulk upsert left device bulk-a with key %s and state %s", device.PublicKey, device.State)
+		}
+
+		if !device.CreatedAt.Equal(before.CreatedAt) {
+			c.errorf("bulk upsert changed CreatedAt of bulk-a from %s to %s", before.CreatedAt, device.CreatedAt)
+		}
+	}
+
+	c.expectStatus(handled.RequestID, model.IOSRequestStatusHandled)
+
+	deviceLectures, err := c.repo.GetDeviceLectures()
+
+	if c.ok("get device lectures", err) {
+		count := 0
+
+		for _, deviceLecture := range *deviceLectures {
+			if deviceLecture.DeviceId == "bulk-a" {
+				count++
+			}
+		}
+
+		if count != 1 {
+			c.errorf("bulk-a has %d instead of 1 enrollment after the upsert", count)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package repotest
+
+import (
+	"path/filepath"
+	"test-student-lecture-selection-algorithm/db"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+// testExpiry differs from the default expiry of both request types.
+var testExpiry = model.IOSRequestExpiry{
+	model.IOSLectureUpdateRequestType: 5 * time.Minute,
+	model.IOSTokenRequestType:         10 * time.Minute,
+}
+
+func TestCheckMemory(t *testing.T) {
+	if err := Check(memory.New()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRequestExpiryMemory(t *testing.T) {
+	repo := memory.New()
+	repo.RequestExpiry = testExpiry
+
+	if err := CheckRequestExpiry(repo, testExpiry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openSQLite opens a new SQLite database in the temporary directory of t.
+func openSQLite(t *testing.T) *db.Repository {
+	t.Helper()
+
+	repo, err := db.Open("sqlite://" + filepath.Join(t.TempDir(), "repotest.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := repo.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return repo
+}
+
+func TestCheckSQLite(t *testing.T) {
+	if err := Check(openSQLite(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRequestExpirySQLite(t *testing.T) {
+	repo := openSQLite(t)
+	repo.RequestExpiry = testExpiry
+
+	if err := CheckRequestExpiry(repo, testExpiry); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,91 @@
+package result
+
+import (
+	"sort"
+)
+
+// Reasons why a lecture has or hasn't got a coverer.
+const (
+	ReasonCovered     = "covered"
+	ReasonNoDevice    = "no ready device enrolled"
+	ReasonOverBudget  = "left out by the budget"
+	ReasonNotSelected = "no selected device assigned"
+)
+
+// Explanation tells why the devices of a run were selected and how every
+// lecture is covered.
+type Explanation struct {
+	RunID    string               `json:"runId"`
+	Devices  []DeviceExplanation  `json:"devices"`
+	Lectures []LectureExplanation `json:"lectures"`
+}
+
+// DeviceExplanation is a selected device. The solver picks the device that
+// covers the most lectures not covered yet, so the lectures of a device are
+// the ones it newly covered when it was picked.
+type DeviceExplanation struct {
+	// Rank is the position at which the device was picked, starting at one.
+	Rank     int      `json:"rank"`
+	DeviceID string   `json:"deviceId"`
+	Lectures []string `json:"lectures"`
+	// Enrolled is the number of lectures of the instance the device is
+	// enrolled in.
+	Enrolled int `json:"enrolled"`
+}
+
+type LectureExplanation struct {
+	LectureID string `json:"lectureId"`
+	Coverer   string `json:"coverer,omitempty"`
+	// Candidates is the number of ready devices enrolled in the lecture.
+	Candidates int    `json:"candidates"`
+	Reason     string `json:"reason"`
+}
+
+// Explain explains the selection of r for the lectures of lectureIds and the
+// enrollments of the ready devices, see solver.Enrolled.
+func Explain(r *Run, lectureIds []string, enrolled map[string][]string) *Explanation {
+	candidates := make(map[string]int)
+
+	for _, deviceLectureIds := range enrolled {
+		for _, lectureId := range deviceLectureIds {
+			candidates[lectureId]++
+		}
+	}
+
+	explanation := Explanation{RunID: r.ID}
+
+	for i, assignment := range *r.Assignments() {
+		explanation.Devices = append(explanation.Devices, DeviceExplanation{
+			Rank:     i + 1,
+			DeviceID: assignment.DeviceId,
+			Lectures: assignment.LectureIds,
+			Enrolled: len(enrolled[assignment.DeviceId]),
+		})
+	}
+
+	sortedIds := append([]string{}, lectureIds...)
+	sort.Strings(sortedIds)
+
+	for _, lectureId := range sortedIds {
+		lecture := LectureExplanation{
+			LectureID:  lectureId,
+			Coverer:    r.Coverers[lectureId],
+			Candidates: candidates[lectureId],
+		}
+
+		switch {
+		case lecture.Coverer != "":
+			lecture.Reason = ReasonCovered
+		case lecture.Candidates == 0:
+			lecture.Reason = ReasonNoDevice
+		case r.Budget > 0 && len(r.SelectedDevices) >= r.Budget:
+			lecture.Reason = ReasonOverBudget
+		default:
+			lecture.Reason = ReasonNotSelected
+		}
+
+		explanation.Lectures = append(explanation.Lectures, lecture)
+	}
+
+	return &explanation
+}
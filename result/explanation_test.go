@@ -0,0 +1,52 @@
+package result
+
+import (
+	"reflect"
+	"test-student-lecture-selection-algorithm/solver"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	// lecture-4 has a candidate, but the budget of one device is used up.
+	// lecture-5 has no ready device.
+	enrolled := map[string][]string{
+		"device-a": {"lecture-1", "lecture-2", "lecture-3"},
+		"device-b": {"lecture-2", "lecture-4"},
+		"device-c": {"lecture-3"},
+	}
+	lectureIds := []string{"lecture-5", "lecture-4", "lecture-3", "lecture-2", "lecture-1"}
+
+	run := Run{ID: "run-1", Budget: 1}
+	run.SetAssignments(&[]solver.Assignment{{DeviceId: "device-a", LectureIds: []string{"lecture-1", "lecture-2", "lecture-3"}}}, 2)
+
+	explanation := Explain(&run, lectureIds, enrolled)
+
+	if explanation.RunID != "run-1" {
+		t.Errorf("explanation of %s instead of run-1", explanation.RunID)
+	}
+
+	devices := []DeviceExplanation{{Rank: 1, DeviceID: "device-a", Lectures: []string{"lecture-1", "lecture-2", "lecture-3"}, Enrolled: 3}}
+
+	if !reflect.DeepEqual(explanation.Devices, devices) {
+		t.Errorf("devices %+v instead of %+v", explanation.Devices, devices)
+	}
+
+	lectures := []LectureExplanation{
+		{LectureID: "lecture-1", Coverer: "device-a", Candidates: 1, Reason: ReasonCovered},
+		{LectureID: "lecture-2", Coverer: "device-a", Candidates: 2, Reason: ReasonCovered},
+		{LectureID: "lecture-3", Coverer: "device-a", Candidates: 2, Reason: ReasonCovered},
+		{LectureID: "lecture-4", Candidates: 1, Reason: ReasonOverBudget},
+		{LectureID: "lecture-5", Candidates: 0, Reason: ReasonNoDevice},
+	}
+
+	if !reflect.DeepEqual(explanation.Lectures, lectures) {
+		t.Errorf("lectures %+v instead of %+v", explanation.Lectures, lectures)
+	}
+
+	// Without a budget a lecture with candidates was not selected.
+	run.Budget = 0
+
+	if lecture := Explain(&run, lectureIds, enrolled).Lectures[3]; lecture.Reason != ReasonNotSelected {
+		t.Errorf("lecture-4 is %q instead of %q without a budget", lecture.Reason, ReasonNotSelected)
+	}
+}
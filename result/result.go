@@ -0,0 +1,256 @@
+// Package result describes a run of the selection in a structured form, so
+// that other tools can consume the selected devices without parsing the log.
+//
+// A result looks like this:
+//
+//	{
+//	  "version": 1,
+//	  "runId": "0f8d7c2e-...",
+//	  "startedAt": "2023-05-01T12:00:00Z",
+//	  "solver": "greedy",
+//	  "timings": {"queryMs": 812.4, "buildMs": 95.1, "searchMs": 40.2, "verifyMs": 130.7, "totalMs": 1078.4},
+//	  "instance": {"lectures": 500, "enrolledDevices": 18103, "enrollments": 90921, "coverableLectures": 500, "maxAttendedLectures": 13},
+//	  "selectedDevices": ["device-1"],
+//	  "coverers": {"IN0001": "device-1"},
+//	  "verification": {"valid": true, "allLecturesEnrolled": true, "enrolledLectures": 500},
+//	  "solverStats": {"selected": 1, "coveredLectures": 500, "lowerBound": 1, "ratio": 1}
+//	}
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+// Version is the version of the format written by this package.
+const Version = 1
+
+type Run struct {
+	Version   int       `json:"version"`
+	ID        string    `json:"runId"`
+	StartedAt time.Time `json:"startedAt"`
+	Solver    string    `json:"solver"`
+	// Term is the semester and year the lectures were restricted to.
+	Term string `json:"term,omitempty"`
+	// Budget is the maximum number of selected devices, zero means no
+	// limit.
+	Budget   int      `json:"budget,omitempty"`
+	Timings  Timings  `json:"timings"`
+	Instance Instance `json:"instance"`
+	// SelectedDevices are the IDs of the selected devices in the order the
+	// solver picked them.
+	SelectedDevices []string `json:"selectedDevices"`
+	// Coverers maps the ID of every covered lecture to the device covering
+	// it.
+	Coverers     map[string]string `json:"coverers"`
+	Verification Verification      `json:"verification"`
+	SolverStats  SolverStats       `json:"solverStats"`
+	// Requests is the number of lecture update requests created for the
+	// selection, only set with -commit.
+	Requests int `json:"requests,omitempty"`
+}
+
+// Timings are the durations of the phases of a run in milliseconds.
+type Timings struct {
+	// Query is the time spent on the queries loading the instance.
+	Query float64 `json:"queryMs"`
+	// Build is the time spent on building the representation of the
+	// solver.
+	Build  float64 `json:"buildMs"`
+	Search float64 `json:"searchMs"`
+	Verify float64 `json:"verifyMs"`
+	Total  float64 `json:"totalMs"`
+}
+
+// Milliseconds converts d to the unit of the timings.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Instance is the size of the problem solved.
+type Instance struct {
+	Lectures            int `json:"lectures"`
+	EnrolledDevices     int `json:"enrolledDevices"`
+	Enrollments         int `json:"enrollments"`
+	CoverableLectures   int `json:"coverableLectures"`
+	MaxAttendedLectures int `json:"maxAttendedLectures"`
+}
+
+// NewInstance measures the instance of lectures and enrolled, see
+// solver.Enrolled.
+func NewInstance(lectures int, enrolled map[string][]string) Instance {
+	instance := Instance{Lectures: lectures, EnrolledDevices: len(enrolled)}
+	coverable := make(map[string]bool)
+
+	for _, lectureIds := range enrolled {
+		instance.Enrollments += len(lectureIds)
+
+		if len(lectureIds) > instance.MaxAttendedLectures {
+			instance.MaxAttendedLectures = len(lectureIds)
+		}
+
+		for _, lectureId := range lectureIds {
+			coverable[lectureId] = true
+		}
+	}
+
+	instance.CoverableLectures = len(coverable)
+
+	return instance
+}
+
+type Verification struct {
+	// Valid tells whether every coverable lecture is assigned to exactly one
+	// device enrolled in it, see solver.Verify.
+	Valid bool `json:"valid"`
+	// Error describes why the selection is not valid.
+	Error string `json:"error,omitempty"`
+	// AllLecturesEnrolled tells whether every lecture has at least one
+	// enrolled device, regardless of the state of the devices.
+	AllLecturesEnrolled bool `json:"allLecturesEnrolled"`
+	EnrolledLectures    int  `json:"enrolledLectures"`
+	// UncoveredLectures are the IDs of the lectures without a coverer.
+	UncoveredLectures []string `json:"uncoveredLectures,omitempty"`
+}
+
+type SolverStats struct {
+	Selected        int `json:"selected"`
+	CoveredLectures int `json:"coveredLectures"`
+	// LowerBound is the lower bound of the number of devices needed, see
+	// solver.LowerBound.
+	LowerBound int `json:"lowerBound"`
+	// Ratio is the number of selected devices relative to the lower bound.
+	Ratio float64 `json:"ratio"`
+}
+
+// SetAssignments fills the selected devices, the coverers and the solver
+// stats from the assignments of the solver.
+func (r *Run) SetAssignments(assignments *[]solver.Assignment, lowerBound int) {
+	r.SelectedDevices = make([]string, 0, len(*assignments))
+	r.Coverers = make(map[string]string)
+
+	for _, assignment := range *assignments {
+		r.SelectedDevices = append(r.SelectedDevices, assignment.DeviceId)
+
+		for _, lectureId := range assignment.LectureIds {
+			r.Coverers[lectureId] = assignment.DeviceId
+		}
+	}
+
+	r.SolverStats = SolverStats{
+		Selected:        len(*assignments),
+		CoveredLectures: len(r.Coverers),
+		LowerBound:      lowerBound,
+		Ratio:           1,
+	}
+
+	if lowerBound > 0 {
+		r.SolverStats.Ratio = float64(len(*assignments)) / float64(lowerBound)
+	}
+}
+
+// SetUncovered sets the lectures of lectureIds without a coverer, so
+// SetAssignments has to be called before.
+func (r *Run) SetUncovered(lectureIds []string) {
+	r.Verification.UncoveredLectures = nil
+
+	for _, lectureId := range lectureIds {
+		if _, ok := r.Coverers[lectureId]; !ok {
+			r.Verification.UncoveredLectures = append(r.Verification.UncoveredLectures, lectureId)
+		}
+	}
+
+	sort.Strings(r.Verification.UncoveredLectures)
+}
+
+// Read decodes a result and checks its version and that every lecture is
+// covered by a selected device.
+func Read(r io.Reader) (*Run, error) {
+	var run Run
+
+	if err := json.NewDecoder(r).Decode(&run); err != nil {
+		return nil, err
+	}
+
+	if run.Version != Version {
+		return nil, fmt.Errorf("unsupported result version %d, expected %d", run.Version, Version)
+	}
+
+	selected := make(map[string]bool, len(run.SelectedDevices))
+
+	for _, deviceId := range run.SelectedDevices {
+		selected[deviceId] = true
+	}
+
+	for lectureId, deviceId := range run.Coverers {
+		if !selected[deviceId] {
+			return nil, fmt.Errorf("lecture %s is covered by %s, which is not selected", lectureId, deviceId)
+		}
+	}
+
+	return &run, nil
+}
+
+// Load reads the result file of path.
+func Load(path string) (*Run, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Assignments returns the selection of r in the order of its devices.
+func (r *Run) Assignments() *[]solver.Assignment {
+	lectureIds := make(map[string][]string, len(r.SelectedDevices))
+
+	for lectureId, deviceId := range r.Coverers {
+		lectureIds[deviceId] = append(lectureIds[deviceId], lectureId)
+	}
+
+	assignments := make([]solver.Assignment, 0, len(r.SelectedDevices))
+
+	for _, deviceId := range r.SelectedDevices {
+		sort.Strings(lectureIds[deviceId])
+		assignments = append(assignments, solver.Assignment{DeviceId: deviceId, LectureIds: lectureIds[deviceId]})
+	}
+
+	return &assignments
+}
+
+// Write encodes r as indented JSON.
+func (r *Run) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// Save writes r to the file of path, "-" is the standard output.
+func (r *Run) Save(path string) error {
+	if path == "-" {
+		return r.Write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
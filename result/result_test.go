@@ -0,0 +1,123 @@
+package result
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"test-student-lecture-selection-algorithm/solver"
+	"testing"
+	"time"
+)
+
+func testRun() *Run {
+	run := Run{
+		Version:   Version,
+		ID:        "run-1",
+		StartedAt: time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC),
+		Solver:    "greedy",
+		Term:      "winter 2026",
+		Budget:    2,
+		Timings:   Timings{Query: 1.5, Build: 0.25, Search: 2, Verify: 0.5, Total: 4.25},
+		Instance:  Instance{Lectures: 4, EnrolledDevices: 3, Enrollments: 6, CoverableLectures: 3, MaxAttendedLectures: 3},
+		Verification: Verification{
+			Valid:               true,
+			AllLecturesEnrolled: false,
+			EnrolledLectures:    3,
+		},
+		Requests: 2,
+	}
+
+	run.SetAssignments(&[]solver.Assignment{
+		{DeviceId: "device-a", LectureIds: []string{"lecture-1", "lecture-2"}},
+		{DeviceId: "device-b", LectureIds: []string{"lecture-3"}},
+	}, 1)
+	run.SetUncovered([]string{"lecture-4", "lecture-3", "lecture-2", "lecture-1"})
+
+	return &run
+}
+
+func TestWriteAndRead(t *testing.T) {
+	run := testRun()
+
+	var buffer bytes.Buffer
+
+	if err := run.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(&buffer)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read, run) {
+		t.Errorf("read %+v instead of %+v", read, run)
+	}
+
+	if !reflect.DeepEqual(read.Assignments(), run.Assignments()) {
+		t.Errorf("assignments %v instead of %v", *read.Assignments(), *run.Assignments())
+	}
+}
+
+func TestReadRejectsInvalidResults(t *testing.T) {
+	for name, content := range map[string]string{
+		"malformed":       `{"version": 1, "runId": `,
+		"wrong type":      `{"version": 1, "selectedDevices": "device-a"}`,
+		"missing version": `{"runId": "run-1"}`,
+		"newer version":   `{"version": 2, "runId": "run-1"}`,
+		"unknown coverer": `{"version": 1, "selectedDevices": ["device-a"], "coverers": {"lecture-1": "device-b"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if run, err := Read(strings.NewReader(content)); err == nil {
+				t.Errorf("read %+v", run)
+			}
+		})
+	}
+}
+
+func TestSetAssignments(t *testing.T) {
+	run := testRun()
+
+	if !reflect.DeepEqual(run.SelectedDevices, []string{"device-a", "device-b"}) {
+		t.Errorf("selected %v instead of device-a and device-b in the order of the solver", run.SelectedDevices)
+	}
+
+	coverers := map[string]string{"lecture-1": "device-a", "lecture-2": "device-a", "lecture-3": "device-b"}
+
+	if !reflect.DeepEqual(run.Coverers, coverers) {
+		t.Errorf("coverers %v instead of %v", run.Coverers, coverers)
+	}
+
+	if want := (SolverStats{Selected: 2, CoveredLectures: 3, LowerBound: 1, Ratio: 2}); run.SolverStats != want {
+		t.Errorf("solver stats %+v instead of %+v", run.SolverStats, want)
+	}
+
+	// Without a lower bound the ratio is one.
+	run.SetAssignments(&[]solver.Assignment{}, 0)
+
+	if want := (SolverStats{Ratio: 1}); run.SolverStats != want || len(run.SelectedDevices) != 0 || len(run.Coverers) != 0 {
+		t.Errorf("empty selection has stats %+v, devices %v and coverers %v", run.SolverStats, run.SelectedDevices, run.Coverers)
+	}
+}
+
+func TestSetUncovered(t *testing.T) {
+	run := testRun()
+
+	if !reflect.DeepEqual(run.Verification.UncoveredLectures, []string{"lecture-4"}) {
+		t.Errorf("uncovered %v instead of lecture-4", run.Verification.UncoveredLectures)
+	}
+
+	// A second call replaces the uncovered lectures.
+	run.SetUncovered([]string{"lecture-6", "lecture-1", "lecture-5"})
+
+	if !reflect.DeepEqual(run.Verification.UncoveredLectures, []string{"lecture-5", "lecture-6"}) {
+		t.Errorf("uncovered %v instead of lecture-5 and lecture-6 sorted", run.Verification.UncoveredLectures)
+	}
+
+	run.SetUncovered([]string{"lecture-1"})
+
+	if run.Verification.UncoveredLectures != nil {
+		t.Errorf("uncovered %v although every lecture is covered", run.Verification.UncoveredLectures)
+	}
+}
@@ -0,0 +1,103 @@
+package scenario
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// Anonymize returns a copy of the scenario that can be shared. The IDs of
+// the devices, lectures and requests are replaced by pseudonyms derived from
+// key with HMAC-SHA256, the public keys are dropped. The name and the
+// description of the scenario are free text that may tell where and when it
+// was captured, they are dropped as well. The enrollments and the times are
+// kept, so the copy poses the same problem to the solver and has the same
+// request timing. Grades are never part of a scenario.
+//
+// The same key yields the same pseudonyms, which allows comparing exports
+// taken at different times. Without the key the pseudonyms can't be linked
+// to the original IDs.
+func (s *Scenario) Anonymize(key []byte) (*Scenario, error) {
+	p := pseudonymizer{key: key}
+
+	anonymized := Scenario{
+		Version:    s.Version,
+		CapturedAt: s.CapturedAt,
+		Lectures:   make([]Lecture, 0, len(s.Lectures)),
+		Devices:    make([]Device, 0, len(s.Devices)),
+		Priorities: s.Priorities,
+	}
+
+	for _, lecture := range s.Lectures {
+		lecture.ID = p.lecture(lecture.ID)
+		anonymized.Lectures = append(anonymized.Lectures, lecture)
+	}
+
+	for _, device := range s.Devices {
+		lectureIds := make([]string, 0, len(device.Lectures))
+
+		for _, lectureId := range device.Lectures {
+			lectureIds = append(lectureIds, p.lecture(lectureId))
+		}
+
+		sort.Strings(lectureIds)
+
+		device.ID = p.device(device.ID)
+		device.PublicKey = ""
+		device.Lectures = lectureIds
+		anonymized.Devices = append(anonymized.Devices, device)
+	}
+
+	for _, request := range s.Requests {
+		request.ID = p.request(request.ID)
+		request.DeviceID = p.device(request.DeviceID)
+		anonymized.Requests = append(anonymized.Requests, request)
+	}
+
+	// The order of the original IDs would leak through the order of the
+	// pseudonyms.
+	anonymized.sort()
+
+	// Distinct IDs with the same pseudonym are as good as impossible, but
+	// they would silently merge devices or lectures.
+	if err := anonymized.Validate(); err != nil {
+		return nil, fmt.Errorf("anonymized scenario is invalid: %w", err)
+	}
+
+	return &anonymized, nil
+}
+
+type pseudonymizer struct {
+	key []byte
+}
+
+// pseudonym returns the HMAC of id in hex. The kind is part of the message,
+// so that a device and a lecture with the same ID get different pseudonyms.
+func (p pseudonymizer) pseudonym(kind string, id string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(kind + ":" + id))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// device returns a pseudonym formatted like a UUID, which is what device IDs
+// of the simulated and generated devices look like.
+func (p pseudonymizer) device(id string) string {
+	return uuidFormat(p.pseudonym("device", id))
+}
+
+func (p pseudonymizer) request(id string) string {
+	return uuidFormat(p.pseudonym("request", id))
+}
+
+// lecture returns 48 bits of the HMAC, which keeps lecture IDs short enough
+// to be read in the logs of the solver.
+func (p pseudonymizer) lecture(id string) string {
+	return "L" + p.pseudonym("lecture", id)[:12]
+}
+
+func uuidFormat(sum string) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", sum[0:8], sum[8:12], sum[12:16], sum[16:20], sum[20:32])
+}
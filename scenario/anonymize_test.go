@@ -0,0 +1,114 @@
+package scenario
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	s := testScenario()
+	anonymized, err := s.Anonymize([]byte("secret"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+
+	if err := anonymized.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"IN0001", "MA0001", "device-a", "device-b", "request-1", "key-a", "key-b", s.Description} {
+		if strings.Contains(buffer.String(), secret) {
+			t.Errorf("anonymized scenario contains %s", secret)
+		}
+	}
+
+	if anonymized.Name != "" || anonymized.Description != "" {
+		t.Errorf("anonymized scenario keeps the name %q and the description %q", anonymized.Name, anonymized.Description)
+	}
+
+	again, err := s.Anonymize([]byte("secret"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(again, anonymized) {
+		t.Error("the same key yields different pseudonyms")
+	}
+
+	other, err := s.Anonymize([]byte("other"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Devices[0].ID == anonymized.Devices[0].ID && other.Devices[1].ID == anonymized.Devices[1].ID {
+		t.Error("different keys yield the same pseudonyms")
+	}
+}
+
+func TestAnonymizeKeepsTheProblem(t *testing.T) {
+	s := testScenario()
+	anonymized, err := s.Anonymize([]byte("secret"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(enrollmentSizes(anonymized), enrollmentSizes(s)) {
+		t.Errorf("enrollments changed from %v to %v", enrollmentSizes(s), enrollmentSizes(anonymized))
+	}
+
+	// The requests keep their times and refer to the pseudonyms of their
+	// devices.
+	devices := make(map[string]Device, len(anonymized.Devices))
+
+	for _, device := range anonymized.Devices {
+		devices[device.ID] = device
+	}
+
+	for i, request := range anonymized.Requests {
+		original := s.Requests[i]
+
+		if !request.CreatedAt.Equal(original.CreatedAt) || request.Status != original.Status || request.Attempts != original.Attempts {
+			t.Errorf("request %s differs from %s", request.ID, original.ID)
+		}
+
+		if _, ok := devices[request.DeviceID]; !ok {
+			t.Errorf("request %s refers to unknown device %s", request.ID, request.DeviceID)
+		}
+	}
+
+	if !reflect.DeepEqual(anonymized.Priorities, s.Priorities) {
+		t.Error("the priorities were changed")
+	}
+}
+
+// enrollmentSizes returns the state and the number of lectures of every
+// device and the number of devices enrolled in every lecture, sorted.
+func enrollmentSizes(s *Scenario) []string {
+	var sizes []string
+	perLecture := make(map[string]int)
+
+	for _, device := range s.Devices {
+		sizes = append(sizes, device.State+":"+strings.Repeat("l", len(device.Lectures)))
+
+		for _, lectureId := range device.Lectures {
+			perLecture[lectureId]++
+		}
+	}
+
+	for _, lecture := range s.Lectures {
+		sizes = append(sizes, "lecture:"+strings.Repeat("d", perLecture[lecture.ID]))
+	}
+
+	sort.Strings(sizes)
+
+	return sizes
+}
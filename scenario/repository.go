@@ -0,0 +1,257 @@
+package scenario
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+var errNotEmpty = errors.New("repository is not empty")
+
+// exportChunkSize is the number of enrollments read per query by Export.
+const exportChunkSize = 10000
+
+// Export captures the lectures, devices, enrollments, requests and
+// scheduling priorities of repo at now. Devices, lectures and requests are
+// ordered, so that exports of the same data are identical.
+func Export(repo repository.Repository, now time.Time) (*Scenario, error) {
+	s := Scenario{Version: Version, CapturedAt: now.UTC()}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lecture := range *lectures {
+		s.Lectures = append(s.Lectures, Lecture{
+			ID:         lecture.Id,
+			Year:       lecture.Year,
+			Semester:   lecture.Semester,
+			LastUpdate: timePointer(lecture.LastUpdate),
+		})
+	}
+
+	enrollments, err := exportEnrollments(repo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := repo.GetDevices()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range *devices {
+		lectureIds := enrollments[device.DeviceID]
+
+		if lectureIds == nil {
+			lectureIds = []string{}
+		}
+
+		s.Devices = append(s.Devices, Device{
+			ID:             device.DeviceID,
+			State:          device.State,
+			CreatedAt:      timePointer(device.CreatedAt),
+			StateChangedAt: nullTimePointer(device.StateChangedAt),
+			PublicKey:      device.PublicKey,
+			Lectures:       lectureIds,
+		})
+	}
+
+	requestLogs, err := repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, requestLog := range *requestLogs {
+		s.Requests = append(s.Requests, Request{
+			ID:        requestLog.RequestID,
+			DeviceID:  requestLog.DeviceID,
+			Type:      requestLog.RequestType,
+			Status:    requestLog.Status,
+			CreatedAt: requestLog.CreatedAt.UTC(),
+			HandledAt: nullTimePointer(requestLog.HandledAt),
+			ExpiresAt: nullTimePointer(requestLog.ExpiresAt),
+			Attempts:  requestLog.Attempts,
+		})
+	}
+
+	priorities, err := repo.GetSchedulingPriorities()
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.Priorities = *priorities
+	s.sort()
+
+	return &s, nil
+}
+
+// exportEnrollments reads the enrollments in chunks and groups them by
+// device.
+func exportEnrollments(repo repository.Enrollments) (map[string][]string, error) {
+	enrollments := make(map[string][]string)
+
+	var after model.IOSDeviceLecture
+
+	for {
+		chunk, err := repo.GetDeviceLecturesAfter(after, exportChunkSize)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, deviceLecture := range *chunk {
+			enrollments[deviceLecture.DeviceId] = append(enrollments[deviceLecture.DeviceId], deviceLecture.LectureId)
+		}
+
+		if len(*chunk) < exportChunkSize {
+			return enrollments, nil
+		}
+
+		after = (*chunk)[len(*chunk)-1]
+	}
+}
+
+// Import writes the scenario to repo. All times are shifted by the time
+// passed between CapturedAt and now, so that the request history is as
+// recent as it was when the scenario was captured. Unless config.Upsert is
+// set, repo has to be empty.
+func (s *Scenario) Import(repo repository.Repository, now time.Time, config repository.BulkConfig) (*repository.BulkStats, error) {
+	if !config.Upsert {
+		existing, err := repo.GetDevices()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(*existing) > 0 {
+			return nil, fmt.Errorf("%w: %d devices", errNotEmpty, len(*existing))
+		}
+	}
+
+	shift := time.Duration(0)
+
+	if !s.CapturedAt.IsZero() {
+		shift = now.Sub(s.CapturedAt)
+	}
+
+	dataset := repository.Dataset{
+		Lectures:    make([]model.IOSLecture, 0, len(s.Lectures)),
+		Devices:     make([]model.IOSDevice, 0, len(s.Devices)),
+		RequestLogs: make([]model.IOSDeviceRequestLog, 0, len(s.Requests)),
+		Priorities:  make([]model.IOSSchedulingPriority, 0, len(s.Priorities)),
+	}
+
+	for _, lecture := range s.Lectures {
+		dataset.Lectures = append(dataset.Lectures, model.IOSLecture{
+			Id:         lecture.ID,
+			Year:       lecture.Year,
+			Semester:   lecture.Semester,
+			LastUpdate: shiftTime(lecture.LastUpdate, shift),
+		})
+	}
+
+	for _, device := range s.Devices {
+		dataset.Devices = append(dataset.Devices, model.IOSDevice{
+			DeviceID:       device.ID,
+			CreatedAt:      shiftTime(device.CreatedAt, shift),
+			PublicKey:      device.PublicKey,
+			State:          device.State,
+			StateChangedAt: shiftNullTime(device.StateChangedAt, shift),
+		})
+
+		for _, lectureId := range device.Lectures {
+			dataset.DeviceLectures = append(dataset.DeviceLectures, model.IOSDeviceLecture{DeviceId: device.ID, LectureId: lectureId})
+		}
+	}
+
+	for _, request := range s.Requests {
+		dataset.RequestLogs = append(dataset.RequestLogs, model.IOSDeviceRequestLog{
+			RequestID:   request.ID,
+			DeviceID:    request.DeviceID,
+			RequestType: request.Type,
+			CreatedAt:   request.CreatedAt.Add(shift),
+			HandledAt:   shiftNullTime(request.HandledAt, shift),
+			Status:      request.Status,
+			Attempts:    request.Attempts,
+			ExpiresAt:   shiftNullTime(request.ExpiresAt, shift),
+		})
+	}
+
+	existing, err := repo.GetSchedulingPriorities()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The priorities get new IDs, they would collide with the existing ones
+	// otherwise. Priorities that exist already aren't added a second time.
+	for _, priority := range s.Priorities {
+		priority.ID = 0
+
+		if !containsPriority(existing, priority) {
+			dataset.Priorities = append(dataset.Priorities, priority)
+		}
+	}
+
+	return repo.BulkLoad(&dataset, config)
+}
+
+// containsPriority returns true if priorities has a priority equal to
+// priority except for the ID.
+func containsPriority(priorities *[]model.IOSSchedulingPriority, priority model.IOSSchedulingPriority) bool {
+	for _, p := range *priorities {
+		p.ID = 0
+
+		if p == priority {
+			return true
+		}
+	}
+
+	return false
+}
+
+func timePointer(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	t = t.UTC()
+
+	return &t
+}
+
+func nullTimePointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return timePointer(t.Time)
+}
+
+// shiftTime returns the zero time for nil, which makes the repository fill
+// in the current time.
+func shiftTime(t *time.Time, shift time.Duration) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return t.Add(shift)
+}
+
+func shiftNullTime(t *time.Time, shift time.Duration) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.Add(shift), Valid: true}
+}
@@ -0,0 +1,64 @@
+package scenario
+
+import (
+	"errors"
+	"reflect"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestImportExport(t *testing.T) {
+	s := testScenario()
+	repo := memory.New()
+
+	if _, err := s.Import(repo, s.CapturedAt, repository.DefaultBulkConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := Export(repo, s.CapturedAt)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported.Name = s.Name
+	exported.Description = s.Description
+
+	if !reflect.DeepEqual(exported, s) {
+		t.Errorf("expected %+v, got %+v", s, exported)
+	}
+
+	if _, err := s.Import(repo, s.CapturedAt, repository.DefaultBulkConfig()); !errors.Is(err, errNotEmpty) {
+		t.Errorf("import into a repository that is not empty returned %v", err)
+	}
+}
+
+func TestImportShiftsTimes(t *testing.T) {
+	s := testScenario()
+	repo := memory.New()
+	now := s.CapturedAt.Add(30 * 24 * time.Hour)
+
+	if _, err := s.Import(repo, now, repository.DefaultBulkConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := Export(repo, now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, request := range exported.Requests {
+		original := s.Requests[i]
+
+		if now.Sub(request.CreatedAt) != s.CapturedAt.Sub(original.CreatedAt) || request.ExpiresAt.Sub(request.CreatedAt) != original.ExpiresAt.Sub(original.CreatedAt) {
+			t.Errorf("request %s was not shifted by 30 days: %+v", request.ID, request)
+		}
+	}
+
+	if age := now.Sub(*exported.Devices[0].CreatedAt); age != 48*time.Hour {
+		t.Errorf("device-a is %s instead of 48h old", age)
+	}
+}
@@ -0,0 +1,245 @@
+// Package scenario reads and writes problem instances as JSON files, so that
+// instances captured from production or constructed by hand can be replayed
+// against the database, the in-memory repository or the solver directly.
+//
+// A scenario file looks like this:
+//
+//	{
+//	  "version": 1,
+//	  "name": "greedy-adversarial",
+//	  "capturedAt": "2023-04-01T12:00:00Z",
+//	  "lectures": [{"id": "IN0001", "year": 2023, "semester": "summer"}],
+//	  "devices": [{"id": "device-1", "state": "active", "lectures": ["IN0001"]}],
+//	  "requests": [{"id": "...", "deviceId": "device-1", "type": "LECTURE_UPDATE_REQUEST", "status": "handled", ...}],
+//	  "priorities": [{"from_day": 1, "to_day": 366, "from_hour": 0, "to_hour": 23, "priority": 2}]
+//	}
+//
+// Times are RFC 3339. The version is increased whenever the format changes
+// in a way older readers can't handle.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+// Version is the version of the format written by this package.
+const Version = 1
+
+type Scenario struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// CapturedAt is the time the scenario was captured. The times of the
+	// scenario are shifted relative to it when it is imported.
+	CapturedAt time.Time                     `json:"capturedAt"`
+	Lectures   []Lecture                     `json:"lectures"`
+	Devices    []Device                      `json:"devices"`
+	Requests   []Request                     `json:"requests,omitempty"`
+	Priorities []model.IOSSchedulingPriority `json:"priorities,omitempty"`
+}
+
+type Lecture struct {
+	ID         string     `json:"id"`
+	Year       int16      `json:"year,omitempty"`
+	Semester   string     `json:"semester,omitempty"`
+	LastUpdate *time.Time `json:"lastUpdate,omitempty"`
+}
+
+// Device is a device together with the lectures it is enrolled in.
+type Device struct {
+	ID             string     `json:"id"`
+	State          string     `json:"state,omitempty"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+	StateChangedAt *time.Time `json:"stateChangedAt,omitempty"`
+	PublicKey      string     `json:"publicKey,omitempty"`
+	Lectures       []string   `json:"lectures"`
+}
+
+type Request struct {
+	ID        string     `json:"id"`
+	DeviceID  string     `json:"deviceId"`
+	Type      string     `json:"type"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	HandledAt *time.Time `json:"handledAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Attempts  int        `json:"attempts,omitempty"`
+}
+
+// Read decodes and validates a scenario.
+func Read(r io.Reader) (*Scenario, error) {
+	var s Scenario
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Load reads the scenario file at path.
+func Load(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Write encodes the scenario with indentation, so that scenario files can be
+// reviewed and diffed.
+func (s *Scenario) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(s)
+}
+
+// Save writes the scenario to the file at path.
+func (s *Scenario) Save(path string) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// sort orders the lectures and devices by ID and the requests by time, so
+// that scenarios of the same data are identical.
+func (s *Scenario) sort() {
+	sort.Slice(s.Lectures, func(i, j int) bool {
+		return s.Lectures[i].ID < s.Lectures[j].ID
+	})
+
+	sort.Slice(s.Devices, func(i, j int) bool {
+		return s.Devices[i].ID < s.Devices[j].ID
+	})
+
+	sort.Slice(s.Requests, func(i, j int) bool {
+		if !s.Requests[i].CreatedAt.Equal(s.Requests[j].CreatedAt) {
+			return s.Requests[i].CreatedAt.Before(s.Requests[j].CreatedAt)
+		}
+
+		return s.Requests[i].ID < s.Requests[j].ID
+	})
+}
+
+var (
+	semesters = map[string]bool{
+		"":                             true,
+		model.IOSLectureSemesterWinter: true,
+		model.IOSLectureSemesterSummer: true,
+	}
+	deviceStates = map[string]bool{
+		"":                               true,
+		model.IOSDeviceStateActive:       true,
+		model.IOSDeviceStateSuspect:      true,
+		model.IOSDeviceStateUnregistered: true,
+	}
+	requestTypes = map[string]bool{
+		model.IOSTokenRequestType:         true,
+		model.IOSLectureUpdateRequestType: true,
+	}
+	requestStatuses = map[string]bool{
+		model.IOSRequestStatusCreated:        true,
+		model.IOSRequestStatusSent:           true,
+		model.IOSRequestStatusDeliveryFailed: true,
+		model.IOSRequestStatusHandled:        true,
+		model.IOSRequestStatusExpired:        true,
+		model.IOSRequestStatusSuperseded:     true,
+	}
+)
+
+// Validate checks the version, that the IDs are unique and that the
+// enrollments and requests refer to lectures and devices of the scenario.
+func (s *Scenario) Validate() error {
+	if s.Version != Version {
+		return fmt.Errorf("unsupported scenario version %d, expected %d", s.Version, Version)
+	}
+
+	lectures := make(map[string]bool, len(s.Lectures))
+
+	for _, lecture := range s.Lectures {
+		if lecture.ID == "" || lectures[lecture.ID] {
+			return fmt.Errorf("lecture %q is empty or duplicated", lecture.ID)
+		}
+
+		if !semesters[lecture.Semester] {
+			return fmt.Errorf("lecture %s has unknown semester %q", lecture.ID, lecture.Semester)
+		}
+
+		lectures[lecture.ID] = true
+	}
+
+	devices := make(map[string]bool, len(s.Devices))
+
+	for _, device := range s.Devices {
+		if device.ID == "" || devices[device.ID] {
+			return fmt.Errorf("device %q is empty or duplicated", device.ID)
+		}
+
+		if !deviceStates[device.State] {
+			return fmt.Errorf("device %s has unknown state %q", device.ID, device.State)
+		}
+
+		enrolled := make(map[string]bool, len(device.Lectures))
+
+		for _, lectureId := range device.Lectures {
+			if !lectures[lectureId] || enrolled[lectureId] {
+				return fmt.Errorf("device %s is enrolled in unknown or duplicated lecture %q", device.ID, lectureId)
+			}
+
+			enrolled[lectureId] = true
+		}
+
+		devices[device.ID] = true
+	}
+
+	requests := make(map[string]bool, len(s.Requests))
+
+	for _, request := range s.Requests {
+		if request.ID == "" || requests[request.ID] {
+			return fmt.Errorf("request %q is empty or duplicated", request.ID)
+		}
+
+		if !devices[request.DeviceID] {
+			return fmt.Errorf("request %s is for unknown device %q", request.ID, request.DeviceID)
+		}
+
+		if !requestTypes[request.Type] {
+			return fmt.Errorf("request %s has unknown type %q", request.ID, request.Type)
+		}
+
+		if !requestStatuses[request.Status] {
+			return fmt.Errorf("request %s has unknown status %q", request.ID, request.Status)
+		}
+
+		requests[request.ID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package scenario
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"test-student-lecture-selection-algorithm/model"
+	"testing"
+	"time"
+)
+
+// testScenario returns a scenario that sets every field, so that a round
+// trip that loses a field is noticed.
+func testScenario() *Scenario {
+	capturedAt := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := capturedAt.Add(-d)
+		return &t
+	}
+
+	return &Scenario{
+		Version:     Version,
+		Name:        "test",
+		Description: "every field is set",
+		CapturedAt:  capturedAt,
+		Lectures: []Lecture{
+			{ID: "IN0001", Year: 2026, Semester: model.IOSLectureSemesterWinter, LastUpdate: at(time.Hour)},
+			{ID: "MA0001", Year: 2026, Semester: model.IOSLectureSemesterWinter, LastUpdate: at(2 * time.Hour)},
+		},
+		Devices: []Device{
+			{ID: "device-a", State: model.IOSDeviceStateActive, CreatedAt: at(48 * time.Hour), PublicKey: "key-a", Lectures: []string{"IN0001", "MA0001"}},
+			{ID: "device-b", State: model.IOSDeviceStateSuspect, CreatedAt: at(24 * time.Hour), StateChangedAt: at(3 * time.Hour), PublicKey: "key-b", Lectures: []string{"MA0001"}},
+			{ID: "device-c", State: model.IOSDeviceStateActive, CreatedAt: at(24 * time.Hour), Lectures: []string{}},
+		},
+		Requests: []Request{
+			{ID: "request-1", DeviceID: "device-a", Type: model.IOSLectureUpdateRequestType, Status: model.IOSRequestStatusHandled, CreatedAt: *at(time.Hour), HandledAt: at(59 * time.Minute), ExpiresAt: at(30 * time.Minute), Attempts: 1},
+			{ID: "request-2", DeviceID: "device-b", Type: model.IOSTokenRequestType, Status: model.IOSRequestStatusDeliveryFailed, CreatedAt: *at(10 * time.Minute), ExpiresAt: at(-50 * time.Minute), Attempts: 2},
+		},
+		Priorities: []model.IOSSchedulingPriority{
+			{ID: 1, FromMonth: 12, FromMonthDay: 20, ToMonth: 1, ToMonthDay: 7, FromHour: 0, ToHour: 23, TimeZone: "Europe/Berlin", Priority: 1},
+		},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	s := testScenario()
+
+	var buffer bytes.Buffer
+
+	if err := s.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(&buffer)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read, s) {
+		t.Errorf("expected %+v, got %+v", s, read)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	s := testScenario()
+	path := filepath.Join(t.TempDir(), "test.json")
+
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("expected %+v, got %+v", s, loaded)
+	}
+}
+
+func TestReadRejectsInvalidScenarios(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"unknown field", `{"version": 1, "lectures": [], "devices": [], "grades": []}`},
+		{"other version", `{"version": 2, "lectures": [], "devices": []}`},
+		{"duplicated lecture", `{"version": 1, "lectures": [{"id": "a"}, {"id": "a"}], "devices": []}`},
+		{"unknown semester", `{"version": 1, "lectures": [{"id": "a", "semester": "spring"}], "devices": []}`},
+		{"unknown state", `{"version": 1, "lectures": [], "devices": [{"id": "d", "state": "asleep", "lectures": []}]}`},
+		{"unknown lecture", `{"version": 1, "lectures": [], "devices": [{"id": "d", "lectures": ["a"]}]}`},
+		{"duplicated enrollment", `{"version": 1, "lectures": [{"id": "a"}], "devices": [{"id": "d", "lectures": ["a", "a"]}]}`},
+		{"unknown device", `{"version": 1, "lectures": [], "devices": [], "requests": [{"id": "r", "deviceId": "d", "type": "LECTURE_UPDATE_REQUEST", "status": "sent", "createdAt": "2026-10-01T12:00:00Z"}]}`},
+		{"unknown status", `{"version": 1, "lectures": [], "devices": [{"id": "d", "lectures": []}], "requests": [{"id": "r", "deviceId": "d", "type": "LECTURE_UPDATE_REQUEST", "status": "lost", "createdAt": "2026-10-01T12:00:00Z"}]}`},
+	}
+
+	for _, test := range tests {
+		if _, err := Read(strings.NewReader(test.json)); err == nil {
+			t.Errorf("%s: scenario was accepted", test.name)
+		}
+	}
+}
+
+func TestBundledScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "scenarios", "*.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) == 0 {
+		t.Fatal("no bundled scenarios found")
+	}
+
+	for _, path := range paths {
+		if _, err := Load(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestSolverInputSkipsInactiveDevices(t *testing.T) {
+	lectures, devicesLectures := testScenario().SolverInput()
+
+	if len(*lectures) != 2 {
+		t.Errorf("expected 2 lectures, got %d", len(*lectures))
+	}
+
+	if _, ok := (*devicesLectures)["device-b"]; ok {
+		t.Error("suspect device-b is part of the solver input")
+	}
+
+	if len((*devicesLectures)["device-a"]) != 2 {
+		t.Errorf("device-a covers %d instead of 2 lectures", len((*devicesLectures)["device-a"]))
+	}
+}
@@ -0,0 +1,33 @@
+package scenario
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/solver"
+)
+
+// SolverInput builds the input of the solver from the scenario like
+// FindPerfectMatch does from a repository: all lectures and the enrollments
+// of the active devices.
+func (s *Scenario) SolverInput() (*solver.LectureToOverlapped, *solver.DeviceToOverlappedLectures) {
+	lectures := make([]model.IOSLecture, 0, len(s.Lectures))
+
+	for _, lecture := range s.Lectures {
+		lectures = append(lectures, model.IOSLecture{Id: lecture.ID})
+	}
+
+	var deviceLectures []model.IOSDeviceLecture
+
+	for _, device := range s.Devices {
+		if !(&model.IOSDevice{State: device.State}).IsActive() {
+			continue
+		}
+
+		for _, lectureId := range device.Lectures {
+			deviceLectures = append(deviceLectures, model.IOSDeviceLecture{DeviceId: device.ID, LectureId: lectureId})
+		}
+	}
+
+	lecturesMap := solver.LectureToOverlappedLectureMap(&lectures)
+
+	return lecturesMap, solver.DevicesLecturesToMap(&deviceLectures, lecturesMap)
+}
@@ -0,0 +1,224 @@
+// Package scheduler runs the solve-and-dispatch pipeline continuously. How
+// often it runs and how many devices it may push to is derived from the
+// IOSSchedulingPriority that is active at the moment.
+package scheduler
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/dispatch"
+	"test-student-lecture-selection-algorithm/grades"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/scheduling"
+	"time"
+)
+
+type Config struct {
+	// Tick is how often the active priority is evaluated.
+	Tick time.Duration
+	// BaseInterval is the time between two runs at the default priority.
+	BaseInterval time.Duration
+	// BaseBudget is the push budget of a run at the default priority.
+	BaseBudget int
+	// Dispatch configures the rounds of a run. Its Budget is overwritten by
+	// the budget of the active priority.
+	Dispatch dispatch.Config
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Tick:         time.Minute,
+		BaseInterval: time.Hour,
+		BaseBudget:   1000,
+		Dispatch:     dispatch.DefaultConfig(),
+	}
+}
+
+// Plan is how often and how aggressively the scheduler runs at a priority.
+type Plan struct {
+	Priority int
+	Interval time.Duration
+	Budget   int
+}
+
+// Paused returns true if no runs are scheduled at the priority.
+func (p *Plan) Paused() bool {
+	return p.Priority <= 0
+}
+
+// PlanFor maps a priority to a Plan. The default priority runs every
+// BaseInterval with BaseBudget pushes, a twice as high priority runs twice as
+// often with twice the budget. Priorities of zero or below pause the
+// scheduler.
+func (c *Config) PlanFor(priority int) Plan {
+	plan := Plan{Priority: priority}
+
+	if priority <= 0 {
+		return plan
+	}
+
+	defaultPriority := model.DefaultIOSSchedulingPriority().Priority
+
+	plan.Interval = c.BaseInterval * time.Duration(defaultPriority) / time.Duration(priority)
+	plan.Budget = c.BaseBudget * priority / defaultPriority
+
+	if plan.Budget < 1 {
+		plan.Budget = 1
+	}
+
+	return plan
+}
+
+type Scheduler struct {
+	Config     Config
+	Repository repository.Repository
+	Committer  dispatch.Committer
+	Sender     dispatch.Sender
+	Responses  dispatch.Responses
+	// BeforeRun is called before every run, e.g. to expire stale requests.
+	BeforeRun func(ctx context.Context) error
+	// Clock tells the time the active priority is resolved for.
+	Clock scheduling.Clock
+	// GradePlanner requests grade refreshes every tick if it is set.
+	GradePlanner *grades.Planner
+
+	lastRun time.Time
+}
+
+func NewScheduler(
+	config Config,
+	repo repository.Repository,
+	committer dispatch.Committer,
+	sender dispatch.Sender,
+	responses dispatch.Responses,
+) *Scheduler {
+	return &Scheduler{
+		Config:     config,
+		Repository: repo,
+		Committer:  committer,
+		Sender:     sender,
+		Responses:  responses,
+		Clock:      scheduling.SystemClock{},
+	}
+}
+
+// Run evaluates the active priority every Tick and starts a run once the
+// interval of its plan has passed. It returns when ctx is canceled, e.g. on
+// SIGTERM. A run in progress stops waiting for answers, the requests it
+// created are expired by the sweeper.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Config.Tick)
+	defer ticker.Stop()
+
+	log.Infof("Scheduler started, evaluating priorities every %s", s.Config.Tick)
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	now := s.Clock.Now()
+	priorities, err := s.Repository.GetSchedulingPriorities()
+
+	if err != nil {
+		log.WithError(err).Error("Failed to load scheduling priorities, skipping tick")
+		return
+	}
+
+	priority := scheduling.Resolve(priorities, now)
+	plan := s.Config.PlanFor(priority.Priority)
+
+	if s.GradePlanner != nil {
+		s.planGrades(ctx, priority)
+	}
+
+	if plan.Paused() {
+		log.Debugf("Scheduler paused by priority %s", priority)
+		return
+	}
+
+	if !s.lastRun.IsZero() && now.Sub(s.lastRun) < plan.Interval {
+		return
+	}
+
+	s.lastRun = now
+
+	log.Infof("Starting run with priority %s (interval %s, budget %d)", priority, plan.Interval, plan.Budget)
+
+	if err := s.RunOnce(ctx, plan); err != nil {
+		log.WithError(err).Error("Scheduled run failed")
+	}
+}
+
+func (s *Scheduler) planGrades(ctx context.Context, priority *model.IOSSchedulingPriority) {
+	requests, err := s.GradePlanner.Plan(priority)
+
+	if err != nil {
+		log.WithError(err).Error("Grade refresh planning failed")
+		return
+	}
+
+	if len(*requests) == 0 {
+		return
+	}
+
+	if err := s.Sender.Send(ctx, 0, requests); err != nil {
+		log.WithError(err).Error("Failed to send grade refresh requests")
+	}
+}
+
+// RunOnce solves and dispatches with the budget of plan and records the run
+// in IOSScheduledUpdateLog.
+func (s *Scheduler) RunOnce(ctx context.Context, plan Plan) error {
+	startTime := time.Now()
+
+	if s.BeforeRun != nil {
+		if err := s.BeforeRun(ctx); err != nil {
+			return err
+		}
+	}
+
+	config := s.Config.Dispatch
+	config.Budget = plan.Budget
+
+	orchestrator := dispatch.NewOrchestrator(config, s.Committer, s.Sender, s.Responses)
+
+	lectures, err := s.Repository.GetLectures()
+
+	if err != nil {
+		return err
+	}
+
+	deviceLectures, err := s.Repository.GetReadyDeviceLectures()
+
+	if err != nil {
+		return err
+	}
+
+	result, err := orchestrator.Run(ctx, lectures, deviceLectures)
+
+	if result != nil {
+		if err := s.Repository.RecordScheduledRun(model.IOSUpdateTypeLectures, startTime); err != nil {
+			log.WithError(err).Error("Failed to record scheduled run")
+		}
+
+		log.Infof(
+			"Run finished: refreshed %d/%d lectures with %d pushes in %s",
+			result.Refreshed,
+			result.Lectures,
+			len(result.Pushed),
+			time.Now().Sub(startTime),
+		)
+	}
+
+	return err
+}
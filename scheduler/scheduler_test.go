@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"test-student-lecture-selection-algorithm/dispatch"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestPlanFor(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseInterval = time.Hour
+	config.BaseBudget = 100
+
+	tests := []struct {
+		priority int
+		plan     Plan
+	}{
+		{5, Plan{Priority: 5, Interval: time.Hour, Budget: 100}},
+		{10, Plan{Priority: 10, Interval: 30 * time.Minute, Budget: 200}},
+		{1, Plan{Priority: 1, Interval: 5 * time.Hour, Budget: 20}},
+		{0, Plan{Priority: 0}},
+		{-1, Plan{Priority: -1}},
+	}
+
+	for _, test := range tests {
+		if plan := config.PlanFor(test.priority); plan != test.plan {
+			t.Errorf("priority %d: expected %+v, got %+v", test.priority, test.plan, plan)
+		}
+	}
+
+	if plan := config.PlanFor(0); !plan.Paused() {
+		t.Error("priority 0 does not pause the scheduler")
+	}
+}
+
+func TestRunOnceLogsTheRun(t *testing.T) {
+	repo := memory.New()
+	devices := []model.IOSDevice{{DeviceID: "device-a"}, {DeviceID: "device-b"}, {DeviceID: "device-c"}}
+	lectures := []model.IOSLecture{
+		{Id: "lecture-1", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-2", Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-3", Semester: model.IOSLectureSemesterWinter},
+	}
+	deviceLectures := []model.IOSDeviceLecture{
+		{DeviceId: "device-a", LectureId: "lecture-1"},
+		{DeviceId: "device-b", LectureId: "lecture-2"},
+		{DeviceId: "device-c", LectureId: "lecture-3"},
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&lectures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDeviceLectures(&deviceLectures); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Dispatch.MaxRounds = 1
+	config.Dispatch.Timeout = time.Millisecond
+	config.Dispatch.PollInterval = time.Millisecond
+
+	s := NewScheduler(
+		config,
+		repo,
+		dispatch.RepositoryCommitter{Repository: repo},
+		dispatch.LogSender{},
+		dispatch.RepositoryResponses{Repository: repo},
+	)
+
+	if err := s.RunOnce(context.Background(), config.PlanFor(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	updateLogs, err := repo.GetScheduledUpdateLogs(model.IOSUpdateTypeLectures)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*updateLogs) != 1 || (*updateLogs)[0].DeviceID != "" {
+		t.Errorf("expected a single log of the run, got %v", *updateLogs)
+	}
+}
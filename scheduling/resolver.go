@@ -0,0 +1,54 @@
+// Package scheduling resolves which IOSSchedulingPriority applies at a given
+// instant.
+//
+// Of all priorities whose range contains the instant the winner is chosen by:
+//
+//  1. Specificity: the priority with the smaller Span wins.
+//  2. Priority: the higher Priority wins.
+//  3. ID: the lower ID wins.
+//
+// The order is total, so the result does not depend on the order in which
+// the priorities are loaded. If no priority is in range
+// DefaultIOSSchedulingPriority applies.
+package scheduling
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+// Precedes returns true if p wins over other when both are in range.
+func Precedes(p *model.IOSSchedulingPriority, other *model.IOSSchedulingPriority) bool {
+	if p.Span() != other.Span() {
+		return p.Span() < other.Span()
+	}
+
+	if p.Priority != other.Priority {
+		return p.Priority > other.Priority
+	}
+
+	return p.ID < other.ID
+}
+
+// Resolve returns the priority that applies at t.
+func Resolve(priorities *[]model.IOSSchedulingPriority, t time.Time) *model.IOSSchedulingPriority {
+	var winner *model.IOSSchedulingPriority
+
+	for i := range *priorities {
+		priority := &(*priorities)[i]
+
+		if !priority.IsInRangeAt(t) {
+			continue
+		}
+
+		if winner == nil || Precedes(priority, winner) {
+			winner = priority
+		}
+	}
+
+	if winner == nil {
+		return model.DefaultIOSSchedulingPriority()
+	}
+
+	return winner
+}
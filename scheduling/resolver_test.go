@@ -0,0 +1,151 @@
+package scheduling
+
+import (
+	"test-student-lecture-selection-algorithm/model"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	night := func(id int, priority int) model.IOSSchedulingPriority {
+		return model.IOSSchedulingPriority{ID: id, FromDay: 1, ToDay: 366, FromHour: 22, ToHour: 1, TimeZone: "Europe/Berlin", Priority: priority}
+	}
+	allDay := func(id int, priority int) model.IOSSchedulingPriority {
+		return model.IOSSchedulingPriority{ID: id, FromDay: 1, ToDay: 366, FromHour: 0, ToHour: 23, TimeZone: "Europe/Berlin", Priority: priority}
+	}
+
+	// A step advances the clock by advance and expects the priority with
+	// the ID id to win, zero is the default priority.
+	type step struct {
+		advance time.Duration
+		id      int
+	}
+
+	tests := []struct {
+		name       string
+		priorities []model.IOSSchedulingPriority
+		start      time.Time
+		steps      []step
+	}{
+		{
+			name:       "smaller span wins across midnight",
+			priorities: []model.IOSSchedulingPriority{allDay(1, 9), night(2, 1)},
+			start:      time.Date(2026, time.June, 30, 21, 30, 0, 0, berlin),
+			steps:      []step{{0, 1}, {time.Hour, 2}, {time.Hour, 2}, {time.Hour, 2}, {time.Hour, 2}, {time.Hour, 1}},
+		},
+		{
+			name:       "higher priority then lower id wins",
+			priorities: []model.IOSSchedulingPriority{night(1, 2), night(5, 4), night(4, 4)},
+			start:      time.Date(2026, time.June, 30, 21, 30, 0, 0, berlin),
+			steps:      []step{{0, 0}, {time.Hour, 4}, {2 * time.Hour, 4}, {2 * time.Hour, 0}},
+		},
+		{
+			name: "calendar dates across new year",
+			priorities: []model.IOSSchedulingPriority{
+				allDay(1, 1),
+				{ID: 2, FromMonth: 12, FromMonthDay: 20, ToMonth: 1, ToMonthDay: 7, FromHour: 0, ToHour: 23, TimeZone: "Europe/Berlin", Priority: 8},
+			},
+			start: time.Date(2026, time.December, 19, 23, 30, 0, 0, berlin),
+			steps: []step{{0, 1}, {time.Hour, 2}, {12 * 24 * time.Hour, 2}, {6 * 24 * time.Hour, 2}, {24 * time.Hour, 1}},
+		},
+		{
+			name: "weekend across midnight",
+			priorities: []model.IOSSchedulingPriority{
+				allDay(1, 9),
+				{ID: 2, FromDay: 1, ToDay: 366, FromHour: 0, ToHour: 23, Weekdays: model.IOSWeekdaysWeekend, TimeZone: "Europe/Berlin", Priority: 1},
+			},
+			// Friday
+			start: time.Date(2026, time.November, 6, 23, 30, 0, 0, berlin),
+			steps: []step{{0, 1}, {time.Hour, 2}, {24 * time.Hour, 2}, {24 * time.Hour, 1}},
+		},
+		{
+			// The clocks move from 02:00 to 03:00 at 01:00 UTC, the hour 2
+			// is skipped.
+			name: "daylight saving time starts",
+			priorities: []model.IOSSchedulingPriority{
+				{ID: 1, FromDay: 1, ToDay: 366, FromHour: 2, ToHour: 2, TimeZone: "Europe/Berlin", Priority: 9},
+				{ID: 2, FromDay: 1, ToDay: 366, FromHour: 0, ToHour: 5, TimeZone: "Europe/Berlin", Priority: 1},
+			},
+			start: time.Date(2026, time.March, 29, 0, 30, 0, 0, time.UTC),
+			steps: []step{{0, 2}, {30 * time.Minute, 2}, {30 * time.Minute, 2}, {30 * time.Minute, 2}, {3 * time.Hour, 0}},
+		},
+		{
+			// The clocks move from 03:00 back to 02:00 at 01:00 UTC, the hour
+			// 2 is passed twice.
+			name: "daylight saving time ends",
+			priorities: []model.IOSSchedulingPriority{
+				{ID: 1, FromDay: 1, ToDay: 366, FromHour: 2, ToHour: 2, TimeZone: "Europe/Berlin", Priority: 9},
+				{ID: 2, FromDay: 1, ToDay: 366, FromHour: 0, ToHour: 5, TimeZone: "Europe/Berlin", Priority: 1},
+			},
+			start: time.Date(2026, time.October, 24, 23, 30, 0, 0, time.UTC),
+			steps: []step{{0, 2}, {time.Hour, 1}, {time.Hour, 1}, {time.Hour, 2}},
+		},
+		{
+			name: "time zone of the priority",
+			priorities: []model.IOSSchedulingPriority{
+				{ID: 1, FromDay: 1, ToDay: 366, FromHour: 22, ToHour: 1, TimeZone: "UTC", Priority: 1},
+				{ID: 2, FromDay: 1, ToDay: 366, FromHour: 22, ToHour: 1, TimeZone: "America/New_York", Priority: 1},
+			},
+			start: time.Date(2026, time.July, 1, 0, 30, 0, 0, time.UTC),
+			steps: []step{{0, 1}, {2 * time.Hour, 2}, {2 * time.Hour, 2}, {2 * time.Hour, 0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reversed := make([]model.IOSSchedulingPriority, 0, len(test.priorities))
+
+			for i := len(test.priorities) - 1; i >= 0; i-- {
+				reversed = append(reversed, test.priorities[i])
+			}
+
+			clock := NewFixedClock(test.start)
+
+			for _, step := range test.steps {
+				clock.Advance(step.advance)
+				now := clock.Now()
+
+				for _, priorities := range [][]model.IOSSchedulingPriority{test.priorities, reversed} {
+					winner := Resolve(&priorities, now)
+
+					if winner.ID != step.id {
+						t.Errorf("at %s priority %d won instead of %d", now.In(berlin), winner.ID, step.id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPrecedes(t *testing.T) {
+	narrow := model.IOSSchedulingPriority{ID: 3, FromDay: 1, ToDay: 366, FromHour: 6, ToHour: 8, Priority: 1}
+	wide := model.IOSSchedulingPriority{ID: 1, FromDay: 1, ToDay: 366, FromHour: 0, ToHour: 23, Priority: 9}
+	higher := model.IOSSchedulingPriority{ID: 4, FromDay: 1, ToDay: 366, FromHour: 6, ToHour: 8, Priority: 2}
+	lowerId := model.IOSSchedulingPriority{ID: 2, FromDay: 1, ToDay: 366, FromHour: 6, ToHour: 8, Priority: 1}
+
+	tests := []struct {
+		name  string
+		p     model.IOSSchedulingPriority
+		other model.IOSSchedulingPriority
+	}{
+		{"smaller span", narrow, wide},
+		{"higher priority", higher, narrow},
+		{"lower id", lowerId, narrow},
+	}
+
+	for _, test := range tests {
+		if !Precedes(&test.p, &test.other) {
+			t.Errorf("%s: %s does not precede %s", test.name, &test.p, &test.other)
+		}
+
+		if Precedes(&test.other, &test.p) {
+			t.Errorf("%s: %s precedes %s both ways", test.name, &test.other, &test.p)
+		}
+	}
+}
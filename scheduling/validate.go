@@ -0,0 +1,135 @@
+package scheduling
+
+import (
+	"fmt"
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+	"time"
+)
+
+const (
+	// IssueAmbiguous is reported for two priorities that are in range at
+	// the same time and only differ in their ID.
+	IssueAmbiguous = "ambiguous"
+	// IssueShadowed is reported for a priority that is in range at some
+	// time but never wins.
+	IssueShadowed = "shadowed"
+	// IssueNeverInRange is reported for a priority whose range is empty.
+	IssueNeverInRange = "never in range"
+	// IssueInvalid is reported for a priority with a field out of range,
+	// e.g. an unknown time zone.
+	IssueInvalid = "invalid"
+)
+
+// Issue is a problem found in a set of priorities.
+type Issue struct {
+	Kind     string
+	Priority model.IOSSchedulingPriority
+	// Other is the priority that wins over Priority for ambiguous issues.
+	Other *model.IOSSchedulingPriority
+	// At is an example instant at which the issue shows.
+	At time.Time
+	// Err is the validation error of invalid issues.
+	Err error
+}
+
+func (i *Issue) String() string {
+	switch i.Kind {
+	case IssueAmbiguous:
+		return fmt.Sprintf(
+			"priority %d (%s) is ambiguous with priority %d (%s), e.g. at %s, only the ID decides",
+			i.Priority.ID, &i.Priority, i.Other.ID, i.Other, i.At.Format(time.RFC3339),
+		)
+	case IssueInvalid:
+		return fmt.Sprintf("priority %d (%s) is invalid: %s", i.Priority.ID, &i.Priority, i.Err)
+	case IssueShadowed:
+		return fmt.Sprintf("priority %d (%s) is shadowed by other priorities and never applies", i.Priority.ID, &i.Priority)
+	default:
+		return fmt.Sprintf("priority %d (%s) is never in range", i.Priority.ID, &i.Priority)
+	}
+}
+
+// referenceYear is a leap year, so that every year day is checked.
+const referenceYear = 2024
+
+// Validate resolves the priorities for every hour of a leap year and reports
+// invalid, ambiguous, shadowed and empty priorities. Weekday masks are
+// evaluated for the weekdays of the reference year only.
+func Validate(priorities *[]model.IOSSchedulingPriority) []Issue {
+	var issues []Issue
+
+	for _, priority := range *priorities {
+		if err := priority.Validate(); err != nil {
+			issues = append(issues, Issue{Kind: IssueInvalid, Priority: priority, Err: err})
+		}
+	}
+
+	inRange := make(map[int]bool)
+	wins := make(map[int]bool)
+	ambiguous := make(map[[2]int]bool)
+
+	// Every priority is evaluated in its own time zone, so stepping through
+	// the hours in UTC covers every local hour once per priority, apart from
+	// the ones skipped by daylight saving time.
+	start := time.Date(referenceYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	for t := start; t.Before(end); t = t.Add(time.Hour) {
+		var candidates []int
+
+		for i := range *priorities {
+			if (*priorities)[i].IsInRangeAt(t) {
+				candidates = append(candidates, i)
+				inRange[i] = true
+			}
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.Slice(candidates, func(a, b int) bool {
+			return Precedes(&(*priorities)[candidates[a]], &(*priorities)[candidates[b]])
+		})
+
+		wins[candidates[0]] = true
+
+		if len(candidates) == 1 {
+			continue
+		}
+
+		winner, second := &(*priorities)[candidates[0]], &(*priorities)[candidates[1]]
+
+		if winner.Span() != second.Span() || winner.Priority != second.Priority {
+			continue
+		}
+
+		pair := [2]int{winner.ID, second.ID}
+
+		if ambiguous[pair] {
+			continue
+		}
+
+		ambiguous[pair] = true
+		other := *winner
+
+		issues = append(issues, Issue{
+			Kind:     IssueAmbiguous,
+			Priority: *second,
+			Other:    &other,
+			At:       t,
+		})
+	}
+
+	for i, priority := range *priorities {
+		switch {
+		case priority.Validate() != nil:
+		case !inRange[i]:
+			issues = append(issues, Issue{Kind: IssueNeverInRange, Priority: priority})
+		case !wins[i]:
+			issues = append(issues, Issue{Kind: IssueShadowed, Priority: priority})
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,88 @@
+package selection
+
+import (
+	"sort"
+	"test-student-lecture-selection-algorithm/loader"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+// LectureStatus tells how current the data of a lecture is and whether a
+// ready device can refresh it.
+type LectureStatus struct {
+	ID         string    `json:"id"`
+	Year       int16     `json:"year"`
+	Semester   string    `json:"semester"`
+	LastUpdate time.Time `json:"lastUpdate"`
+	// Staleness is the age of the data in seconds.
+	Staleness     float64 `json:"stalenessSeconds"`
+	LastRequestID *string `json:"lastRequestId,omitempty"`
+	// ReadyDevices is the number of ready devices enrolled in the lecture,
+	// the lecture can't be refreshed without any.
+	ReadyDevices int `json:"readyDevices"`
+}
+
+// Coverage summarizes the statuses of the lectures.
+type Coverage struct {
+	Lectures int `json:"lectures"`
+	// Coverable is the number of lectures with a ready device.
+	Coverable int `json:"coverable"`
+	// MaxStaleness and MedianStaleness are in seconds.
+	MaxStaleness    float64         `json:"maxStalenessSeconds"`
+	MedianStaleness float64         `json:"medianStalenessSeconds"`
+	Statuses        []LectureStatus `json:"statuses"`
+}
+
+// Lectures returns the status of the lectures of the term at now, of all
+// lectures for a nil term, the stalest first.
+func Lectures(repo repository.Repository, term *Term, now time.Time, config loader.Config) (*Coverage, error) {
+	allLectures, err := repo.GetLectures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lectures := term.Filter(allLectures)
+	readyDevices := make(map[string]int, len(*lectures))
+	devicesLectures, err := loader.NewLoader(config, repo).ReadyDevicesLectures(solver.LectureToOverlappedLectureMap(lectures))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deviceLectures := range *devicesLectures {
+		for _, lecture := range deviceLectures {
+			readyDevices[lecture.LectureId]++
+		}
+	}
+
+	coverage := Coverage{Lectures: len(*lectures), Statuses: make([]LectureStatus, 0, len(*lectures))}
+
+	for _, lecture := range *lectures {
+		coverage.Statuses = append(coverage.Statuses, LectureStatus{
+			ID:            lecture.Id,
+			Year:          lecture.Year,
+			Semester:      lecture.Semester,
+			LastUpdate:    lecture.LastUpdate,
+			Staleness:     now.Sub(lecture.LastUpdate).Seconds(),
+			LastRequestID: lecture.LastRequestId,
+			ReadyDevices:  readyDevices[lecture.Id],
+		})
+
+		if readyDevices[lecture.Id] > 0 {
+			coverage.Coverable++
+		}
+	}
+
+	sort.SliceStable(coverage.Statuses, func(i, j int) bool {
+		return coverage.Statuses[i].Staleness > coverage.Statuses[j].Staleness
+	})
+
+	if len(coverage.Statuses) > 0 {
+		coverage.MaxStaleness = coverage.Statuses[0].Staleness
+		coverage.MedianStaleness = coverage.Statuses[len(coverage.Statuses)/2].Staleness
+	}
+
+	return &coverage, nil
+}
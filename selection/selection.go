@@ -0,0 +1,266 @@
+// Package selection runs the pipeline answering which devices to poll: it
+// loads the lectures and the enrollments of the ready devices, solves, persists
+// the selection as lecture update requests if asked to and verifies it. The
+// command line and the HTTP API share it.
+package selection
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/dispatch"
+	"test-student-lecture-selection-algorithm/loader"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/result"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+// Term is a semester of a year.
+type Term struct {
+	Year     int16  `json:"year"`
+	Semester string `json:"semester"`
+}
+
+// Validate returns an error if t is no valid term.
+func (t *Term) Validate() error {
+	if t.Year < 1 {
+		return fmt.Errorf("invalid year %d", t.Year)
+	}
+
+	if t.Semester != model.IOSLectureSemesterWinter && t.Semester != model.IOSLectureSemesterSummer {
+		return fmt.Errorf("semester has to be %s or %s, got %q", model.IOSLectureSemesterWinter, model.IOSLectureSemesterSummer, t.Semester)
+	}
+
+	return nil
+}
+
+// Filter returns the lectures of the term, all lectures for a nil term.
+func (t *Term) Filter(lectures *[]model.IOSLecture) *[]model.IOSLecture {
+	if t == nil {
+		return lectures
+	}
+
+	var filtered []model.IOSLecture
+
+	for _, lecture := range *lectures {
+		if lecture.Year == t.Year && lecture.Semester == t.Semester {
+			filtered = append(filtered, lecture)
+		}
+	}
+
+	return &filtered
+}
+
+type Options struct {
+	// Solver is the name of the registered solver.
+	Solver string
+	// Commit persists the selection as lecture update requests.
+	Commit bool
+	// Term restricts the selection to the lectures of a term, nil selects
+	// all lectures.
+	Term *Term
+	// Budget is the maximum number of selected devices, zero means no
+	// limit. The devices covering the most lectures are kept, see
+	// solver.Truncate.
+	Budget int
+	Loader loader.Config
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Solver: "greedy",
+		Loader: loader.DefaultConfig(),
+	}
+}
+
+// Validate returns an error describing the first invalid option.
+func (o *Options) Validate() error {
+	if _, err := solver.Get(o.Solver); err != nil {
+		return err
+	}
+
+	if o.Budget < 0 {
+		return fmt.Errorf("negative budget %d", o.Budget)
+	}
+
+	if o.Term != nil {
+		return o.Term.Validate()
+	}
+
+	return nil
+}
+
+// Outcome is the result of a run together with its explanation.
+type Outcome struct {
+	Run         *result.Run
+	Explanation *result.Explanation
+}
+
+// Run selects the devices covering the lectures. The verification checks the
+// selection of the solver before the budget is applied, the lectures left
+// out by the budget are reported as uncovered.
+func Run(repo repository.Repository, options Options) (*Outcome, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	solve, err := solver.Get(options.Solver)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalStartTime := time.Now()
+	run := result.Run{
+		Version:   result.Version,
+		ID:        model.NewRequestID(),
+		StartedAt: totalStartTime,
+		Solver:    options.Solver,
+		Budget:    options.Budget,
+	}
+
+	if options.Term != nil {
+		run.Term = fmt.Sprintf("%s %d", options.Term.Semester, options.Term.Year)
+	}
+
+	allLectures, err := repo.GetLectures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lectures := options.Term.Filter(allLectures)
+
+	queryTime := time.Now().Sub(totalStartTime)
+	buildStartTime := time.Now()
+	lecturesToOverlappedMap := solver.LectureToOverlappedLectureMap(lectures)
+	buildTime := time.Now().Sub(buildStartTime)
+	enrollmentLoader := loader.NewLoader(options.Loader, repo)
+	devicesLecturesMap, err := enrollmentLoader.ReadyDevicesLectures(lecturesToOverlappedMap)
+
+	if err != nil {
+		return nil, err
+	}
+
+	run.Timings.Query = result.Milliseconds(queryTime + enrollmentLoader.Last.Elapsed - enrollmentLoader.Last.Building)
+	run.Timings.Build = result.Milliseconds(buildTime + enrollmentLoader.Last.Building)
+
+	log.Infof("Time to load: %s", time.Now().Sub(totalStartTime))
+
+	log.Infof("Lecture count: %d", len(*lectures))
+	log.Infof("Enrolled device count: %d", len(*devicesLecturesMap))
+
+	log.Infof("------------------")
+
+	log.Infof("Searching for perfect student set...")
+
+	enrolled := solver.Enrolled(devicesLecturesMap)
+	lowerBound := solver.LowerBound(devicesLecturesMap)
+	run.Instance = result.NewInstance(len(*lectures), enrolled)
+
+	startTime := time.Now()
+
+	overlappingStudents := solve(devicesLecturesMap, lecturesToOverlappedMap)
+
+	run.Timings.Search = result.Milliseconds(time.Now().Sub(startTime))
+
+	log.Infof("Found perfect set: %d (students) in %s", len(*overlappingStudents), time.Now().Sub(startTime))
+
+	verifyStartTime := time.Now()
+
+	if err := solver.Verify(overlappingStudents, enrolled); err != nil {
+		run.Verification.Error = err.Error()
+		log.WithError(err).Warn("The perfect set is not valid")
+	} else {
+		run.Verification.Valid = true
+	}
+
+	if options.Budget > 0 && len(*overlappingStudents) > options.Budget {
+		log.Infof("Keeping %d of %d devices within the budget", options.Budget, len(*overlappingStudents))
+
+		overlappingStudents = solver.Truncate(overlappingStudents, options.Budget)
+	}
+
+	run.SetAssignments(overlappingStudents, lowerBound)
+
+	if options.Commit {
+		requests, err := dispatch.RepositoryCommitter{Repository: repo}.Commit(overlappingStudents)
+
+		if err != nil {
+			return nil, err
+		}
+
+		run.Requests = len(*requests)
+
+		log.Infof("Created %d lecture update requests", len(*requests))
+	}
+
+	log.Infof("------------------")
+
+	log.Infof("Total execution time: %s", time.Now().Sub(totalStartTime))
+
+	log.Infof("------------------")
+
+	log.Infof("Checking if all lectures are covered...")
+
+	covered, enrolledCount, err := checkIfAggregatedStudentsHaveAllLectures(repo, lectures, overlappingStudents)
+
+	if err != nil {
+		return nil, err
+	}
+
+	run.Timings.Verify = result.Milliseconds(time.Now().Sub(verifyStartTime))
+	run.Verification.AllLecturesEnrolled = covered
+	run.Verification.EnrolledLectures = enrolledCount
+
+	var lectureIds []string
+
+	for _, lecture := range *lectures {
+		lectureIds = append(lectureIds, lecture.Id)
+	}
+
+	run.SetUncovered(lectureIds)
+
+	log.Infof("All lectures are covered: %t", covered)
+
+	run.Timings.Total = result.Milliseconds(time.Now().Sub(totalStartTime))
+
+	return &Outcome{
+		Run:         &run,
+		Explanation: result.Explain(&run, lectureIds, enrolled),
+	}, nil
+}
+
+// checkIfAggregatedStudentsHaveAllLectures counts the lectures with at least
+// one enrolled device, regardless of the state of the devices, with a single
+// distinct query instead of a second scan of the enrollments.
+func checkIfAggregatedStudentsHaveAllLectures(repo repository.Repository, lectures *[]model.IOSLecture, aggregatedStudents *[]solver.Assignment) (bool, int, error) {
+	lecturesCount := len(*lectures)
+	lectureIds, err := repo.GetLecturesThatHaveAtLeastOneDevice()
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	enrolled := make(map[string]bool, len(*lectureIds))
+
+	for _, lectureId := range *lectureIds {
+		enrolled[lectureId] = true
+	}
+
+	log.Infof("Lecture count: %d", lecturesCount)
+	log.Infof("Aggregated students count: %d", len(*aggregatedStudents))
+
+	enrolledCount := 0
+
+	for _, lecture := range *lectures {
+		if enrolled[lecture.Id] {
+			enrolledCount++
+		}
+	}
+
+	log.Infof("Enrolled lecture count: %d", enrolledCount)
+
+	return enrolledCount == lecturesCount, enrolledCount, nil
+}
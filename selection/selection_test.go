@@ -0,0 +1,237 @@
+package selection
+
+import (
+	"reflect"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"test-student-lecture-selection-algorithm/result"
+	"testing"
+	"time"
+)
+
+// newTestRepository returns a repository in which device-a covers the most
+// lectures of the winter term, device-b is the only one enrolled in the
+// summer lecture and only the suspect device-d is enrolled in lecture-5.
+func newTestRepository(t *testing.T) repository.Repository {
+	t.Helper()
+
+	repo := memory.New()
+	devices := []model.IOSDevice{
+		{DeviceID: "device-a", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-b", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-c", State: model.IOSDeviceStateActive},
+		{DeviceID: "device-d", State: model.IOSDeviceStateSuspect},
+	}
+	lectures := []model.IOSLecture{
+		{Id: "lecture-1", Year: 2026, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-2", Year: 2026, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-3", Year: 2026, Semester: model.IOSLectureSemesterWinter},
+		{Id: "lecture-4", Year: 2026, Semester: model.IOSLectureSemesterSummer},
+		{Id: "lecture-5", Year: 2026, Semester: model.IOSLectureSemesterWinter},
+	}
+	deviceLectures := []model.IOSDeviceLecture{
+		{DeviceId: "device-a", LectureId: "lecture-1"},
+		{DeviceId: "device-a", LectureId: "lecture-2"},
+		{DeviceId: "device-a", LectureId: "lecture-3"},
+		{DeviceId: "device-b", LectureId: "lecture-1"},
+		{DeviceId: "device-b", LectureId: "lecture-4"},
+		{DeviceId: "device-c", LectureId: "lecture-3"},
+		{DeviceId: "device-d", LectureId: "lecture-5"},
+	}
+
+	if err := repo.CreateDevices(&devices); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&lectures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDeviceLectures(&deviceLectures); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func run(t *testing.T, repo repository.Repository, options Options) *Outcome {
+	t.Helper()
+
+	outcome, err := Run(repo, options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return outcome
+}
+
+func requests(t *testing.T, repo repository.Repository) *[]model.IOSDeviceRequestLog {
+	t.Helper()
+
+	requestLogs, err := repo.GetRequestLogsSince(time.Unix(0, 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return requestLogs
+}
+
+func TestRunSolves(t *testing.T) {
+	repo := newTestRepository(t)
+	outcome := run(t, repo, DefaultOptions())
+
+	if want := []string{"device-a", "device-b"}; !reflect.DeepEqual(outcome.Run.SelectedDevices, want) {
+		t.Errorf("selected %v instead of %v", outcome.Run.SelectedDevices, want)
+	}
+
+	if outcome.Run.Version != result.Version || !outcome.Run.Verification.Valid {
+		t.Errorf("run of version %d is not valid: %s", outcome.Run.Version, outcome.Run.Verification.Error)
+	}
+
+	// lecture-5 has an enrolled device, but it is suspect.
+	verification := outcome.Run.Verification
+
+	if !verification.AllLecturesEnrolled || verification.EnrolledLectures != 5 {
+		t.Errorf("%d enrolled lectures instead of all 5", verification.EnrolledLectures)
+	}
+
+	if !reflect.DeepEqual(verification.UncoveredLectures, []string{"lecture-5"}) {
+		t.Errorf("uncovered %v instead of lecture-5", verification.UncoveredLectures)
+	}
+
+	if outcome.Explanation.Lectures[4].Reason != result.ReasonNoDevice {
+		t.Errorf("lecture-5 is %q instead of %q", outcome.Explanation.Lectures[4].Reason, result.ReasonNoDevice)
+	}
+
+	// Without Commit nothing is written.
+	if requestLogs := requests(t, repo); outcome.Run.Requests != 0 || len(*requestLogs) != 0 {
+		t.Errorf("solving created %d requests", len(*requestLogs))
+	}
+}
+
+func TestRunCommits(t *testing.T) {
+	repo := newTestRepository(t)
+	options := DefaultOptions()
+	options.Commit = true
+
+	outcome := run(t, repo, options)
+	requestLogs := requests(t, repo)
+
+	if outcome.Run.Requests != 2 || len(*requestLogs) != 2 {
+		t.Fatalf("committed %d requests, %d in the repository instead of 2", outcome.Run.Requests, len(*requestLogs))
+	}
+
+	requestIds := make(map[string]string)
+
+	for _, requestLog := range *requestLogs {
+		if requestLog.RequestType != model.IOSLectureUpdateRequestType {
+			t.Errorf("committed a %s", requestLog.RequestType)
+		}
+
+		requestIds[requestLog.DeviceID] = requestLog.RequestID
+	}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lecture := range *lectures {
+		coverer := outcome.Run.Coverers[lecture.Id]
+
+		switch {
+		case coverer == "" && lecture.LastRequestId != nil:
+			t.Errorf("uncovered %s points to request %s", lecture.Id, *lecture.LastRequestId)
+		case coverer != "" && (lecture.LastRequestId == nil || *lecture.LastRequestId != requestIds[coverer]):
+			t.Errorf("%s does not point to the request of its coverer %s", lecture.Id, coverer)
+		}
+	}
+}
+
+func TestRunFiltersTheTerm(t *testing.T) {
+	options := DefaultOptions()
+	options.Term = &Term{Year: 2026, Semester: model.IOSLectureSemesterWinter}
+
+	outcome := run(t, newTestRepository(t), options)
+
+	if want := []string{"device-a"}; !reflect.DeepEqual(outcome.Run.SelectedDevices, want) {
+		t.Errorf("selected %v instead of %v for the winter term", outcome.Run.SelectedDevices, want)
+	}
+
+	if outcome.Run.Term != "winter 2026" || outcome.Run.Instance.Lectures != 4 {
+		t.Errorf("term %q with %d lectures instead of winter 2026 with 4", outcome.Run.Term, outcome.Run.Instance.Lectures)
+	}
+
+	if _, ok := outcome.Run.Coverers["lecture-4"]; ok {
+		t.Error("the summer lecture is covered in the winter term")
+	}
+
+	// A term without lectures selects nothing.
+	options.Term = &Term{Year: 2025, Semester: model.IOSLectureSemesterSummer}
+
+	if outcome := run(t, newTestRepository(t), options); len(outcome.Run.SelectedDevices) != 0 {
+		t.Errorf("selected %v in a term without lectures", outcome.Run.SelectedDevices)
+	}
+}
+
+func TestRunKeepsTheBestDevicesWithinTheBudget(t *testing.T) {
+	repo := newTestRepository(t)
+	options := DefaultOptions()
+	options.Budget = 1
+	options.Commit = true
+
+	outcome := run(t, repo, options)
+
+	if want := []string{"device-a"}; !reflect.DeepEqual(outcome.Run.SelectedDevices, want) {
+		t.Errorf("selected %v instead of %v within a budget of 1", outcome.Run.SelectedDevices, want)
+	}
+
+	if want := []string{"lecture-4", "lecture-5"}; !reflect.DeepEqual(outcome.Run.Verification.UncoveredLectures, want) {
+		t.Errorf("uncovered %v instead of %v", outcome.Run.Verification.UncoveredLectures, want)
+	}
+
+	// The verification checks the selection before the budget.
+	if !outcome.Run.Verification.Valid {
+		t.Errorf("selection within the budget is not valid: %s", outcome.Run.Verification.Error)
+	}
+
+	if lecture := outcome.Explanation.Lectures[3]; lecture.LectureID != "lecture-4" || lecture.Reason != result.ReasonOverBudget {
+		t.Errorf("%s is %q instead of %q", lecture.LectureID, lecture.Reason, result.ReasonOverBudget)
+	}
+
+	if requestLogs := requests(t, repo); len(*requestLogs) != 1 || (*requestLogs)[0].DeviceID != "device-a" {
+		t.Errorf("committed %v instead of a request to device-a", *requestLogs)
+	}
+}
+
+func TestRunRejectsInvalidOptions(t *testing.T) {
+	for name, modify := range map[string]func(options *Options){
+		"unknown solver":   func(options *Options) { options.Solver = "unknown" },
+		"negative budget":  func(options *Options) { options.Budget = -1 },
+		"invalid year":     func(options *Options) { options.Term = &Term{Semester: model.IOSLectureSemesterWinter} },
+		"unknown semester": func(options *Options) { options.Term = &Term{Year: 2026, Semester: "spring"} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			options := DefaultOptions()
+			options.Commit = true
+			modify(&options)
+
+			if err := options.Validate(); err == nil {
+				t.Error("options are valid")
+			}
+
+			if outcome, err := Run(repo, options); err == nil {
+				t.Errorf("ran with invalid options and selected %v", outcome.Run.SelectedDevices)
+			}
+
+			if requestLogs := requests(t, repo); len(*requestLogs) != 0 {
+				t.Errorf("created %d requests with invalid options", len(*requestLogs))
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package simulation
+
+import (
+	"errors"
+	"math/rand"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/scenario"
+	"time"
+)
+
+var errNoHistory = errors.New("no answered or expired requests to learn the reliability from")
+
+// Reliability is how a device answers requests.
+type Reliability struct {
+	// ResponseRate is the probability that the device answers a request.
+	ResponseRate float64
+	// Latencies are the observed times between the creation of a request
+	// and its answer. Latencies are drawn from them.
+	Latencies []time.Duration
+}
+
+// ReliabilityModel holds the Reliability of every device with a request
+// history.
+type ReliabilityModel struct {
+	Devices map[string]Reliability
+	// Pooled is the reliability of all devices together. It is used for
+	// devices without history and to draw the latencies of devices that
+	// never answered.
+	Pooled Reliability
+}
+
+// LearnReliability estimates the reliability of the devices from the
+// requests. Handled requests count as answered, expired and undeliverable
+// ones as unanswered, the others are still open or were superseded and are
+// left out. The response rate of a device is smoothed towards the pooled
+// rate as if the device had priorWeight additional requests answered at the
+// pooled rate, so that a single request doesn't make a device perfect.
+func LearnReliability(requests []scenario.Request, priorWeight float64) (*ReliabilityModel, error) {
+	type counts struct {
+		answered int
+		total    int
+	}
+
+	perDevice := make(map[string]*counts)
+	latencies := make(map[string][]time.Duration)
+	pooled := counts{}
+
+	var pooledLatencies []time.Duration
+
+	for _, request := range requests {
+		switch request.Status {
+		case model.IOSRequestStatusHandled:
+		case model.IOSRequestStatusExpired, model.IOSRequestStatusDeliveryFailed:
+		default:
+			continue
+		}
+
+		c := perDevice[request.DeviceID]
+
+		if c == nil {
+			c = &counts{}
+			perDevice[request.DeviceID] = c
+		}
+
+		c.total++
+		pooled.total++
+
+		if request.Status != model.IOSRequestStatusHandled || request.HandledAt == nil {
+			continue
+		}
+
+		c.answered++
+		pooled.answered++
+
+		latency := request.HandledAt.Sub(request.CreatedAt)
+		latencies[request.DeviceID] = append(latencies[request.DeviceID], latency)
+		pooledLatencies = append(pooledLatencies, latency)
+	}
+
+	if pooled.total == 0 || len(pooledLatencies) == 0 {
+		return nil, errNoHistory
+	}
+
+	pooledRate := float64(pooled.answered) / float64(pooled.total)
+	reliabilityModel := ReliabilityModel{
+		Devices: make(map[string]Reliability, len(perDevice)),
+		Pooled:  Reliability{ResponseRate: pooledRate, Latencies: pooledLatencies},
+	}
+
+	for deviceId, c := range perDevice {
+		reliabilityModel.Devices[deviceId] = Reliability{
+			ResponseRate: (float64(c.answered) + priorWeight*pooledRate) / (float64(c.total) + priorWeight),
+			Latencies:    latencies[deviceId],
+		}
+	}
+
+	return &reliabilityModel, nil
+}
+
+// Of returns the reliability of the device, the pooled one for devices
+// without history.
+func (m *ReliabilityModel) Of(deviceId string) Reliability {
+	reliability, ok := m.Devices[deviceId]
+
+	if !ok {
+		return m.Pooled
+	}
+
+	if len(reliability.Latencies) == 0 {
+		reliability.Latencies = m.Pooled.Latencies
+	}
+
+	return reliability
+}
+
+// answer draws whether a device of reliability answers and how long it
+// takes.
+func (r Reliability) answer(random *rand.Rand) (bool, time.Duration) {
+	if random.Float64() >= r.ResponseRate {
+		return false, 0
+	}
+
+	return true, r.Latencies[random.Intn(len(r.Latencies))]
+}
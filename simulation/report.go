@@ -0,0 +1,115 @@
+package simulation
+
+import (
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+)
+
+// Distribution summarizes observed values.
+type Distribution struct {
+	Count int
+	Mean  float64
+	P50   float64
+	P90   float64
+	P99   float64
+	Max   float64
+}
+
+// NewDistribution summarizes values, which it sorts.
+func NewDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+
+	sort.Float64s(values)
+
+	sum := 0.0
+
+	for _, value := range values {
+		sum += value
+	}
+
+	return Distribution{
+		Count: len(values),
+		Mean:  sum / float64(len(values)),
+		P50:   quantile(values, 0.5),
+		P90:   quantile(values, 0.9),
+		P99:   quantile(values, 0.99),
+		Max:   values[len(values)-1],
+	}
+}
+
+// quantile returns the nearest rank quantile of the sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+// Report summarizes the observations of all trials.
+type Report struct {
+	Config Config
+	Trials int
+	// Staleness is the distribution of the age of the lecture data in hours
+	// at every dispatch.
+	Staleness Distribution
+	// Coverage is the distribution of the share of lectures refreshed by a
+	// dispatch.
+	Coverage Distribution
+	// PushesPerDevice is the distribution of the pushes a device receives
+	// during the period.
+	PushesPerDevice Distribution
+	// PushVolume is the distribution of the total pushes of a trial.
+	PushVolume Distribution
+}
+
+func summarize(config Config, trials []Trial) *Report {
+	var staleness, coverage, pushesPerDevice, pushVolume []float64
+
+	for _, trial := range trials {
+		for _, age := range trial.Staleness {
+			staleness = append(staleness, age.Hours())
+		}
+
+		coverage = append(coverage, trial.Coverage...)
+
+		for _, pushes := range trial.Pushes {
+			pushesPerDevice = append(pushesPerDevice, float64(pushes))
+		}
+
+		pushVolume = append(pushVolume, float64(trial.Pushed()))
+	}
+
+	return &Report{
+		Config:          config,
+		Trials:          len(trials),
+		Staleness:       NewDistribution(staleness),
+		Coverage:        NewDistribution(coverage),
+		PushesPerDevice: NewDistribution(pushesPerDevice),
+		PushVolume:      NewDistribution(pushVolume),
+	}
+}
+
+// Log writes the report to the log.
+func (r *Report) Log() {
+	log.Infof("Simulated %d trials of %s with solver %s, dispatching every %s", r.Trials, r.Config.Period, r.Config.Dispatch.Solver, r.Config.Interval)
+
+	for _, line := range []struct {
+		name         string
+		distribution Distribution
+		unit         string
+	}{
+		{"Staleness", r.Staleness, "h"},
+		{"Coverage per dispatch", r.Coverage, ""},
+		{"Pushes per device", r.PushesPerDevice, ""},
+		{"Push volume per trial", r.PushVolume, ""},
+	} {
+		d := line.distribution
+		log.Infof("%s: mean %.2f%s, p50 %.2f%s, p90 %.2f%s, p99 %.2f%s, max %.2f%s", line.name, d.Mean, line.unit, d.P50, line.unit, d.P90, line.unit, d.P99, line.unit, d.Max, line.unit)
+	}
+}
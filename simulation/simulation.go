@@ -0,0 +1,429 @@
+// Package simulation estimates the effect of a dispatch policy before it is
+// deployed. It replays the policy, i.e. the solver, the readiness of the
+// devices and the dispatch rounds, on a simulated clock over a period and
+// repeats this in many trials. The devices answer according to the
+// reliability learned from their request history, and the enrollments
+// change over time as students switch lectures, join and leave.
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"test-student-lecture-selection-algorithm/dispatch"
+	"test-student-lecture-selection-algorithm/lifecycle"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/scenario"
+	"test-student-lecture-selection-algorithm/solver"
+	"time"
+)
+
+type Config struct {
+	// Seed of the first trial, every further trial adds one. The solvers
+	// are deterministic, so the same Config and scenario yield the same
+	// Report.
+	Seed   int64
+	Trials int
+	// Period is the simulated time span of every trial.
+	Period time.Duration
+	// Interval is the time between two dispatches.
+	Interval time.Duration
+	// Dispatch configures the solver and the rounds of every dispatch. The
+	// PollInterval is not used, answers are evaluated at the end of a round.
+	Dispatch dispatch.Config
+	// RequestExpiry is how long a lecture update request can be answered.
+	RequestExpiry time.Duration
+	// SuspectAfter is the number of consecutive unanswered requests after
+	// which a device is no longer selected, see lifecycle.Config.
+	SuspectAfter int
+	// RecoveryPerDay is the probability per day that a suspect device
+	// answers any other request, e.g. a campus token request, and is
+	// selected again.
+	RecoveryPerDay float64
+	// PriorWeight smooths the learned response rates, see LearnReliability.
+	PriorWeight float64
+	// ChurnPerDay is the share of devices per day that switch one of their
+	// lectures for another one.
+	ChurnPerDay float64
+	// JoinPerDay and LeavePerDay are the shares of devices per day that
+	// register and unregister.
+	JoinPerDay  float64
+	LeavePerDay float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Seed:           1,
+		Trials:         10,
+		Period:         7 * 24 * time.Hour,
+		Interval:       6 * time.Hour,
+		Dispatch:       dispatch.DefaultConfig(),
+		RequestExpiry:  model.DefaultIOSRequestExpiry()[model.IOSLectureUpdateRequestType],
+		SuspectAfter:   lifecycle.DefaultConfig().SuspectAfter,
+		RecoveryPerDay: 0.1,
+		PriorWeight:    2,
+		ChurnPerDay:    0.01,
+		JoinPerDay:     0.005,
+		LeavePerDay:    0.002,
+	}
+}
+
+// Validate returns an error describing the first invalid setting of c.
+func (c *Config) Validate() error {
+	switch {
+	case c.Trials < 1:
+		return fmt.Errorf("at least one trial is needed, got %d", c.Trials)
+	case c.Interval <= 0 || c.Period < c.Interval:
+		return fmt.Errorf("interval %s has to be positive and not longer than the period %s", c.Interval, c.Period)
+	case c.Dispatch.MaxRounds < 1 || c.Dispatch.Timeout <= 0:
+		return fmt.Errorf("invalid dispatch of %d rounds of %s", c.Dispatch.MaxRounds, c.Dispatch.Timeout)
+	case c.SuspectAfter < 1:
+		return fmt.Errorf("suspect after %d requests has to be at least one", c.SuspectAfter)
+	case c.PriorWeight < 0:
+		return fmt.Errorf("negative prior weight %g", c.PriorWeight)
+	}
+
+	for _, rate := range []float64{c.RecoveryPerDay, c.ChurnPerDay, c.JoinPerDay, c.LeavePerDay} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("rate per day %g is not a probability", rate)
+		}
+	}
+
+	return nil
+}
+
+type Simulator struct {
+	Config      Config
+	Scenario    *scenario.Scenario
+	Reliability *ReliabilityModel
+	solve       solver.Solver
+}
+
+// NewSimulator learns the reliability of the devices from the requests of s,
+// which is also the state every trial starts from.
+func NewSimulator(config Config, s *scenario.Scenario) (*Simulator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	solve, err := solver.Get(config.Dispatch.Solver)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reliability, err := LearnReliability(s.Requests, config.PriorWeight)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Simulator{
+		Config:      config,
+		Scenario:    s,
+		Reliability: reliability,
+		solve:       solve,
+	}, nil
+}
+
+// Run runs all trials and summarizes them.
+func (s *Simulator) Run() (*Report, error) {
+	var trials []Trial
+
+	for i := 0; i < s.Config.Trials; i++ {
+		trial, err := s.trial(s.Config.Seed + int64(i))
+
+		if err != nil {
+			return nil, fmt.Errorf("trial %d: %w", i+1, err)
+		}
+
+		trials = append(trials, *trial)
+	}
+
+	return summarize(s.Config, trials), nil
+}
+
+// device is the simulated state of a device.
+type device struct {
+	id          string
+	lectures    []string
+	reliability Reliability
+	// unanswered counts the consecutive unanswered requests.
+	unanswered int
+	suspect    bool
+	pushes     int
+}
+
+// trialState is the state of a single trial.
+type trialState struct {
+	simulator   *Simulator
+	random      *rand.Rand
+	now         time.Time
+	devices     []*device
+	lectureIds  []string
+	lastRefresh map[string]time.Time
+	joined      int
+}
+
+// Trial has the observations of a single trial.
+type Trial struct {
+	// Staleness has the age of the data of every lecture at every dispatch
+	// and at the end of the period.
+	Staleness []time.Duration
+	// Coverage has the share of lectures refreshed by every dispatch.
+	Coverage []float64
+	// Pushes has the number of pushes of every device that was registered
+	// at some time during the trial.
+	Pushes []int
+}
+
+// Pushed returns the total number of pushes of the trial.
+func (t *Trial) Pushed() int {
+	total := 0
+
+	for _, pushes := range t.Pushes {
+		total += pushes
+	}
+
+	return total
+}
+
+func (s *Simulator) trial(seed int64) (*Trial, error) {
+	start := s.Scenario.CapturedAt
+
+	if start.IsZero() {
+		return nil, errors.New("the scenario has no capture time to start from")
+	}
+
+	state := trialState{
+		simulator:   s,
+		random:      rand.New(rand.NewSource(seed)),
+		now:         start,
+		lastRefresh: make(map[string]time.Time, len(s.Scenario.Lectures)),
+	}
+
+	for _, lecture := range s.Scenario.Lectures {
+		state.lectureIds = append(state.lectureIds, lecture.ID)
+		state.lastRefresh[lecture.ID] = start
+
+		if lecture.LastUpdate != nil {
+			state.lastRefresh[lecture.ID] = *lecture.LastUpdate
+		}
+	}
+
+	var gone []*device
+
+	for _, d := range s.Scenario.Devices {
+		if d.State == model.IOSDeviceStateUnregistered {
+			continue
+		}
+
+		state.devices = append(state.devices, &device{
+			id:          d.ID,
+			lectures:    append([]string{}, d.Lectures...),
+			reliability: s.Reliability.Of(d.ID),
+			suspect:     d.State == model.IOSDeviceStateSuspect,
+		})
+	}
+
+	trial := Trial{}
+	end := start.Add(s.Config.Period)
+
+	for ; state.now.Before(end); state.now = state.now.Add(s.Config.Interval) {
+		trial.Staleness = append(trial.Staleness, state.staleness()...)
+		trial.Coverage = append(trial.Coverage, state.dispatch())
+
+		gone = append(gone, state.evolve()...)
+	}
+
+	trial.Staleness = append(trial.Staleness, state.staleness()...)
+
+	for _, d := range append(state.devices, gone...) {
+		trial.Pushes = append(trial.Pushes, d.pushes)
+	}
+
+	return &trial, nil
+}
+
+func (t *trialState) staleness() []time.Duration {
+	staleness := make([]time.Duration, 0, len(t.lectureIds))
+
+	for _, lectureId := range t.lectureIds {
+		staleness = append(staleness, t.now.Sub(t.lastRefresh[lectureId]))
+	}
+
+	return staleness
+}
+
+// dispatch runs the rounds of a dispatch at now like dispatch.Orchestrator
+// and returns the share of lectures it refreshed. A round ends after the
+// Timeout, late answers still refresh the lectures as long as the request
+// has not expired, but the devices count as not responding.
+func (t *trialState) dispatch() float64 {
+	config := t.simulator.Config.Dispatch
+	expiry := t.simulator.Config.RequestExpiry
+	refreshed := make(map[string]bool)
+	excluded := make(map[string]bool)
+	pushed := 0
+
+	for round := 1; round <= config.MaxRounds; round++ {
+		if float64(len(refreshed)) >= config.CoverageTarget*float64(len(t.lectureIds)) {
+			break
+		}
+
+		lectures := make(solver.LectureToOverlapped)
+
+		for _, lectureId := range t.lectureIds {
+			if !refreshed[lectureId] {
+				lectures[lectureId] = &solver.LectureOverlapped{LectureId: lectureId}
+			}
+		}
+
+		devices := make(map[string]*device)
+		devicesLectures := make(solver.DeviceToOverlappedLectures)
+
+		for _, d := range t.devices {
+			if d.suspect || excluded[d.id] {
+				continue
+			}
+
+			devices[d.id] = d
+
+			for _, lectureId := range d.lectures {
+				if lecture, ok := lectures[lectureId]; ok {
+					devicesLectures[d.id] = append(devicesLectures[d.id], lecture)
+				}
+			}
+		}
+
+		assignments := *t.simulator.solve(&devicesLectures, &lectures)
+
+		if len(assignments) == 0 {
+			break
+		}
+
+		if config.Budget > 0 {
+			if pushed >= config.Budget {
+				break
+			}
+
+			assignments = *solver.Truncate(&assignments, config.Budget-pushed)
+		}
+
+		roundStart := t.now.Add(time.Duration(round-1) * config.Timeout)
+
+		for _, assignment := range assignments {
+			d := devices[assignment.DeviceId]
+			d.pushes++
+			pushed++
+			excluded[d.id] = true
+
+			answers, latency := d.reliability.answer(t.random)
+
+			if !answers || latency > expiry {
+				d.unanswered++
+				d.suspect = d.unanswered >= t.simulator.Config.SuspectAfter
+				continue
+			}
+
+			d.unanswered = 0
+
+			// A device refreshes all its lectures, not only the assigned
+			// ones.
+			for _, lectureId := range d.lectures {
+				if refreshedAt := roundStart.Add(latency); refreshedAt.After(t.lastRefresh[lectureId]) {
+					t.lastRefresh[lectureId] = refreshedAt
+				}
+
+				if latency <= config.Timeout {
+					refreshed[lectureId] = true
+				}
+			}
+		}
+	}
+
+	if len(t.lectureIds) == 0 {
+		return 1
+	}
+
+	return float64(len(refreshed)) / float64(len(t.lectureIds))
+}
+
+// evolve applies the changes of one Interval to the devices and returns the
+// devices that left.
+func (t *trialState) evolve() []*device {
+	config := t.simulator.Config
+	days := t.simulator.Config.Interval.Hours() / 24
+
+	for _, d := range t.devices {
+		if d.suspect && t.random.Float64() < config.RecoveryPerDay*days {
+			d.suspect = false
+			d.unanswered = 0
+		}
+	}
+
+	for i := t.count(config.ChurnPerDay * days); i > 0 && len(t.devices) > 0; i-- {
+		t.switchLecture(t.devices[t.random.Intn(len(t.devices))])
+	}
+
+	var gone []*device
+
+	for i := t.count(config.LeavePerDay * days); i > 0 && len(t.devices) > 0; i-- {
+		j := t.random.Intn(len(t.devices))
+		gone = append(gone, t.devices[j])
+		t.devices[j] = t.devices[len(t.devices)-1]
+		t.devices = t.devices[:len(t.devices)-1]
+	}
+
+	for i := t.count(config.JoinPerDay * days); i > 0 && len(t.devices) > 0; i-- {
+		// A new student is like a random existing one, except for the
+		// request history.
+		template := t.devices[t.random.Intn(len(t.devices))]
+		t.joined++
+		t.devices = append(t.devices, &device{
+			id:          fmt.Sprintf("simulated-%d", t.joined),
+			lectures:    append([]string{}, template.lectures...),
+			reliability: template.reliability,
+		})
+	}
+
+	return gone
+}
+
+// count returns how many of the devices are affected by a rate, rounded
+// randomly so that small rates take effect on average.
+func (t *trialState) count(rate float64) int {
+	expected := rate * float64(len(t.devices))
+	count := math.Floor(expected)
+
+	if t.random.Float64() < expected-count {
+		count++
+	}
+
+	return int(count)
+}
+
+// switchLecture replaces a lecture of d by the lecture of a random
+// enrollment of another device, which keeps the popularity of the lectures.
+func (t *trialState) switchLecture(d *device) {
+	if len(d.lectures) == 0 {
+		return
+	}
+
+	other := t.devices[t.random.Intn(len(t.devices))]
+
+	if len(other.lectures) == 0 {
+		return
+	}
+
+	lectureId := other.lectures[t.random.Intn(len(other.lectures))]
+
+	for _, enrolled := range d.lectures {
+		if enrolled == lectureId {
+			return
+		}
+	}
+
+	d.lectures[t.random.Intn(len(d.lectures))] = lectureId
+}
@@ -0,0 +1,96 @@
+package simulation
+
+import (
+	"fmt"
+	"reflect"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/scenario"
+	"testing"
+	"time"
+)
+
+// testScenario returns 40 devices enrolled in 2 of 10 lectures each, so that
+// the solvers have to break many ties, with a request history in which
+// every third device did not answer.
+func testScenario() *scenario.Scenario {
+	capturedAt := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	s := scenario.Scenario{Version: scenario.Version, Name: "test", CapturedAt: capturedAt}
+
+	for i := 0; i < 10; i++ {
+		s.Lectures = append(s.Lectures, scenario.Lecture{ID: fmt.Sprintf("lecture-%d", i)})
+	}
+
+	for i := 0; i < 40; i++ {
+		deviceId := fmt.Sprintf("device-%02d", i)
+		s.Devices = append(s.Devices, scenario.Device{
+			ID:       deviceId,
+			State:    model.IOSDeviceStateActive,
+			Lectures: []string{fmt.Sprintf("lecture-%d", i%10), fmt.Sprintf("lecture-%d", (i/10+i+1)%10)},
+		})
+
+		createdAt := capturedAt.Add(-time.Duration(i+1) * time.Hour)
+		request := scenario.Request{
+			ID:        fmt.Sprintf("request-%02d", i),
+			DeviceID:  deviceId,
+			Type:      model.IOSLectureUpdateRequestType,
+			Status:    model.IOSRequestStatusExpired,
+			CreatedAt: createdAt,
+		}
+
+		if i%3 != 0 {
+			handledAt := createdAt.Add(time.Duration(i+1) * time.Second)
+			request.Status = model.IOSRequestStatusHandled
+			request.HandledAt = &handledAt
+		}
+
+		s.Requests = append(s.Requests, request)
+	}
+
+	return &s
+}
+
+func TestRunIsReproducible(t *testing.T) {
+	for _, solverName := range []string{"greedy", "greedy-pruned"} {
+		config := DefaultConfig()
+		config.Seed = 42
+		config.Trials = 3
+		config.Period = 3 * 24 * time.Hour
+		config.Dispatch.Solver = solverName
+		config.Dispatch.Budget = 4
+
+		var reports []*Report
+
+		for i := 0; i < 2; i++ {
+			simulator, err := NewSimulator(config, testScenario())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := simulator.Run()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reports = append(reports, report)
+		}
+
+		if !reflect.DeepEqual(reports[0], reports[1]) {
+			t.Errorf("%s: the same seed yields different reports:\n%+v\n%+v", solverName, reports[0], reports[1])
+		}
+
+		if reports[0].Trials != 3 || reports[0].PushVolume.Count != 3 || reports[0].PushVolume.Max == 0 {
+			t.Errorf("%s: unexpected report %+v", solverName, reports[0])
+		}
+	}
+}
+
+func TestNewSimulatorNeedsHistory(t *testing.T) {
+	s := testScenario()
+	s.Requests = nil
+
+	if _, err := NewSimulator(DefaultConfig(), s); err == nil {
+		t.Error("simulator without request history was created")
+	}
+}
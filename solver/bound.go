@@ -0,0 +1,82 @@
+package solver
+
+import (
+	"sort"
+)
+
+// LowerBound returns a lower bound of the number of devices needed to cover
+// the lectures that have at least one enrolled device. It is the larger of
+//
+//   - the number of those lectures divided by the largest enrollment and
+//   - the size of a set of lectures no two of which share a device, as every
+//     lecture of such a set needs a device of its own.
+//
+// LowerBound doesn't modify its input.
+func LowerBound(devicesLectures *DeviceToOverlappedLectures) int {
+	devicesOfLecture := make(map[string][]string)
+	maxCount := 0
+
+	for device, lectures := range *devicesLectures {
+		for _, lecture := range lectures {
+			devicesOfLecture[lecture.LectureId] = append(devicesOfLecture[lecture.LectureId], device)
+		}
+
+		if len(lectures) > maxCount {
+			maxCount = len(lectures)
+		}
+	}
+
+	if maxCount == 0 {
+		return 0
+	}
+
+	bound := (len(devicesOfLecture) + maxCount - 1) / maxCount
+
+	// Lectures with few devices block few other lectures, so they are
+	// packed first.
+	lectureIds := make([]string, 0, len(devicesOfLecture))
+
+	for lectureId := range devicesOfLecture {
+		lectureIds = append(lectureIds, lectureId)
+	}
+
+	sort.Slice(lectureIds, func(i, j int) bool {
+		a, b := len(devicesOfLecture[lectureIds[i]]), len(devicesOfLecture[lectureIds[j]])
+
+		if a != b {
+			return a < b
+		}
+
+		return lectureIds[i] < lectureIds[j]
+	})
+
+	used := make(map[string]bool)
+	packed := 0
+
+	for _, lectureId := range lectureIds {
+		free := true
+
+		for _, device := range devicesOfLecture[lectureId] {
+			if used[device] {
+				free = false
+				break
+			}
+		}
+
+		if !free {
+			continue
+		}
+
+		packed++
+
+		for _, device := range devicesOfLecture[lectureId] {
+			used[device] = true
+		}
+	}
+
+	if packed > bound {
+		return packed
+	}
+
+	return bound
+}
@@ -0,0 +1,43 @@
+package solver
+
+import (
+	"sort"
+)
+
+// Truncate keeps the budget assignments covering the most lectures. Solvers
+// don't order their assignments by size, e.g. Prune reassigns lectures to
+// devices picked earlier, so the first assignments aren't necessarily the
+// most valuable ones. Ties are kept in the order of the solver, and so is
+// the order of the kept assignments. A budget of zero or less means no
+// limit.
+func Truncate(assignments *[]Assignment, budget int) *[]Assignment {
+	if budget <= 0 || len(*assignments) <= budget {
+		return assignments
+	}
+
+	order := make([]int, len(*assignments))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return len((*assignments)[order[i]].LectureIds) > len((*assignments)[order[j]].LectureIds)
+	})
+
+	kept := make([]bool, len(*assignments))
+
+	for _, i := range order[:budget] {
+		kept[i] = true
+	}
+
+	truncated := make([]Assignment, 0, budget)
+
+	for i, assignment := range *assignments {
+		if kept[i] {
+			truncated = append(truncated, assignment)
+		}
+	}
+
+	return &truncated
+}
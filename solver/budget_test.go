@@ -0,0 +1,37 @@
+package solver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	assignments := []Assignment{
+		{DeviceId: "device-a", LectureIds: []string{"L1"}},
+		{DeviceId: "device-b", LectureIds: []string{"L2", "L3", "L4"}},
+		{DeviceId: "device-c", LectureIds: []string{"L5", "L6"}},
+		{DeviceId: "device-d", LectureIds: []string{"L7", "L8"}},
+	}
+
+	tests := []struct {
+		budget  int
+		devices []string
+	}{
+		{0, []string{"device-a", "device-b", "device-c", "device-d"}},
+		{4, []string{"device-a", "device-b", "device-c", "device-d"}},
+		{1, []string{"device-b"}},
+		// device-c and device-d tie, the one picked first is kept.
+		{2, []string{"device-b", "device-c"}},
+		{3, []string{"device-b", "device-c", "device-d"}},
+	}
+
+	for _, test := range tests {
+		if got := deviceIds(Truncate(&assignments, test.budget)); !reflect.DeepEqual(got, test.devices) {
+			t.Errorf("budget %d: expected %v, got %v", test.budget, test.devices, got)
+		}
+	}
+
+	if assignments[0].DeviceId != "device-a" || len(assignments) != 4 {
+		t.Error("the assignments were modified")
+	}
+}
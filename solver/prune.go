@@ -0,0 +1,87 @@
+package solver
+
+import (
+	"sort"
+)
+
+// GreedyPruned runs Greedy and removes the devices that became redundant
+// afterwards: a device picked early may have all of its lectures covered by
+// devices picked later.
+func GreedyPruned(devicesLectures *DeviceToOverlappedLectures, lectures *LectureToOverlapped) *[]Assignment {
+	enrolled := Enrolled(devicesLectures)
+
+	return Prune(Greedy(devicesLectures, lectures), enrolled)
+}
+
+// Prune removes devices of assignments, last picked first, as long as the
+// lectures of the assignments stay covered by the enrollments of the other
+// devices. The lectures are then assigned to the first remaining device
+// enrolled in them.
+func Prune(assignments *[]Assignment, enrolled map[string][]string) *[]Assignment {
+	covered := make(map[string]int)
+
+	for _, assignment := range *assignments {
+		for _, lectureId := range assignment.LectureIds {
+			covered[lectureId] = 0
+		}
+	}
+
+	for _, assignment := range *assignments {
+		for _, lectureId := range enrolled[assignment.DeviceId] {
+			if _, ok := covered[lectureId]; ok {
+				covered[lectureId]++
+			}
+		}
+	}
+
+	removed := make(map[string]bool)
+
+	for i := len(*assignments) - 1; i >= 0; i-- {
+		deviceId := (*assignments)[i].DeviceId
+		redundant := true
+
+		for _, lectureId := range enrolled[deviceId] {
+			if count, ok := covered[lectureId]; ok && count < 2 {
+				redundant = false
+				break
+			}
+		}
+
+		if !redundant {
+			continue
+		}
+
+		removed[deviceId] = true
+
+		for _, lectureId := range enrolled[deviceId] {
+			if _, ok := covered[lectureId]; ok {
+				covered[lectureId]--
+			}
+		}
+	}
+
+	var pruned []Assignment
+
+	assigned := make(map[string]bool, len(covered))
+
+	for _, assignment := range *assignments {
+		if removed[assignment.DeviceId] {
+			continue
+		}
+
+		var lectureIds []string
+
+		for _, lectureId := range enrolled[assignment.DeviceId] {
+			if _, ok := covered[lectureId]; ok && !assigned[lectureId] {
+				assigned[lectureId] = true
+				lectureIds = append(lectureIds, lectureId)
+			}
+		}
+
+		sort.Strings(lectureIds)
+
+		pruned = append(pruned, Assignment{DeviceId: assignment.DeviceId, LectureIds: lectureIds})
+	}
+
+	return &pruned
+}
@@ -0,0 +1,57 @@
+package solver
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Solver selects devices that cover the lectures. Like GetOverlapping, a
+// Solver may modify devicesLectures and lectures, so every call needs an
+// input of its own.
+type Solver func(devicesLectures *DeviceToOverlappedLectures, lectures *LectureToOverlapped) *[]Assignment
+
+var solvers = make(map[string]Solver)
+
+// Register makes a solver available by name to the benchmarks and the
+// commands that let the solver be chosen. Registering a name twice panics.
+func Register(name string, solver Solver) {
+	if _, ok := solvers[name]; ok {
+		panic(fmt.Sprintf("solver %s is registered twice", name))
+	}
+
+	solvers[name] = solver
+}
+
+// Get returns the solver registered by name.
+func Get(name string) (Solver, error) {
+	solver, ok := solvers[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown solver %q, registered are %v", name, Names())
+	}
+
+	return solver, nil
+}
+
+// Names returns the names of the registered solvers in order.
+func Names() []string {
+	names := make([]string, 0, len(solvers))
+
+	for name := range solvers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	Register("greedy", Greedy)
+	Register("greedy-pruned", GreedyPruned)
+}
+
+// Greedy is GetOverlapping starting with the largest enrollment.
+func Greedy(devicesLectures *DeviceToOverlappedLectures, lectures *LectureToOverlapped) *[]Assignment {
+	return GetOverlapping(devicesLectures, lectures, MaxAttendedLecturesCount(devicesLectures))
+}
@@ -0,0 +1,201 @@
+// Package solver searches for a small set of devices whose enrolled lectures
+// cover all lectures.
+package solver
+
+import (
+	"sort"
+	"test-student-lecture-selection-algorithm/model"
+)
+
+type LectureOverlapped struct {
+	LectureId  string
+	Overlapped bool
+}
+
+type LectureToOverlapped map[string]*LectureOverlapped
+type DeviceToOverlappedLectures map[string][]*LectureOverlapped
+type CompareFunc[T comparable] func(T) bool
+
+// Assignment is a device chosen by the solver together with the lectures it
+// is responsible for. Every lecture is assigned to exactly one device.
+type Assignment struct {
+	DeviceId   string
+	LectureIds []string
+}
+
+// GetOverlapping greedily picks the device that covers the most lectures
+// which are not covered yet until all lectures are covered or no device
+// covers anything new. Ties are broken by the lowest device ID, so the same
+// input always yields the same assignments.
+func GetOverlapping(
+	devicesLectures *DeviceToOverlappedLectures,
+	lectures *LectureToOverlapped,
+	maxAttendedLecturesCount int,
+) *[]Assignment {
+	var overlapped []*LectureOverlapped
+	var assignments []Assignment
+	currentMaxAttended := maxAttendedLecturesCount
+	deviceIds := sortedDeviceIds(devicesLectures)
+
+	for len(overlapped) < len(*lectures) {
+		var newMax string
+		var overlappingLectures *[]*LectureOverlapped
+
+		newMax, overlappingLectures = findBestNextMatch(
+			devicesLectures,
+			deviceIds,
+			currentMaxAttended,
+		)
+
+		if newMax == "" {
+			break
+		}
+
+		delete(*devicesLectures, newMax)
+
+		assignment := Assignment{DeviceId: newMax}
+
+		overlapped = append(overlapped, *overlappingLectures...)
+
+		for _, lecture := range *overlappingLectures {
+			lecture.Overlapped = true
+			assignment.LectureIds = append(assignment.LectureIds, lecture.LectureId)
+		}
+
+		assignments = append(assignments, assignment)
+
+		overlappingLecturesCount := len(*overlappingLectures)
+
+		if currentMaxAttended > overlappingLecturesCount {
+			currentMaxAttended = overlappingLecturesCount
+		}
+	}
+
+	return &assignments
+}
+
+// findBestNextMatch returns the first device of deviceIds that covers the
+// most lectures which are not covered yet. Devices that were removed from
+// devicesLectures are skipped.
+func findBestNextMatch(
+	devicesLectures *DeviceToOverlappedLectures,
+	deviceIds []string,
+	currentMaxAttended int,
+) (string, *[]*LectureOverlapped) {
+	maxAttends := 0
+	studentWithMaxAttends := ""
+	var overlappingLectures []*LectureOverlapped
+
+	for _, device := range deviceIds {
+		lectures, ok := (*devicesLectures)[device]
+
+		if !ok {
+			continue
+		}
+
+		newAttends := filter(&lectures, func(lecture *LectureOverlapped) bool {
+			return !lecture.Overlapped
+		})
+
+		newAttendsCount := len(*newAttends)
+
+		if newAttendsCount > maxAttends {
+			maxAttends = newAttendsCount
+			studentWithMaxAttends = device
+			overlappingLectures = *newAttends
+
+			if maxAttends == currentMaxAttended {
+				break
+			}
+		}
+	}
+
+	return studentWithMaxAttends, &overlappingLectures
+}
+
+func sortedDeviceIds(devicesLectures *DeviceToOverlappedLectures) []string {
+	deviceIds := make([]string, 0, len(*devicesLectures))
+
+	for deviceId := range *devicesLectures {
+		deviceIds = append(deviceIds, deviceId)
+	}
+
+	sort.Strings(deviceIds)
+
+	return deviceIds
+}
+
+func filter[T comparable](s *[]T, fn CompareFunc[T]) *[]T {
+	var p []T
+	for _, v := range *s {
+		if fn(v) {
+			p = append(p, v)
+		}
+	}
+	return &p
+}
+
+// MaxAttendedLecturesCount returns the highest number of lectures a single
+// device is enrolled in.
+func MaxAttendedLecturesCount(devicesLectures *DeviceToOverlappedLectures) int {
+	maxCount := 0
+
+	for _, lectures := range *devicesLectures {
+		if len(lectures) > maxCount {
+			maxCount = len(lectures)
+		}
+	}
+
+	return maxCount
+}
+
+func LectureToOverlappedLectureMap(l *[]model.IOSLecture) *LectureToOverlapped {
+	lectures := make(LectureToOverlapped)
+
+	for _, lecture := range *l {
+		overlapped := LectureOverlapped{
+			LectureId:  lecture.Id,
+			Overlapped: false,
+		}
+
+		lectures[lecture.Id] = &overlapped
+	}
+
+	return &lectures
+}
+
+// DevicesLecturesToMap groups the enrollments by device. Enrollments of
+// lectures that are not part of lectures are skipped, which allows solving
+// for a subset of the lectures only.
+func DevicesLecturesToMap(dl *[]model.IOSDeviceLecture, lectures *LectureToOverlapped) *DeviceToOverlappedLectures {
+	devicesLectures := make(DeviceToOverlappedLectures)
+
+	AddDevicesLectures(&devicesLectures, dl, lectures)
+
+	return &devicesLectures
+}
+
+// AddDevicesLectures adds the enrollments of dl to devicesLectures like
+// DevicesLecturesToMap, which allows building the map from chunks of
+// enrollments.
+func AddDevicesLectures(devicesLectures *DeviceToOverlappedLectures, dl *[]model.IOSDeviceLecture, lectures *LectureToOverlapped) {
+	for _, d := range *dl {
+		overlappedLecture, ok := (*lectures)[d.LectureId]
+
+		if !ok {
+			continue
+		}
+
+		(*devicesLectures)[d.DeviceId] = append((*devicesLectures)[d.DeviceId], overlappedLecture)
+	}
+}
+
+func DevicesToMap(d *[]model.IOSDevice) *map[string]model.IOSDevice {
+	devices := make(map[string]model.IOSDevice)
+
+	for _, device := range *d {
+		devices[device.DeviceID] = device
+	}
+
+	return &devices
+}
@@ -0,0 +1,175 @@
+package solver
+
+import (
+	"reflect"
+	"test-student-lecture-selection-algorithm/model"
+	"testing"
+)
+
+// input builds the input of the solvers from enrollments given as device
+// IDs mapped to lecture IDs. Every lecture is enrolled in by a device.
+func input(enrollments map[string][]string) (*LectureToOverlapped, *DeviceToOverlappedLectures) {
+	var lectures []model.IOSLecture
+	var deviceLectures []model.IOSDeviceLecture
+	seen := make(map[string]bool)
+
+	for deviceId, lectureIds := range enrollments {
+		for _, lectureId := range lectureIds {
+			deviceLectures = append(deviceLectures, model.IOSDeviceLecture{DeviceId: deviceId, LectureId: lectureId})
+
+			if !seen[lectureId] {
+				seen[lectureId] = true
+				lectures = append(lectures, model.IOSLecture{Id: lectureId})
+			}
+		}
+	}
+
+	lecturesMap := LectureToOverlappedLectureMap(&lectures)
+
+	return lecturesMap, DevicesLecturesToMap(&deviceLectures, lecturesMap)
+}
+
+func deviceIds(assignments *[]Assignment) []string {
+	ids := make([]string, 0, len(*assignments))
+
+	for _, assignment := range *assignments {
+		ids = append(ids, assignment.DeviceId)
+	}
+
+	return ids
+}
+
+// adversarial can be covered by informatics and mathematics, but greedy
+// picks mixed first.
+var adversarial = map[string][]string{
+	"informatics": {"IN1", "IN2", "IN3"},
+	"mathematics": {"MA1", "MA2", "MA3"},
+	"mixed":       {"IN1", "IN2", "MA1", "MA2"},
+}
+
+func TestGreedyBreaksTiesByDeviceId(t *testing.T) {
+	enrollments := map[string][]string{
+		"device-c": {"L1", "L2"},
+		"device-a": {"L2", "L3"},
+		"device-b": {"L3", "L4"},
+		"device-d": {"L1"},
+	}
+
+	for i := 0; i < 20; i++ {
+		lectures, devicesLectures := input(enrollments)
+		got := deviceIds(Greedy(devicesLectures, lectures))
+
+		// device-a wins the tie of the first pick, device-c and device-b
+		// cover one new lecture each afterwards.
+		if want := []string{"device-a", "device-b", "device-c"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestGreedyPrunedRemovesRedundantDevices(t *testing.T) {
+	lectures, devicesLectures := input(adversarial)
+	enrolled := Enrolled(devicesLectures)
+	greedy := Greedy(devicesLectures, lectures)
+
+	if got := deviceIds(greedy); !reflect.DeepEqual(got, []string{"mixed", "informatics", "mathematics"}) {
+		t.Fatalf("greedy picked %v", got)
+	}
+
+	lectures, devicesLectures = input(adversarial)
+	pruned := GreedyPruned(devicesLectures, lectures)
+
+	if got := deviceIds(pruned); !reflect.DeepEqual(got, []string{"informatics", "mathematics"}) {
+		t.Errorf("greedy-pruned picked %v", got)
+	}
+
+	for name, assignments := range map[string]*[]Assignment{"greedy": greedy, "greedy-pruned": pruned} {
+		if err := Verify(assignments, enrolled); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestPruneNeverGrowsTheSelection(t *testing.T) {
+	enrollments := map[string][]string{
+		"device-a": {"L1", "L2", "L3", "L4"},
+		"device-b": {"L1", "L5"},
+		"device-c": {"L2", "L6"},
+		"device-d": {"L3", "L7"},
+		"device-e": {"L4", "L8"},
+		"device-f": {"L5", "L6", "L7"},
+	}
+
+	for _, name := range Names() {
+		solve, err := Get(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lectures, devicesLectures := input(enrollments)
+		enrolled := Enrolled(devicesLectures)
+		assignments := solve(devicesLectures, lectures)
+
+		if err := Verify(assignments, enrolled); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		if pruned := Prune(assignments, enrolled); len(*pruned) > len(*assignments) {
+			t.Errorf("%s: pruning grew the selection from %d to %d devices", name, len(*assignments), len(*pruned))
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	enrolled := map[string][]string{"device-a": {"L1", "L2"}, "device-b": {"L2"}}
+
+	tests := []struct {
+		name        string
+		assignments []Assignment
+		valid       bool
+	}{
+		{"valid", []Assignment{{DeviceId: "device-a", LectureIds: []string{"L1"}}, {DeviceId: "device-b", LectureIds: []string{"L2"}}}, true},
+		{"uncovered lecture", []Assignment{{DeviceId: "device-b", LectureIds: []string{"L2"}}}, false},
+		{"lecture assigned twice", []Assignment{{DeviceId: "device-a", LectureIds: []string{"L1", "L2"}}, {DeviceId: "device-b", LectureIds: []string{"L2"}}}, false},
+		{"device not enrolled", []Assignment{{DeviceId: "device-b", LectureIds: []string{"L1", "L2"}}}, false},
+	}
+
+	for _, test := range tests {
+		if err := Verify(&test.assignments, enrolled); (err == nil) != test.valid {
+			t.Errorf("%s: unexpected result %v", test.name, err)
+		}
+	}
+}
+
+func TestLowerBound(t *testing.T) {
+	tests := []struct {
+		name        string
+		enrollments map[string][]string
+		bound       int
+	}{
+		{"no enrollments", map[string][]string{}, 0},
+		{"one device", map[string][]string{"device-a": {"L1", "L2"}}, 1},
+		// 6 lectures with at most 4 per device need 2 devices.
+		{"largest enrollment", adversarial, 2},
+		// L1, L3 and L5 share no device.
+		{"disjoint lectures", map[string][]string{
+			"device-a": {"L1", "L2"},
+			"device-b": {"L2", "L3"},
+			"device-c": {"L3", "L4"},
+			"device-d": {"L4", "L5"},
+		}, 3},
+	}
+
+	for _, test := range tests {
+		lectures, devicesLectures := input(test.enrollments)
+
+		if bound := LowerBound(devicesLectures); bound != test.bound {
+			t.Errorf("%s: expected %d, got %d", test.name, test.bound, bound)
+		}
+
+		if size := len(*Greedy(devicesLectures, lectures)); size < test.bound {
+			t.Errorf("%s: greedy selected %d devices, below the lower bound %d", test.name, size, test.bound)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package solver
+
+import (
+	"fmt"
+)
+
+// Enrolled returns the IDs of the lectures of every device. As the solvers
+// remove the devices they pick from their input, it has to be taken before
+// solving.
+func Enrolled(devicesLectures *DeviceToOverlappedLectures) map[string][]string {
+	enrolled := make(map[string][]string, len(*devicesLectures))
+
+	for device, deviceLectures := range *devicesLectures {
+		for _, lecture := range deviceLectures {
+			enrolled[device] = append(enrolled[device], lecture.LectureId)
+		}
+	}
+
+	return enrolled
+}
+
+// Verify checks that every lecture with an enrolled device is assigned to
+// exactly one device that is enrolled in it.
+func Verify(assignments *[]Assignment, enrolled map[string][]string) error {
+	isEnrolled := make(map[[2]string]bool)
+	coverable := make(map[string]bool)
+
+	for device, lectureIds := range enrolled {
+		for _, lectureId := range lectureIds {
+			isEnrolled[[2]string{device, lectureId}] = true
+			coverable[lectureId] = true
+		}
+	}
+
+	assigned := make(map[string]string, len(coverable))
+
+	for _, assignment := range *assignments {
+		for _, lectureId := range assignment.LectureIds {
+			if !isEnrolled[[2]string{assignment.DeviceId, lectureId}] {
+				return fmt.Errorf("lecture %s is assigned to device %s, which is not enrolled in it", lectureId, assignment.DeviceId)
+			}
+
+			if other, ok := assigned[lectureId]; ok {
+				return fmt.Errorf("lecture %s is assigned to devices %s and %s", lectureId, other, assignment.DeviceId)
+			}
+
+			assigned[lectureId] = assignment.DeviceId
+		}
+	}
+
+	if len(assigned) != len(coverable) {
+		return fmt.Errorf("%d of %d coverable lectures are assigned", len(assigned), len(coverable))
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package sweeper expires requests that were not answered in time and
+// retries requests whose push delivery failed.
+package sweeper
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository"
+	"time"
+)
+
+type Config struct {
+	// Interval is the time between two sweeps when running continuously.
+	Interval time.Duration
+	// MaxAttempts is how often the delivery of a request is attempted.
+	MaxAttempts int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:    time.Minute,
+		MaxAttempts: 3,
+	}
+}
+
+// A Sender delivers requests again. dispatch.Sender implementations can be
+// used.
+type Sender interface {
+	Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error
+}
+
+type Sweeper struct {
+	Config     Config
+	Repository repository.Repository
+	// Sender retries the failed deliveries. Requests are not retried if it
+	// is nil.
+	Sender Sender
+	// Now returns the current time the requests expire at.
+	Now func() time.Time
+}
+
+func NewSweeper(config Config, repo repository.Repository, sender Sender) *Sweeper {
+	return &Sweeper{
+		Config:     config,
+		Repository: repo,
+		Sender:     sender,
+		Now:        time.Now,
+	}
+}
+
+// Sweep expires the stale requests and retries the failed deliveries once.
+// The retries stop when ctx is canceled.
+func (s *Sweeper) Sweep(ctx context.Context) error {
+	now := s.Now()
+
+	expired, err := s.Repository.ExpireRequests(now)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Expired %d requests", expired)
+
+	if s.Sender == nil {
+		return nil
+	}
+
+	retryable, err := s.Repository.GetRetryableRequests(s.Config.MaxAttempts, now)
+
+	if err != nil {
+		return err
+	}
+
+	if len(*retryable) == 0 {
+		return nil
+	}
+
+	log.Infof("Retrying %d requests with failed delivery", len(*retryable))
+
+	return s.Sender.Send(ctx, 0, retryable)
+}
+
+// Run sweeps every Interval until ctx is canceled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(ctx); err != nil {
+			log.WithError(err).Error("Sweep failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
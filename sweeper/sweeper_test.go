@@ -0,0 +1,80 @@
+package sweeper
+
+import (
+	"context"
+	"test-student-lecture-selection-algorithm/model"
+	"test-student-lecture-selection-algorithm/repository/memory"
+	"testing"
+	"time"
+)
+
+type recordingSender struct {
+	requests []model.IOSDeviceRequestLog
+}
+
+func (s *recordingSender) Send(ctx context.Context, round int, requests *[]model.IOSDeviceRequestLog) error {
+	s.requests = append(s.requests, *requests...)
+
+	return nil
+}
+
+func TestSweep(t *testing.T) {
+	repo := memory.New()
+	now := time.Now()
+
+	if err := repo.CreateDevices(&[]model.IOSDevice{{DeviceID: "device-a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateLectures(&[]model.IOSLecture{{Id: "lecture-1", Semester: model.IOSLectureSemesterWinter}}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := repo.CreateLectureUpdateRequests(map[string][]string{"device-a": {"lecture-1"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestLogs := []model.IOSDeviceRequestLog{
+		model.NewIOSDeviceRequestLog("device-a", model.IOSTokenRequestType, now),
+		model.NewIOSDeviceRequestLog("device-a", model.IOSTokenRequestType, now),
+	}
+
+	if err := repo.CreateRequestLogs(&requestLogs); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := []string{requestLogs[0].RequestID, requestLogs[1].RequestID}
+	exhausted := []string{requestLogs[1].RequestID}
+
+	for _, requestIds := range [][]string{failed, exhausted, exhausted} {
+		if _, err := repo.MarkRequestsAttempted(&requestIds, model.IOSRequestStatusDeliveryFailed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The lecture update request expires 30 minutes after its creation, the
+	// token requests after an hour.
+	sender := &recordingSender{}
+	sweeper := NewSweeper(DefaultConfig(), repo, sender)
+	sweeper.Now = func() time.Time { return (*stale)[0].ExpiresAt.Time.Add(time.Second) }
+
+	if err := sweeper.Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lectures, err := repo.GetLectures()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lecture := (*lectures)[0]; lecture.LastRequestId != nil {
+		t.Errorf("lecture-1 still points to expired request %s", *lecture.LastRequestId)
+	}
+
+	if len(sender.requests) != 1 || sender.requests[0].RequestID != requestLogs[0].RequestID {
+		t.Errorf("expected only the request with one failed attempt to be retried, got %v", sender.requests)
+	}
+}